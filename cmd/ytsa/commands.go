@@ -0,0 +1,363 @@
+package main
+
+import (
+	"app/pkgs/aspects"
+	"app/pkgs/bq_ingest"
+	"app/pkgs/comment_import"
+	"app/pkgs/gemini_magic"
+	"app/pkgs/mentions"
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"app/pkgs/transcript"
+	"app/pkgs/yt_video"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func newFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ytsa %s [flags]\n\n%s\n\nFlags:\n", name, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func addFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "json", "output format: json, markdown or table")
+}
+
+// fetchVideo fetches a video and stores its data under a new or given tracking ID.
+func fetchVideo(ctx context.Context, s *store, ref, trackingID string) (*models.VideoData, error) {
+	if err := shared.RequireAPIKeys(&shared.AppConfig, true, false); err != nil {
+		return nil, err
+	}
+	videoID, err := yt_video.ParseVideoRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if trackingID == "" {
+		trackingID = uuid.New().String()
+	}
+
+	data, err := yt_video.Fetch(ctx, &shared.AppConfig, videoID, trackingID)
+	if err != nil {
+		return nil, err
+	}
+	location, err := s.write(ctx, trackingID+".json", data)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Fetched %d comments for %s into %s\n", len(data.Comments), videoID, location)
+	return data, nil
+}
+
+// readTranscript parses an SRT or WebVTT file and stores it next to the video
+// data. It returns nil when no file is given.
+func readTranscript(ctx context.Context, s *store, path string, data *models.VideoData) (*models.Transcript, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := transcript.Parse(raw, transcript.DetectFormat(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t := &models.Transcript{VideoID: data.ID, Source: transcript.SourceUpload, Segments: segments}
+	if _, err := s.write(ctx, data.TrackingID+"_transcript.json", t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readAspects reads and validates an aspect taxonomy file. It returns nil when
+// no file is given.
+func readAspects(path string) (*models.AspectTaxonomy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var taxonomy models.AspectTaxonomy
+	if err := json.Unmarshal(raw, &taxonomy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := aspects.Validate(&taxonomy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if taxonomy.Name == "" {
+		taxonomy.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &taxonomy, nil
+}
+
+// readDictionary reads and validates a mention dictionary file. It returns nil
+// when no file is given.
+func readDictionary(path string) (*models.MentionDictionary, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dictionary models.MentionDictionary
+	if err := json.Unmarshal(raw, &dictionary); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := mentions.Validate(&dictionary); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &dictionary, nil
+}
+
+// analyzeVideo analyzes video data and stores the resulting record next to it.
+func analyzeVideo(ctx context.Context, s *store, data *models.VideoData, opts models.AnalyzeOptions) (*models.AnalysisRecord, error) {
+	if err := shared.RequireAPIKeys(&shared.AppConfig, false, true); err != nil {
+		return nil, err
+	}
+	if data.TrackingID == "" {
+		data.TrackingID = uuid.New().String()
+	}
+
+	record, err := gemini_magic.Analyze(ctx, &shared.AppConfig, data, data.TrackingID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(record.Annotations) > 0 {
+		if _, err := s.write(ctx, data.TrackingID+"_annotations.json", record.Annotations); err != nil {
+			return nil, err
+		}
+	}
+	location, err := s.write(ctx, data.TrackingID+"_analyzed.json", record)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Stored analysis in %s\n", location)
+	return record, nil
+}
+
+func ingestVideo(ctx context.Context, data *models.VideoData, record *models.AnalysisRecord) error {
+	_, messages, err := bq_ingest.Ingest(ctx, &shared.AppConfig, data, record)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, strings.Join(messages, " "))
+	return nil
+}
+
+func fetchCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("fetch", "Fetches a video and its comments from the YouTube API.")
+	s := addStoreFlags(fs)
+	video := fs.String("video", "", "video ID or YouTube URL")
+	trackingID := fs.String("tracking-id", "", "tracking ID to store the data under, generated when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *video == "" {
+		return errors.New("--video is required")
+	}
+
+	data, err := fetchVideo(ctx, s, *video, *trackingID)
+	if err != nil {
+		return err
+	}
+	fmt.Println(data.TrackingID)
+	return nil
+}
+
+func analyzeCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("analyze", "Analyzes video data with Gemini and prints the analysis.")
+	s := addStoreFlags(fs)
+	input := fs.String("input", "", "local VideoData JSON file, instead of <trackingId>.json in the store")
+	trackingID := fs.String("tracking-id", "", "tracking ID of stored video data")
+	transcriptFile := fs.String("transcript", "", "SRT or WebVTT transcript to cross-reference the comments with")
+	packaging := fs.Bool("packaging", false, "also critique the title and thumbnail")
+	includeBots := fs.Bool("include-bots", false, "keep suspected bot comments in the sentiment and themes")
+	language := fs.String("language", "", "language of the report text, e.g. sv or German (default English)")
+	aspectsFile := fs.String("aspects", "", "aspect taxonomy JSON file for the aspect scorecard")
+	dictionaryFile := fs.String("dictionary", "", "mention dictionary JSON file that normalizes brand and product aliases")
+	clusters := fs.Bool("clusters", false, "also cluster the comments into themes by their embeddings")
+	format := addFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := s.loadVideoData(ctx, *input, *trackingID)
+	if err != nil {
+		return err
+	}
+	if data.TrackingID == "" {
+		data.TrackingID = uuid.New().String()
+	}
+	opts := models.AnalyzeOptions{Packaging: *packaging, IncludeBots: *includeBots, OutputLanguage: *language, Clusters: *clusters}
+	if opts.Transcript, err = readTranscript(ctx, s, *transcriptFile, data); err != nil {
+		return err
+	}
+	if opts.Aspects, err = readAspects(*aspectsFile); err != nil {
+		return err
+	}
+	if opts.Dictionary, err = readDictionary(*dictionaryFile); err != nil {
+		return err
+	}
+	record, err := analyzeVideo(ctx, s, data, opts)
+	if err != nil {
+		return err
+	}
+	return render(os.Stdout, record, *format)
+}
+
+func ingestCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("ingest", "Ingests video data and its analysis into BigQuery.")
+	s := addStoreFlags(fs)
+	input := fs.String("input", "", "local VideoData JSON file, instead of <trackingId>.json in the store")
+	analysis := fs.String("analysis", "", "local AnalysisRecord JSON file, instead of <trackingId>_analyzed.json in the store")
+	trackingID := fs.String("tracking-id", "", "tracking ID of the stored artifacts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := s.loadVideoData(ctx, *input, *trackingID)
+	if err != nil {
+		return err
+	}
+	record, err := s.loadAnalysisRecord(ctx, *analysis, data.TrackingID)
+	if err != nil {
+		if *analysis != "" || !isNotExist(err) {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "No analysis found; ingesting video data only.")
+		record = nil
+	}
+	return ingestVideo(ctx, data, record)
+}
+
+func runCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("run", "Fetches and analyzes a video and prints the analysis.")
+	s := addStoreFlags(fs)
+	video := fs.String("video", "", "video ID or YouTube URL")
+	ingest := fs.Bool("ingest", false, "also ingest the results into BigQuery")
+	transcriptFile := fs.String("transcript", "", "SRT or WebVTT transcript to cross-reference the comments with")
+	packaging := fs.Bool("packaging", false, "also critique the title and thumbnail")
+	includeBots := fs.Bool("include-bots", false, "keep suspected bot comments in the sentiment and themes")
+	language := fs.String("language", "", "language of the report text, e.g. sv or German (default English)")
+	aspectsFile := fs.String("aspects", "", "aspect taxonomy JSON file for the aspect scorecard")
+	dictionaryFile := fs.String("dictionary", "", "mention dictionary JSON file that normalizes brand and product aliases")
+	clusters := fs.Bool("clusters", false, "also cluster the comments into themes by their embeddings")
+	format := addFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *video == "" {
+		return errors.New("--video is required")
+	}
+
+	data, err := fetchVideo(ctx, s, *video, "")
+	if err != nil {
+		return err
+	}
+	opts := models.AnalyzeOptions{Packaging: *packaging, IncludeBots: *includeBots, OutputLanguage: *language, Clusters: *clusters}
+	if opts.Transcript, err = readTranscript(ctx, s, *transcriptFile, data); err != nil {
+		return err
+	}
+	if opts.Aspects, err = readAspects(*aspectsFile); err != nil {
+		return err
+	}
+	if opts.Dictionary, err = readDictionary(*dictionaryFile); err != nil {
+		return err
+	}
+	record, err := analyzeVideo(ctx, s, data, opts)
+	if err != nil {
+		return err
+	}
+	if *ingest {
+		if err := ingestVideo(ctx, data, record); err != nil {
+			return err
+		}
+	}
+	return render(os.Stdout, record, *format)
+}
+
+func reportCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("report", "Prints a stored analysis.")
+	s := addStoreFlags(fs)
+	input := fs.String("input", "", "local AnalysisRecord JSON file, instead of <trackingId>_analyzed.json in the store")
+	trackingID := fs.String("tracking-id", "", "tracking ID of the stored analysis")
+	format := addFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	record, err := s.loadAnalysisRecord(ctx, *input, *trackingID)
+	if err != nil {
+		return err
+	}
+	return render(os.Stdout, record, *format)
+}
+
+func importCommand(ctx context.Context, args []string) error {
+	fs := newFlagSet("import", "Converts an exported comment file into video data that can be analyzed.")
+	s := addStoreFlags(fs)
+	file := fs.String("file", "", "CSV, JSONL or Takeout (.zip or comments.csv) export")
+	format := fs.String("format", "", "csv, jsonl or takeout, detected from the file name when empty")
+	mappingFile := fs.String("mapping", "", "JSON file mapping comment fields to column names")
+	videoID := fs.String("video-id", "", "video the comments belong to, required when the file covers several videos")
+	title := fs.String("title", "", "video title")
+	fetchMetadata := fs.Bool("fetch-metadata", false, "fetch the video metadata from the YouTube API")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+
+	req := models.ImportRequest{
+		Format:        *format,
+		VideoID:       *videoID,
+		Title:         *title,
+		FetchMetadata: *fetchMetadata,
+	}
+	if req.Format == "" {
+		req.Format = comment_import.DetectFormat(*file)
+	}
+	if *mappingFile != "" {
+		if err := s.loadJSON(ctx, *mappingFile, "", &req.Mapping); err != nil {
+			return err
+		}
+	}
+	if req.FetchMetadata {
+		if err := shared.RequireAPIKeys(&shared.AppConfig, true, false); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	trackingID := uuid.New().String()
+	video, err := comment_import.Import(ctx, &shared.AppConfig, data, req, trackingID)
+	if err != nil {
+		return err
+	}
+	location, err := s.write(ctx, trackingID+".json", video)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d comments into %s\n", len(video.Comments), location)
+	fmt.Println(trackingID)
+	return nil
+}
@@ -0,0 +1,56 @@
+// Command ytsa runs the fetch, analyze and ingest steps of the pipeline from
+// the command line. It reads the same environment variables as the server and
+// keeps its artifacts in a local directory unless --gcs is given.
+package main
+
+import (
+	"app/pkgs/shared"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: ytsa <command> [flags]
+
+Commands:
+  fetch    Fetch a video and its comments and store them as <trackingId>.json
+  analyze  Analyze stored or local video data and store <trackingId>_analyzed.json
+  ingest   Ingest video data and its analysis into BigQuery
+  import   Convert a CSV, JSONL or Takeout comment export into <trackingId>.json
+  run      Fetch and analyze a video, optionally ingesting the result
+  report   Print a stored analysis as JSON, Markdown or a table
+
+Run 'ytsa <command> -h' for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"fetch":   fetchCommand,
+		"analyze": analyzeCommand,
+		"ingest":  ingestCommand,
+		"import":  importCommand,
+		"run":     runCommand,
+		"report":  reportCommand,
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ytsa: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := command(context.Background(), os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		shared.Logger.Error("Command failed", "command", os.Args[1], "error", err)
+		fmt.Fprintln(os.Stderr, "ytsa:", err)
+		os.Exit(1)
+	}
+}
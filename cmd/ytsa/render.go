@@ -0,0 +1,334 @@
+package main
+
+import (
+	"app/pkgs/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+func render(w io.Writer, record *models.AnalysisRecord, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(record)
+	case "markdown", "md":
+		renderMarkdown(w, record)
+		return nil
+	case "table":
+		return renderTable(w, record)
+	default:
+		return fmt.Errorf("unknown format %q, expected json, markdown or table", format)
+	}
+}
+
+// oneLine collapses whitespace so free text fits in a table cell or list item.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func renderMarkdown(w io.Writer, r *models.AnalysisRecord) {
+	stats := r.PerformanceMetrics.VideoStatistics
+	ratios := r.PerformanceMetrics.EngagementRatios
+	audience := r.AudienceAnalysis
+
+	fmt.Fprintf(w, "# Analysis Report\n\n")
+	fmt.Fprintf(w, "*Tracking ID:* `%s`  \n*Run date:* %s\n\n", r.TrackingID, r.RunDate)
+	fmt.Fprintf(w, "## Executive Summary\n\n%s\n\n", r.ExecutiveSummary)
+
+	fmt.Fprintf(w, "## Performance Metrics\n\n")
+	fmt.Fprintf(w, "| Views | Likes | Comments | Like/View | Comment/View |\n|---|---|---|---|---|\n")
+	fmt.Fprintf(w, "| %d | %d | %d | %.4f | %.4f |\n\n", stats.ViewCount, stats.LikeCount, stats.CommentCount, ratios.LikeToViewRatio, ratios.CommentToViewRatio)
+	fmt.Fprintf(w, "%s\n\n", r.PerformanceMetrics.Interpretation)
+
+	fmt.Fprintf(w, "## Audience Analysis: %s\n\n", audience.SentimentLabel)
+	fmt.Fprintf(w, "Positive: %d, Negative: %d, Neutral: %d\n\n", audience.PositiveComments, audience.NegativeComments, audience.NeutralComments)
+	fmt.Fprintf(w, "%s\n\n**Persona:** %s\n\n", audience.Summary, audience.AudiencePersona)
+
+	fmt.Fprintf(w, "## Content Feedback\n\n### Positive Feedback\n\n")
+	for _, p := range r.ContentFeedback.PositiveFeedback {
+		fmt.Fprintf(w, "- **%s** _%q_\n", oneLine(p.Point), oneLine(p.RepresentativeComment))
+	}
+	fmt.Fprintf(w, "\n### Constructive Criticism\n\n")
+	for _, p := range r.ContentFeedback.ConstructiveCriticism {
+		fmt.Fprintf(w, "- **%s** _%q_\n", oneLine(p.Point), oneLine(p.RepresentativeComment))
+	}
+	fmt.Fprintf(w, "\n### Unanswered Questions\n\n")
+	for _, q := range r.ContentFeedback.UnansweredQuestions {
+		fmt.Fprintf(w, "- **%s** _%q_\n", oneLine(q.Question), oneLine(q.RepresentativeComment))
+	}
+
+	if review := r.TranscriptReview; review != nil {
+		fmt.Fprintf(w, "\n### Answered in the Video\n\n")
+		for _, q := range review.AnsweredQuestions {
+			fmt.Fprintf(w, "- **%s** (%s) %s\n", oneLine(q.Question), q.TranscriptTimestamp, oneLine(q.Evidence))
+		}
+		fmt.Fprintf(w, "\n### Factual Disputes\n\n")
+		for _, d := range review.FactualDisputes {
+			fmt.Fprintf(w, "- **%s** _%s_", oneLine(d.Claim), d.Verdict)
+			if d.TranscriptTimestamp != "" {
+				fmt.Fprintf(w, " (%s)", d.TranscriptTimestamp)
+			}
+			fmt.Fprintf(w, " %s\n", oneLine(d.Evidence))
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Key Themes\n\n")
+	for _, t := range r.KeyThemes {
+		fmt.Fprintf(w, "### %s\n\n%s\n\n> %s\n\n", t.ThemeTitle, t.Summary, oneLine(t.RepresentativeComment))
+	}
+
+	if len(r.ThemeClusters) > 0 {
+		fmt.Fprintf(w, "## Theme Clusters\n\n")
+		fmt.Fprintf(w, "| Theme | Comments | Share | Likes | Positive | Negative | Neutral |\n|---|---|---|---|---|---|---|\n")
+		for _, t := range r.ThemeClusters {
+			fmt.Fprintf(w, "| %s | %d | %.1f%% | %d | %d | %d | %d |\n", strings.ReplaceAll(oneLine(t.ThemeTitle), "|", "\\|"), t.MemberCount, t.Share*100, t.LikeTotal, t.Positive, t.Negative, t.Neutral)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.ChapterSentiment) > 0 {
+		fmt.Fprintf(w, "## Chapters\n\n")
+		for _, c := range r.ChapterSentiment {
+			fmt.Fprintf(w, "### %s (%s)\n\n", c.Title, c.Label)
+			fmt.Fprintf(w, "Mentions: %d (Positive: %d, Negative: %d, Neutral: %d)\n\n", c.Mentions, c.Positive, c.Negative, c.Neutral)
+			if c.Summary != "" {
+				fmt.Fprintf(w, "%s\n\n", c.Summary)
+			}
+			if len(c.Themes) > 0 {
+				fmt.Fprintf(w, "**Themes:** %s\n\n", strings.Join(c.Themes, ", "))
+			}
+		}
+	}
+
+	if len(r.MomentHeatmap) > 0 {
+		fmt.Fprintf(w, "## Moments\n\n")
+		fmt.Fprintf(w, "| Segment | Peak | Mentions | Positive | Negative | Neutral | Top quote |\n|---|---|---|---|---|---|---|\n")
+		for _, m := range r.MomentHeatmap {
+			quote := ""
+			if len(m.TopQuotes) > 0 {
+				quote = strings.ReplaceAll(oneLine(m.TopQuotes[0]), "|", "\\|")
+			}
+			fmt.Fprintf(w, "| %s | %d | %d | %d | %d | %d | %s |\n", m.Label, m.PeakSecond, m.Mentions, m.Positive, m.Negative, m.Neutral, quote)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.AspectScorecard) > 0 {
+		fmt.Fprintf(w, "## Aspect Scorecard (%s)\n\n", r.AspectTaxonomy)
+		fmt.Fprintf(w, "| Aspect | Mentions | Share | Net | Positive | Negative | Neutral | Top quote |\n|---|---|---|---|---|---|---|---|\n")
+		for _, a := range r.AspectScorecard {
+			quote := ""
+			if len(a.TopQuotes) > 0 {
+				quote = strings.ReplaceAll(oneLine(a.TopQuotes[0]), "|", "\\|")
+			}
+			fmt.Fprintf(w, "| %s | %d | %.1f%% | %+.2f | %d | %d | %d | %s |\n", a.Aspect, a.Mentions, a.Share*100, a.NetSentiment, a.Positive, a.Negative, a.Neutral, quote)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if q := r.Questions; q != nil && q.Questions > 0 {
+		fmt.Fprintf(w, "## Viewer Questions\n\n%d questions, %d answered, %d open\n\n", q.Questions, q.Answered, q.Open)
+		fmt.Fprintf(w, "| Question | Status | Comments | Likes | Examples |\n|---|---|---|---|---|\n")
+		for _, c := range q.Clusters {
+			fmt.Fprintf(w, "| %s | %s | %d | %d | %s |\n", strings.ReplaceAll(oneLine(c.Question), "|", "\\|"), c.Status, c.Comments, c.LikeTotal, strings.Join(c.ExampleCommentIDs, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.Mentions) > 0 {
+		fmt.Fprintf(w, "## Brand and Product Mentions\n\n")
+		fmt.Fprintf(w, "| Entity | Type | Mentions | Likes | Positive | Negative | Neutral | In description |\n|---|---|---|---|---|---|---|---|\n")
+		for _, m := range r.Mentions {
+			fmt.Fprintf(w, "| %s | %s | %d | %d | %d | %d | %d | %t |\n", strings.ReplaceAll(m.Entity, "|", "\\|"), m.Type, m.Mentions, m.LikeTotal, m.Positive, m.Negative, m.Neutral, m.InDescription)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.Languages) > 0 {
+		fmt.Fprintf(w, "## Languages\n\n")
+		fmt.Fprintf(w, "| Language | Comments | Share | Positive | Negative | Neutral |\n|---|---|---|---|---|---|\n")
+		for _, l := range r.Languages {
+			fmt.Fprintf(w, "| %s | %d | %.1f%% | %d | %d | %d |\n", l.Language, l.Comments, l.Share*100, l.Positive, l.Negative, l.Neutral)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if b := r.BotReport; b != nil && b.SuspectedComments > 0 {
+		fmt.Fprintf(w, "## Suspected Bots\n\n%d suspected comments (%.1f%%), ", b.SuspectedComments, b.SuspectedShare*100)
+		if b.Excluded {
+			fmt.Fprintf(w, "excluded from the analysis.\n\n")
+		} else {
+			fmt.Fprintf(w, "included in the analysis.\n\n")
+		}
+		fmt.Fprintf(w, "- Near duplicates: %d in %d groups\n- Author bursts: %d in %d bursts\n- Link spam: %d\n\n", b.DuplicateComments, len(b.DuplicateGroups), b.BurstComments, len(b.AuthorBursts), b.LinkSpamComments)
+		for _, g := range b.DuplicateGroups {
+			fmt.Fprintf(w, "- (%d) _%q_\n", g.Size, oneLine(g.Text))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if m := r.Moderation; m != nil && m.FlaggedComments > 0 {
+		fmt.Fprintf(w, "## Moderation\n\n%d flagged comments (%.1f%%)\n\n", m.FlaggedComments, m.FlaggedShare*100)
+		fmt.Fprintf(w, "| Category | Flags | High | Medium | Low |\n|---|---|---|---|---|\n")
+		for _, c := range m.Categories {
+			fmt.Fprintf(w, "| %s | %d | %d | %d | %d |\n", c.Category, c.Count, c.High, c.Medium, c.Low)
+		}
+		fmt.Fprintln(w)
+		for _, f := range m.Flags {
+			fmt.Fprintf(w, "- **%s/%s** `%s` _%q_ %s\n", f.Category, f.Severity, f.CommentID, oneLine(f.Text), oneLine(f.Reason))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "## Engagement Highlights\n\n")
+	for _, h := range r.EngagementHighlights {
+		fmt.Fprintf(w, "- (%d) _%q_ %s\n", h.EngagementCount, oneLine(h.CommentText), oneLine(h.ReasonForEngagement))
+	}
+
+	if p := r.PackagingCritique; p != nil {
+		fmt.Fprintf(w, "\n## Packaging\n\n%s\n\n", p.Summary)
+		fmt.Fprintf(w, "| | Score | Assessment |\n|---|---|---|\n")
+		fmt.Fprintf(w, "| Title clarity | %d/10 | %s |\n", p.TitleClarity.Score, oneLine(p.TitleClarity.Assessment))
+		if p.ThumbnailReviewed {
+			fmt.Fprintf(w, "| Thumbnail clarity | %d/10 | %s |\n", p.ThumbnailClarity.Score, oneLine(p.ThumbnailClarity.Assessment))
+		}
+		fmt.Fprintf(w, "| Clickbait risk | %d/10 | %s |\n\n", p.ClickbaitRisk.Score, oneLine(p.ClickbaitRisk.Assessment))
+		for _, m := range p.AudienceMismatch {
+			fmt.Fprintf(w, "- **Promise:** %s **Reality:** %s _%q_\n", oneLine(m.Promise), oneLine(m.Reality), oneLine(m.RepresentativeComment))
+		}
+		for _, s := range p.Suggestions {
+			fmt.Fprintf(w, "- **%s** %s\n", oneLine(s.Suggestion), oneLine(s.Reason))
+		}
+	}
+
+	swot := r.SWOTAnalysis
+	fmt.Fprintf(w, "\n## SWOT Analysis\n\n")
+	fmt.Fprintf(w, "**Strengths:** %s\n\n**Weaknesses:** %s\n\n**Opportunities:** %s\n\n**Threats:** %s\n\n", swot.Strengths, swot.Weaknesses, swot.Opportunities, swot.Threats)
+
+	recs := r.ActionableRecommendations
+	fmt.Fprintf(w, "## Actionable Recommendations\n\n### Content Strategy\n\n")
+	for _, c := range recs.ContentStrategy {
+		fmt.Fprintf(w, "- **%s** %s\n", oneLine(c.Idea), oneLine(c.Reason))
+	}
+	fmt.Fprintf(w, "\n### Video Improvements\n\n")
+	for _, v := range recs.VideoImprovements {
+		fmt.Fprintf(w, "- **%s** %s\n", oneLine(v.Suggestion), oneLine(v.Reason))
+	}
+	fmt.Fprintf(w, "\n### Community Management\n\n%s\n\n### Monetization Opportunities\n\n", recs.CommunityManagement)
+	for _, m := range recs.MonetizationOpportunities {
+		fmt.Fprintf(w, "- **%s:** %s\n", m.Category, strings.Join(m.Products, ", "))
+	}
+}
+
+func renderTable(w io.Writer, r *models.AnalysisRecord) error {
+	stats := r.PerformanceMetrics.VideoStatistics
+	ratios := r.PerformanceMetrics.EngagementRatios
+	audience := r.AudienceAnalysis
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Tracking ID\t%s\n", r.TrackingID)
+	fmt.Fprintf(tw, "Run date\t%s\n", r.RunDate)
+	fmt.Fprintf(tw, "Sentiment\t%s\n", audience.SentimentLabel)
+	fmt.Fprintf(tw, "Positive / Negative / Neutral\t%d / %d / %d\n", audience.PositiveComments, audience.NegativeComments, audience.NeutralComments)
+	fmt.Fprintf(tw, "Views\t%d\n", stats.ViewCount)
+	fmt.Fprintf(tw, "Likes\t%d\n", stats.LikeCount)
+	fmt.Fprintf(tw, "Comments\t%d\n", stats.CommentCount)
+	fmt.Fprintf(tw, "Like/View\t%.4f\n", ratios.LikeToViewRatio)
+	fmt.Fprintf(tw, "Comment/View\t%.4f\n", ratios.CommentToViewRatio)
+	if p := r.PackagingCritique; p != nil {
+		fmt.Fprintf(tw, "Title clarity\t%d/10\n", p.TitleClarity.Score)
+		if p.ThumbnailReviewed {
+			fmt.Fprintf(tw, "Thumbnail clarity\t%d/10\n", p.ThumbnailClarity.Score)
+		}
+		fmt.Fprintf(tw, "Clickbait risk\t%d/10\n", p.ClickbaitRisk.Score)
+	}
+	if m := r.Moderation; m != nil {
+		fmt.Fprintf(tw, "Flagged comments\t%d\n", m.FlaggedComments)
+	}
+	if b := r.BotReport; b != nil {
+		fmt.Fprintf(tw, "Suspected bots\t%d (excluded: %t)\n", b.SuspectedComments, b.Excluded)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "THEME\tSUMMARY")
+	for _, t := range r.KeyThemes {
+		fmt.Fprintf(tw, "%s\t%s\n", oneLine(t.ThemeTitle), oneLine(t.Summary))
+	}
+	fmt.Fprintln(tw)
+
+	if len(r.ThemeClusters) > 0 {
+		fmt.Fprintln(tw, "CLUSTER\tCOMMENTS\tLIKES\tPOS/NEG/NEU")
+		for _, t := range r.ThemeClusters {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d/%d/%d\n", oneLine(t.ThemeTitle), t.MemberCount, t.LikeTotal, t.Positive, t.Negative, t.Neutral)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintln(tw, "ENGAGEMENT\tCOMMENT")
+	for _, h := range r.EngagementHighlights {
+		fmt.Fprintf(tw, "%d\t%s\n", h.EngagementCount, oneLine(h.CommentText))
+	}
+
+	if len(r.ChapterSentiment) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "CHAPTER\tSEGMENT\tMENTIONS\tPOS/NEG/NEU")
+		for _, c := range r.ChapterSentiment {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d/%d/%d\n", oneLine(c.Title), c.Label, c.Mentions, c.Positive, c.Negative, c.Neutral)
+		}
+	}
+
+	if len(r.MomentHeatmap) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "MOMENT\tMENTIONS\tPOS/NEG/NEU")
+		for _, m := range r.MomentHeatmap {
+			fmt.Fprintf(tw, "%s\t%d\t%d/%d/%d\n", m.Label, m.Mentions, m.Positive, m.Negative, m.Neutral)
+		}
+	}
+
+	if len(r.AspectScorecard) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "ASPECT\tMENTIONS\tNET\tPOS/NEG/NEU")
+		for _, a := range r.AspectScorecard {
+			fmt.Fprintf(tw, "%s\t%d\t%+.2f\t%d/%d/%d\n", a.Aspect, a.Mentions, a.NetSentiment, a.Positive, a.Negative, a.Neutral)
+		}
+	}
+
+	if q := r.Questions; q != nil && q.Open > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "OPEN QUESTION\tCOMMENTS\tLIKES")
+		for _, c := range q.Clusters {
+			if c.Status == models.QuestionOpen {
+				fmt.Fprintf(tw, "%s\t%d\t%d\n", oneLine(c.Question), c.Comments, c.LikeTotal)
+			}
+		}
+	}
+
+	if len(r.Mentions) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "ENTITY\tTYPE\tMENTIONS\tPOS/NEG/NEU")
+		for _, m := range r.Mentions {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d/%d/%d\n", oneLine(m.Entity), m.Type, m.Mentions, m.Positive, m.Negative, m.Neutral)
+		}
+	}
+
+	if len(r.Languages) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "LANGUAGE\tCOMMENTS\tSHARE\tPOS/NEG/NEU")
+		for _, l := range r.Languages {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%d/%d/%d\n", l.Language, l.Comments, l.Share*100, l.Positive, l.Negative, l.Neutral)
+		}
+	}
+
+	if m := r.Moderation; m != nil && m.FlaggedComments > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "FLAG\tSEVERITY\tCOMMENT")
+		for _, f := range m.Flags {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Category, f.Severity, oneLine(f.Text))
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// store reads and writes pipeline artifacts using the same object names as the
+// server, either in a local directory or in the configured GCS bucket.
+type store struct {
+	dir string
+	gcs bool
+}
+
+func addStoreFlags(fs *flag.FlagSet) *store {
+	s := &store{}
+	fs.StringVar(&s.dir, "dir", ".", "directory for local artifacts")
+	fs.BoolVar(&s.gcs, "gcs", false, "read and write artifacts in GCS_BUCKET_NAME instead of --dir")
+	return s
+}
+
+func (s *store) location(name string) string {
+	if s.gcs {
+		return fmt.Sprintf("gs://%s/%s", shared.AppConfig.GCSBucketName, name)
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s *store) read(ctx context.Context, name string) ([]byte, error) {
+	if s.gcs {
+		return shared.GetFileFromGCS(ctx, shared.AppConfig.GCSBucketName, name)
+	}
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s *store) write(ctx context.Context, name string, v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not marshal %s: %w", name, err)
+	}
+	if s.gcs {
+		if err := shared.UploadToGCS(ctx, shared.AppConfig.GCSBucketName, name, data); err != nil {
+			return "", err
+		}
+	} else {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o644); err != nil {
+			return "", err
+		}
+	}
+	return s.location(name), nil
+}
+
+// isNotExist reports whether an artifact is missing in either backend.
+func isNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, storage.ErrObjectNotExist)
+}
+
+// loadJSON decodes an explicit local file when path is set, or the named
+// artifact from the store otherwise.
+func (s *store) loadJSON(ctx context.Context, path, name string, v interface{}) error {
+	var data []byte
+	var err error
+	source := path
+	if path != "" {
+		data, err = os.ReadFile(path)
+	} else {
+		source = s.location(name)
+		data, err = s.read(ctx, name)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", source, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("could not decode %s: %w", source, err)
+	}
+	return nil
+}
+
+func (s *store) loadVideoData(ctx context.Context, path, trackingID string) (*models.VideoData, error) {
+	if path == "" && trackingID == "" {
+		return nil, errors.New("an input file or --tracking-id is required")
+	}
+	var data models.VideoData
+	if err := s.loadJSON(ctx, path, trackingID+".json", &data); err != nil {
+		return nil, err
+	}
+	if data.TrackingID == "" {
+		data.TrackingID = trackingID
+	}
+	if data.TrackingID == "" {
+		data.TrackingID = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	return &data, nil
+}
+
+func (s *store) loadAnalysisRecord(ctx context.Context, path, trackingID string) (*models.AnalysisRecord, error) {
+	if path == "" && trackingID == "" {
+		return nil, errors.New("an analysis file or --tracking-id is required")
+	}
+	var record models.AnalysisRecord
+	if err := s.loadJSON(ctx, path, trackingID+"_analyzed.json", &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
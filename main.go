@@ -1,11 +1,30 @@
 package main
 
 import (
+	"app/pkgs/alerts"
+	"app/pkgs/aspects"
+	"app/pkgs/batch"
 	"app/pkgs/bq_ingest"
+	"app/pkgs/comment_chat"
+	"app/pkgs/comment_import"
+	"app/pkgs/comment_search"
 	"app/pkgs/gemini_magic"
+	"app/pkgs/live_chat"
+	"app/pkgs/mentions"
+	"app/pkgs/moderation"
+	"app/pkgs/questions"
+	"app/pkgs/run_history"
+	"app/pkgs/sentiment_trend"
 	"app/pkgs/shared"
+	"app/pkgs/transcript"
+	"app/pkgs/triggers"
 	"app/pkgs/ui_handler"
+	"app/pkgs/video_stats"
+	"app/pkgs/watchlist"
+	"app/pkgs/webhooks"
 	"app/pkgs/yt_video"
+	"context"
+	"log"
 	"log/slog"
 	"net/http"
 	"os"
@@ -13,12 +32,46 @@ import (
 )
 
 func main() {
+	if err := shared.RequireAPIKeys(&shared.AppConfig, true, true); err != nil {
+		log.Fatalf("CRITICAL: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		os.Exit(batch.Main(context.Background(), &shared.AppConfig, os.Args[2:]))
+	}
+
 	http.HandleFunc("/", ui_handler.Info)
 	http.HandleFunc("/ui", ui_handler.ServeUI)
 	http.HandleFunc("/ui/process", ui_handler.ProcessHandler)
 	http.HandleFunc("/youtube", yt_video.FetchData(&shared.AppConfig))
 	http.HandleFunc("/magic", gemini_magic.AnalyzeData(&shared.AppConfig))
 	http.HandleFunc("/ingest", bq_ingest.IngestData(&shared.AppConfig))
+	http.HandleFunc("/rollup", gemini_magic.RollupChannel(&shared.AppConfig))
+	http.HandleFunc("/ingest/rollup", bq_ingest.IngestChannelReport(&shared.AppConfig))
+	http.HandleFunc("/compare", gemini_magic.CompareVideos(&shared.AppConfig))
+	http.HandleFunc("/watchlist", watchlist.Manage(&shared.AppConfig))
+	http.HandleFunc("/watchlist/run", watchlist.RunWatchlist(&shared.AppConfig))
+	http.HandleFunc("/history", run_history.History(&shared.AppConfig))
+	http.HandleFunc("/stats", video_stats.Velocity(&shared.AppConfig))
+	http.HandleFunc("/stats/poll", video_stats.PollStats(&shared.AppConfig))
+	http.HandleFunc("/trend", sentiment_trend.Trend(&shared.AppConfig))
+	http.HandleFunc("/alerts/rules", alerts.Rules(&shared.AppConfig))
+	http.HandleFunc("/alerts/evaluate", alerts.EvaluateAlerts(&shared.AppConfig))
+	http.HandleFunc("/webhooks", webhooks.Subscriptions(&shared.AppConfig))
+	http.HandleFunc("/webhooks/deliveries", webhooks.Deliveries(&shared.AppConfig))
+	http.HandleFunc("/events", triggers.HandleEvent(&shared.AppConfig))
+	http.HandleFunc("/import", comment_import.ImportComments(&shared.AppConfig))
+	http.HandleFunc("/livechat", live_chat.LiveChat(&shared.AppConfig))
+	http.HandleFunc("/transcript", transcript.Transcript(&shared.AppConfig))
+	http.HandleFunc("GET /runs/{id}/search", comment_search.Search(&shared.AppConfig))
+	http.HandleFunc("GET /runs/{id}/chat", comment_chat.Chat(&shared.AppConfig))
+	http.HandleFunc("/runs/{id}/moderation", moderation.Queue(&shared.AppConfig))
+	http.HandleFunc("/aspects/{name}", aspects.Taxonomy(&shared.AppConfig))
+	http.HandleFunc("/mentions/dictionary", mentions.Dictionary(&shared.AppConfig))
+	http.HandleFunc("GET /runs/{id}/questions", questions.List(&shared.AppConfig))
+
+	go watchlist.StartScheduler(context.Background(), &shared.AppConfig)
+	go video_stats.StartPoller(context.Background(), &shared.AppConfig)
 
 	slog.Info("Starting server", "port", shared.AppConfig.Port)
 
@@ -0,0 +1,195 @@
+package alerts
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/run_history"
+	"app/pkgs/shared"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// questionSimilarity is the word overlap above which a question is considered
+// to be the same as a question from the previous run.
+const questionSimilarity = 0.5
+
+// previousRecord returns the analysis of the most recent successful run of a
+// video other than the given tracking ID, or nil if there is none.
+func previousRecord(ctx context.Context, cfg *models.AppConfig, videoID, trackingID string) (*models.AnalysisRecord, error) {
+	history, err := run_history.Load(ctx, cfg, videoID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(history.Runs) - 1; i >= 0; i-- {
+		run := history.Runs[i]
+		if run.TrackingID == trackingID || run.Status != "success" {
+			continue
+		}
+		record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, run.TrackingID)
+		if err != nil {
+			shared.Logger.Warn("Skipping previous run without analysis", "error", err, "trackingId", run.TrackingID)
+			continue
+		}
+		return record, nil
+	}
+	return nil, nil
+}
+
+// toxicComments returns the number of comments flagged as harassment or hate.
+func toxicComments(report *models.ModerationReport) int64 {
+	var count int64
+	for _, category := range report.Categories {
+		if category.Category == models.ModerationHarassment || category.Category == models.ModerationHate {
+			count += category.Count
+		}
+	}
+	return count
+}
+
+// evaluateRules checks an analysis against the rules. The previous analysis
+// of the same video may be nil, in which case comparative rules are skipped.
+func evaluateRules(rules models.AlertRules, record, previous *models.AnalysisRecord) []models.Alert {
+	alerts := []models.Alert{}
+	audience := record.AudienceAnalysis
+	total := audience.PositiveComments + audience.NegativeComments + audience.NeutralComments
+
+	if rules.NegativeShareAbove > 0 && total > 0 {
+		share := float64(audience.NegativeComments) / float64(total)
+		if share > rules.NegativeShareAbove {
+			alerts = append(alerts, models.Alert{
+				Rule:      "negative_share_above",
+				Severity:  "warning",
+				Message:   fmt.Sprintf("Negative comment share is %.1f%%, above the %.1f%% threshold.", share*100, rules.NegativeShareAbove*100),
+				Value:     share,
+				Threshold: rules.NegativeShareAbove,
+			})
+		}
+	}
+
+	if slices.Contains(rules.SentimentLabels, audience.SentimentLabel) {
+		alerts = append(alerts, models.Alert{
+			Rule:     "sentiment_label",
+			Severity: "critical",
+			Message:  fmt.Sprintf("Overall audience sentiment is '%s'.", audience.SentimentLabel),
+		})
+	}
+
+	if previous == nil {
+		return alerts
+	}
+
+	if rules.NegativeSpikeFactor > 0 && previous.AudienceAnalysis.NegativeComments > 0 {
+		factor := float64(audience.NegativeComments) / float64(previous.AudienceAnalysis.NegativeComments)
+		if factor >= rules.NegativeSpikeFactor {
+			alerts = append(alerts, models.Alert{
+				Rule:      "negative_spike_factor",
+				Severity:  "critical",
+				Message:   fmt.Sprintf("Negative comments grew from %d to %d (x%.1f) since the previous run.", previous.AudienceAnalysis.NegativeComments, audience.NegativeComments, factor),
+				Value:     factor,
+				Threshold: rules.NegativeSpikeFactor,
+			})
+		}
+	}
+
+	// Analyses made before moderation flags existed carry no report, so
+	// they cannot be compared.
+	if rules.ToxicSpikeFactor > 0 && record.Moderation != nil && previous.Moderation != nil {
+		current, before := toxicComments(record.Moderation), toxicComments(previous.Moderation)
+		// A video without toxic comments before counts as one, so the
+		// first wave is caught too.
+		factor := float64(current) / float64(max(before, 1))
+		if current >= rules.ToxicSpikeMin && factor >= rules.ToxicSpikeFactor {
+			alerts = append(alerts, models.Alert{
+				Rule:      "toxic_spike_factor",
+				Severity:  "critical",
+				Message:   fmt.Sprintf("Harassment and hate comments grew from %d to %d (x%.1f) since the previous run.", before, current, factor),
+				Value:     factor,
+				Threshold: rules.ToxicSpikeFactor,
+			})
+		}
+	}
+
+	if rules.NewUnansweredQuestions {
+		for _, question := range record.ContentFeedback.UnansweredQuestions {
+			known := false
+			for _, prev := range previous.ContentFeedback.UnansweredQuestions {
+				if shared.SimilarText(question.Question, prev.Question, questionSimilarity) {
+					known = true
+					break
+				}
+			}
+			if !known {
+				alerts = append(alerts, models.Alert{
+					Rule:     "new_unanswered_questions",
+					Severity: "info",
+					Message:  fmt.Sprintf("New unanswered question: %s", question.Question),
+				})
+			}
+		}
+	}
+	return alerts
+}
+
+// Evaluate checks the analysis of a run against the alert rules and sends any
+// resulting alerts to every configured notifier. Notifier failures are logged
+// and do not fail the evaluation.
+func Evaluate(ctx context.Context, cfg *models.AppConfig, trackingID string) (*models.AlertNotification, error) {
+	rules, err := LoadRules(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	video, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load raw data: %w", err)
+	}
+	record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load analyzed data: %w", err)
+	}
+	previous, err := previousRecord(ctx, cfg, video.ID, trackingID)
+	if err != nil {
+		return nil, err
+	}
+
+	notification := &models.AlertNotification{
+		TrackingID:  trackingID,
+		VideoID:     video.ID,
+		VideoTitle:  video.Title,
+		TriggeredAt: time.Now(),
+		Alerts:      evaluateRules(rules, record, previous),
+	}
+	if len(notification.Alerts) == 0 {
+		return notification, nil
+	}
+
+	shared.Logger.Info("Alert rules triggered", "alertCount", len(notification.Alerts), "trackingId", trackingID)
+	for _, notifier := range Notifiers(cfg) {
+		if err := notifier.Notify(ctx, *notification); err != nil {
+			shared.Logger.Error("Failed to send alert notification", "notifier", notifier.Name(), "error", err, "trackingId", trackingID)
+		}
+	}
+	return notification, nil
+}
+
+// EvaluateAlerts evaluates the alert rules for an analyzed tracking ID and
+// returns the alerts that were raised.
+func EvaluateAlerts(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trackingID := r.URL.Query().Get("trackingId")
+		if trackingID == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'trackingId' query parameter")
+			return
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		notification, err := Evaluate(r.Context(), cfg, trackingID)
+		if err != nil {
+			shared.Logger.Error("could not evaluate alert rules", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to evaluate alert rules")
+			return
+		}
+		shared.JSONResponse(w, trackingID, http.StatusOK, notification)
+	}
+}
@@ -0,0 +1,45 @@
+package alerts
+
+import (
+	"app/pkgs/models"
+	"testing"
+)
+
+func moderation(harassment, hate, spam int64) *models.ModerationReport {
+	return &models.ModerationReport{Categories: []models.ModerationCategory{
+		{Category: models.ModerationHarassment, Count: harassment},
+		{Category: models.ModerationHate, Count: hate},
+		{Category: models.ModerationSpam, Count: spam},
+	}}
+}
+
+func TestToxicSpikeRule(t *testing.T) {
+	rules := models.AlertRules{ToxicSpikeFactor: 2, ToxicSpikeMin: 5}
+	tests := []struct {
+		name              string
+		current, previous *models.ModerationReport
+		want              bool
+	}{
+		{name: "doubled", current: moderation(6, 4, 0), previous: moderation(3, 2, 0), want: true},
+		{name: "below factor", current: moderation(6, 3, 0), previous: moderation(3, 2, 0), want: false},
+		{name: "first wave", current: moderation(5, 0, 0), previous: moderation(0, 0, 40), want: true},
+		{name: "below minimum", current: moderation(2, 2, 0), previous: moderation(1, 0, 0), want: false},
+		{name: "spam does not count", current: moderation(1, 0, 50), previous: moderation(1, 0, 0), want: false},
+		{name: "previous without moderation", current: moderation(20, 0, 0), previous: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &models.AnalysisRecord{Moderation: tt.current}
+			previous := &models.AnalysisRecord{Moderation: tt.previous}
+			got := false
+			for _, alert := range evaluateRules(rules, record, previous) {
+				if alert.Rule == "toxic_spike_factor" {
+					got = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("toxic spike alert = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
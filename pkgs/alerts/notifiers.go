@@ -0,0 +1,136 @@
+package alerts
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var notifierHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// Notifier delivers a group of alerts raised for one analysis.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, notification models.AlertNotification) error
+}
+
+// Notifiers returns the notifiers enabled in the configuration.
+func Notifiers(cfg *models.AppConfig) []Notifier {
+	var notifiers []Notifier
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: cfg.AlertWebhookURL})
+	}
+	if cfg.AlertSlackURL != "" {
+		notifiers = append(notifiers, &SlackNotifier{URL: cfg.AlertSlackURL})
+	}
+	if cfg.AlertSMTPHost != "" && cfg.AlertEmailTo != "" {
+		notifiers = append(notifiers, &EmailNotifier{
+			Host:     cfg.AlertSMTPHost,
+			Port:     cfg.AlertSMTPPort,
+			Username: cfg.AlertSMTPUsername,
+			Password: cfg.AlertSMTPPassword,
+			From:     cfg.AlertEmailFrom,
+			To:       shared.SplitIDs(cfg.AlertEmailTo),
+		})
+	}
+	return notifiers
+}
+
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifierHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// summaryLines renders a notification as human-readable lines.
+func summaryLines(notification models.AlertNotification) []string {
+	lines := []string{
+		fmt.Sprintf("%d alert(s) for \"%s\" (video %s, tracking ID %s):", len(notification.Alerts), notification.VideoTitle, notification.VideoID, notification.TrackingID),
+	}
+	for _, alert := range notification.Alerts {
+		lines = append(lines, fmt.Sprintf("- [%s] %s", strings.ToUpper(alert.Severity), alert.Message))
+	}
+	return lines
+}
+
+// WebhookNotifier POSTs the notification as JSON to a generic endpoint.
+type WebhookNotifier struct {
+	URL string
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification models.AlertNotification) error {
+	return postJSON(ctx, n.URL, notification)
+}
+
+// SlackNotifier posts a text message to a Slack-compatible incoming webhook.
+type SlackNotifier struct {
+	URL string
+}
+
+func (n *SlackNotifier) Name() string { return "slack" }
+
+func (n *SlackNotifier) Notify(ctx context.Context, notification models.AlertNotification) error {
+	return postJSON(ctx, n.URL, map[string]string{"text": strings.Join(summaryLines(notification), "\n")})
+}
+
+// EmailNotifier sends a plain-text email through an SMTP server. Authentication
+// is skipped when no username is set, which suits local stand-in servers.
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *EmailNotifier) Name() string { return "email" }
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification models.AlertNotification) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	subject := fmt.Sprintf("[YouTube Sentiment] %d alert(s) for %s", len(notification.Alerts), notification.VideoTitle)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	// Video titles are often not ASCII, which a raw header would mangle.
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.Join(summaryLines(notification), "\r\n"))
+	msg.WriteString("\r\n")
+
+	addr := fmt.Sprintf("%s:%d", n.Host, n.Port)
+	if err := smtp.SendMail(addr, auth, n.From, n.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+	return nil
+}
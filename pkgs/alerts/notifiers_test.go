@@ -0,0 +1,177 @@
+package alerts
+
+import (
+	"app/pkgs/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testNotification() models.AlertNotification {
+	return models.AlertNotification{
+		TrackingID: "t1",
+		VideoID:    "v1",
+		VideoTitle: "Größter Test – 日本語",
+		Alerts: []models.Alert{
+			{Rule: "negative_share_above", Severity: "warning", Message: "Negative comment share is 40.0%."},
+			{Rule: "toxic_spike_factor", Severity: "critical", Message: "Harassment and hate comments grew."},
+		},
+	}
+}
+
+// captureServer records the body of every request and answers with status.
+func captureServer(t *testing.T, status int) (*httptest.Server, <-chan []byte) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, bodies := captureServer(t, http.StatusNoContent)
+	if err := (&WebhookNotifier{URL: server.URL}).Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	var got models.AlertNotification
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatalf("could not decode payload: %v", err)
+	}
+	if got.TrackingID != "t1" || got.VideoTitle != testNotification().VideoTitle || len(got.Alerts) != 2 {
+		t.Errorf("payload = %+v, want the notification", got)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	server, bodies := captureServer(t, http.StatusOK)
+	if err := (&SlackNotifier{URL: server.URL}).Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatalf("could not decode payload: %v", err)
+	}
+	lines := strings.Split(got["text"], "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "- [CRITICAL]") {
+		t.Errorf("text = %q, want a summary line and one line per alert", got["text"])
+	}
+}
+
+func TestWebhookNotifierReportsErrorStatus(t *testing.T) {
+	server, _ := captureServer(t, http.StatusInternalServerError)
+	if err := (&WebhookNotifier{URL: server.URL}).Notify(context.Background(), testNotification()); err == nil {
+		t.Error("Notify succeeded, want an error for status 500")
+	}
+}
+
+// smtpStandIn accepts one message without TLS or authentication, like a local
+// MailHog, and returns the envelope recipients and the message data.
+func smtpStandIn(t *testing.T) (string, <-chan []string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	recipients := make(chan []string, 1)
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		var rcpt []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO"):
+				rcpt = append(rcpt, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				recipients <- rcpt
+				messages <- data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), recipients, messages
+}
+
+func TestEmailNotifier(t *testing.T) {
+	addr, recipients, messages := smtpStandIn(t)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+	n := &EmailNotifier{Host: host, Port: portNumber, From: "alerts@example.com", To: []string{"a@example.com", "b@example.com"}}
+
+	notification := testNotification()
+	if err := n.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got := <-recipients; strings.Join(got, ",") != "a@example.com,b@example.com" {
+		t.Errorf("recipients = %v, want both addresses", got)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(<-messages))
+	if err != nil {
+		t.Fatalf("could not parse message: %v", err)
+	}
+	rawSubject := msg.Header.Get("Subject")
+	for _, r := range rawSubject {
+		if r > 127 {
+			t.Fatalf("Subject header %q is not ASCII", rawSubject)
+		}
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(rawSubject)
+	if err != nil {
+		t.Fatalf("could not decode subject: %v", err)
+	}
+	if want := "[YouTube Sentiment] 2 alert(s) for " + notification.VideoTitle; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "- [WARNING] Negative comment share is 40.0%.") {
+		t.Errorf("body = %q, want one line per alert", body)
+	}
+}
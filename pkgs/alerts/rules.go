@@ -0,0 +1,93 @@
+package alerts
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+)
+
+const rulesObjectName = "alert_rules.json"
+
+// DefaultRules are used until rules are stored with PUT /alerts/rules.
+var DefaultRules = models.AlertRules{
+	NegativeShareAbove:     0.3,
+	SentimentLabels:        []string{"Negative", "Overwhelmingly Negative"},
+	NegativeSpikeFactor:    2.0,
+	NewUnansweredQuestions: true,
+	ToxicSpikeFactor:       2.0,
+	ToxicSpikeMin:          5,
+}
+
+// LoadRules reads the alert rules from GCS, falling back to DefaultRules.
+func LoadRules(ctx context.Context, cfg *models.AppConfig) (models.AlertRules, error) {
+	fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, rulesObjectName)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return DefaultRules, nil
+		}
+		return models.AlertRules{}, fmt.Errorf("could not get alert rules from GCS: %w", err)
+	}
+	var rules models.AlertRules
+	if err := json.Unmarshal(fileData, &rules); err != nil {
+		return models.AlertRules{}, fmt.Errorf("could not unmarshal alert rules JSON: %w", err)
+	}
+	return rules, nil
+}
+
+// Rules returns (GET) or replaces (PUT) the alert rules.
+func Rules(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		switch r.Method {
+		case http.MethodGet:
+			rules, err := LoadRules(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load alert rules", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load alert rules")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, rules)
+
+		case http.MethodPut:
+			var rules models.AlertRules
+			if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if rules.NegativeShareAbove < 0 || rules.NegativeShareAbove > 1 {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'negative_share_above' must be between 0 and 1")
+				return
+			}
+			if rules.NegativeSpikeFactor < 0 {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'negative_spike_factor' must not be negative")
+				return
+			}
+			if rules.ToxicSpikeFactor < 0 || rules.ToxicSpikeMin < 0 {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'toxic_spike_factor' and 'toxic_spike_min' must not be negative")
+				return
+			}
+			rulesJSON, err := json.Marshal(rules)
+			if err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to marshal alert rules")
+				return
+			}
+			if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, rulesObjectName, rulesJSON); err != nil {
+				shared.Logger.Error("could not save alert rules", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to save alert rules")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, rules)
+
+		default:
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
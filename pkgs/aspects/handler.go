@@ -0,0 +1,64 @@
+package aspects
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Taxonomy returns (GET) or replaces (PUT) the aspect taxonomy stored under a
+// channel ID or profile name.
+func Taxonomy(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		name := r.PathValue("name")
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+		if !ValidName(name) {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "The taxonomy name must be a channel ID or a profile name of letters, digits, '-' and '_'")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			taxonomy, err := Load(ctx, cfg, name)
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				shared.JSONErrorResponse(w, "", http.StatusNotFound, "Aspect taxonomy not found")
+				return
+			}
+			if err != nil {
+				shared.Logger.Error("could not load aspect taxonomy", "name", name, "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load aspect taxonomy")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, taxonomy)
+
+		case http.MethodPut:
+			var taxonomy models.AspectTaxonomy
+			if err := json.NewDecoder(r.Body).Decode(&taxonomy); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if err := Validate(&taxonomy); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid taxonomy: "+err.Error())
+				return
+			}
+			taxonomy.Name = name
+			taxonomy.UpdatedAt = time.Now()
+			if err := Save(ctx, cfg, &taxonomy); err != nil {
+				shared.Logger.Error("could not save aspect taxonomy", "name", name, "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to save aspect taxonomy")
+				return
+			}
+			shared.Logger.Info("Saved aspect taxonomy", "name", name, "aspects", len(taxonomy.Aspects))
+			shared.JSONResponse(w, "", http.StatusOK, taxonomy)
+
+		default:
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package aspects
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	// maxAspects keeps the per-comment output of the map step small.
+	maxAspects       = 20
+	maxAspectNameLen = 40
+)
+
+// namePattern matches channel IDs and profile names, which become object names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func taxonomyObjectName(name string) string {
+	return fmt.Sprintf("aspects/%s.json", name)
+}
+
+// ValidName reports whether name can be used as a taxonomy name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Validate trims the aspect names and descriptions of a taxonomy and checks
+// that there are between one and maxAspects distinct aspects.
+func Validate(taxonomy *models.AspectTaxonomy) error {
+	if len(taxonomy.Aspects) == 0 {
+		return errors.New("'aspects' must not be empty")
+	}
+	if len(taxonomy.Aspects) > maxAspects {
+		return fmt.Errorf("at most %d aspects are allowed", maxAspects)
+	}
+	seen := make(map[string]bool, len(taxonomy.Aspects))
+	for i := range taxonomy.Aspects {
+		a := &taxonomy.Aspects[i]
+		a.Name = strings.TrimSpace(a.Name)
+		a.Description = strings.TrimSpace(a.Description)
+		if a.Name == "" {
+			return errors.New("every aspect needs a 'name'")
+		}
+		if utf8.RuneCountInString(a.Name) > maxAspectNameLen {
+			return fmt.Errorf("aspect name %q is longer than %d characters", a.Name, maxAspectNameLen)
+		}
+		key := strings.ToLower(a.Name)
+		if seen[key] {
+			return fmt.Errorf("aspect %q is listed twice", a.Name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Load reads the taxonomy stored under a channel ID or profile name.
+func Load(ctx context.Context, cfg *models.AppConfig, name string) (*models.AspectTaxonomy, error) {
+	data, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, taxonomyObjectName(name))
+	if err != nil {
+		return nil, err
+	}
+	var taxonomy models.AspectTaxonomy
+	if err := json.Unmarshal(data, &taxonomy); err != nil {
+		return nil, fmt.Errorf("could not unmarshal aspect taxonomy JSON: %w", err)
+	}
+	return &taxonomy, nil
+}
+
+// Save stores a validated taxonomy under its name.
+func Save(ctx context.Context, cfg *models.AppConfig, taxonomy *models.AspectTaxonomy) error {
+	data, err := json.Marshal(taxonomy)
+	if err != nil {
+		return fmt.Errorf("could not marshal aspect taxonomy JSON: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, taxonomyObjectName(taxonomy.Name), data); err != nil {
+		return fmt.Errorf("could not upload aspect taxonomy to GCS: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the taxonomy for an analysis: the named profile when one is
+// requested, otherwise the taxonomy of the video's channel. It returns nil
+// when the channel has none, and storage.ErrObjectNotExist when the requested
+// profile does not exist.
+func Resolve(ctx context.Context, cfg *models.AppConfig, profile, channelID string) (*models.AspectTaxonomy, error) {
+	if profile != "" {
+		return Load(ctx, cfg, profile)
+	}
+	if channelID == "" || !ValidName(channelID) {
+		return nil, nil
+	}
+	taxonomy, err := Load(ctx, cfg, channelID)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+	return taxonomy, err
+}
@@ -0,0 +1,215 @@
+package batch
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/pipeline"
+	"app/pkgs/shared"
+	"app/pkgs/webhooks"
+	"app/pkgs/yt_video"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options configures a batch task.
+type Options struct {
+	// Input is a local file or gs://bucket/object with one video ID or URL per line.
+	Input string
+	// Output is where the manifest is written, a local file or gs://bucket/object.
+	Output      string
+	JobID       string
+	TaskIndex   int
+	TaskCount   int
+	Concurrency int
+}
+
+// line is a non-empty input line together with its 1-based line number.
+type line struct {
+	Number int
+	Text   string
+}
+
+func readInput(ctx context.Context, input string) ([]byte, error) {
+	if strings.HasPrefix(input, "gs://") {
+		bucket, object, err := shared.ParseGCSURI(input)
+		if err != nil {
+			return nil, err
+		}
+		return shared.GetFileFromGCS(ctx, bucket, object)
+	}
+	return os.ReadFile(input)
+}
+
+func writeOutput(ctx context.Context, output string, data []byte) error {
+	if strings.HasPrefix(output, "gs://") {
+		bucket, object, err := shared.ParseGCSURI(output)
+		if err != nil {
+			return err
+		}
+		return shared.UploadToGCS(ctx, bucket, object, data)
+	}
+	return os.WriteFile(output, data, 0o644)
+}
+
+// shardLines returns the non-blank, non-comment lines assigned to a task.
+// Lines are distributed round-robin so every task gets a similar share.
+func shardLines(data []byte, taskIndex, taskCount int) []line {
+	var lines []line
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	number, position := 0, 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if position%taskCount == taskIndex {
+			lines = append(lines, line{Number: number, Text: text})
+		}
+		position++
+	}
+	return lines
+}
+
+// Run executes the pipeline for every video assigned to this task and writes
+// the manifest. Failures of individual videos are recorded in the manifest and
+// do not stop the batch.
+func Run(ctx context.Context, cfg *models.AppConfig, opts Options) (*models.BatchManifest, error) {
+	data, err := readInput(ctx, opts.Input)
+	if err != nil {
+		return nil, fmt.Errorf("could not read input %s: %w", opts.Input, err)
+	}
+	lines := shardLines(data, opts.TaskIndex, opts.TaskCount)
+
+	manifest := &models.BatchManifest{
+		JobID:     opts.JobID,
+		TaskIndex: opts.TaskIndex,
+		TaskCount: opts.TaskCount,
+		Input:     opts.Input,
+		StartedAt: time.Now(),
+		Total:     len(lines),
+		Results:   make([]models.BatchResult, len(lines)),
+	}
+	shared.Logger.Info("Starting batch task", "jobId", opts.JobID, "taskIndex", opts.TaskIndex, "taskCount", opts.TaskCount, "videos", len(lines))
+
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, l := range lines {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, l line) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			manifest.Results[i] = runLine(ctx, cfg, l)
+		}(i, l)
+	}
+	wg.Wait()
+	webhooks.Wait()
+
+	for _, result := range manifest.Results {
+		if result.Status == "success" {
+			manifest.Succeeded++
+		} else {
+			manifest.Failed++
+		}
+	}
+	manifest.FinishedAt = time.Now()
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("could not marshal manifest: %w", err)
+	}
+	if err := writeOutput(ctx, opts.Output, manifestJSON); err != nil {
+		return manifest, fmt.Errorf("could not write manifest to %s: %w", opts.Output, err)
+	}
+	shared.Logger.Info("Finished batch task", "jobId", opts.JobID, "taskIndex", opts.TaskIndex, "succeeded", manifest.Succeeded, "failed", manifest.Failed, "manifest", opts.Output)
+	return manifest, nil
+}
+
+func runLine(ctx context.Context, cfg *models.AppConfig, l line) models.BatchResult {
+	startTime := time.Now()
+	result := models.BatchResult{Line: l.Number, Input: l.Text}
+
+	videoID, err := yt_video.ParseVideoRef(l.Text)
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		result.Duration = time.Since(startTime).String()
+		return result
+	}
+	result.VideoID = videoID
+
+	result.TrackingID, err = pipeline.Run(ctx, cfg, pipeline.Request{VideoID: videoID, Trigger: "batch"}, nil)
+	result.Status = "success"
+	if err != nil {
+		shared.Logger.Error("Batch pipeline run failed", "error", err, "videoId", videoID, "line", l.Number, "trackingId", result.TrackingID)
+		result.Status = "error"
+		result.Error = err.Error()
+	}
+	result.Duration = time.Since(startTime).String()
+	return result
+}
+
+// Main runs the batch subcommand with the given arguments and returns the
+// process exit code. Task sharding defaults to the Cloud Run Jobs
+// CLOUD_RUN_TASK_INDEX and CLOUD_RUN_TASK_COUNT variables.
+func Main(ctx context.Context, cfg *models.AppConfig, args []string) int {
+	defaultJobID := shared.GetEnvString("CLOUD_RUN_EXECUTION", "")
+	if defaultJobID == "" {
+		defaultJobID = uuid.New().String()
+	}
+
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	opts := Options{}
+	fs.StringVar(&opts.Input, "input", "", "file or gs://bucket/object with one video ID or URL per line")
+	fs.StringVar(&opts.Output, "output", "", "manifest destination, defaults to gs://<bucket>/batch/<job-id>/manifest-<task-index>.json")
+	fs.StringVar(&opts.JobID, "job-id", defaultJobID, "identifier used in the manifest and its default path")
+	fs.IntVar(&opts.TaskIndex, "task-index", shared.GetEnvInt("CLOUD_RUN_TASK_INDEX", 0), "index of this task")
+	fs.IntVar(&opts.TaskCount, "task-count", shared.GetEnvInt("CLOUD_RUN_TASK_COUNT", 1), "number of tasks sharing the input")
+	fs.IntVar(&opts.Concurrency, "concurrency", 1, "number of videos processed in parallel")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := validate(&opts, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "batch:", err)
+		fs.Usage()
+		return 2
+	}
+
+	manifest, err := Run(ctx, cfg, opts)
+	if err != nil {
+		shared.Logger.Error("Batch task failed", "error", err, "jobId", opts.JobID, "taskIndex", opts.TaskIndex)
+		return 1
+	}
+	if manifest.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func validate(opts *Options, cfg *models.AppConfig) error {
+	if opts.Input == "" {
+		return errors.New("--input is required")
+	}
+	if opts.TaskCount < 1 || opts.TaskIndex < 0 || opts.TaskIndex >= opts.TaskCount {
+		return fmt.Errorf("task index %d is out of range for %d tasks", opts.TaskIndex, opts.TaskCount)
+	}
+	if opts.Concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	if opts.Output == "" {
+		opts.Output = fmt.Sprintf("gs://%s/batch/%s/manifest-%d.json", cfg.GCSBucketName, opts.JobID, opts.TaskIndex)
+	}
+	return nil
+}
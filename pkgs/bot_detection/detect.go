@@ -0,0 +1,285 @@
+package bot_detection
+
+import (
+	"app/pkgs/models"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// minDuplicateRunes skips short comments like "first" or "great video",
+	// which real viewers write identically all the time.
+	minDuplicateRunes = 20
+	// minDuplicateGroup is the number of near-identical comments that make a wave.
+	minDuplicateGroup = 3
+	// duplicateSimilarity is the estimated Jaccard similarity of near duplicates.
+	duplicateSimilarity = 0.8
+	// burstWindow and minBurstComments define a burst: at least this many
+	// top-level comments by one author within the window.
+	burstWindow      = 10 * time.Minute
+	minBurstComments = 3
+	// minSharedDomain is the number of comments linking the same site that
+	// makes the link spam.
+	minSharedDomain = 3
+)
+
+var (
+	// urlPattern matches explicit links; the domain is the first group.
+	urlPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)(?:www\.)?([a-z0-9.-]+\.[a-z]{2,})`)
+	// shortenerPattern matches link shorteners and messenger links, with or without scheme.
+	shortenerPattern = regexp.MustCompile(`(?i)\b(?:bit\.ly|tinyurl\.com|goo\.gl|cutt\.ly|is\.gd|rb\.gy|shorturl\.at|t\.co|t\.me|wa\.me)/`)
+	// contactPattern matches invitations to continue on a messenger, e.g.
+	// "text me on telegram @winner_desk" or "WhatsApp +1 555 0100 200".
+	contactPattern = regexp.MustCompile(`(?i)\b(?:telegram|whatsapp|whats app|signal|wickr)\b.{0,40}(?:@[a-z0-9_]{3,}|\+?\d[\d ()-]{7,}\d)`)
+)
+
+// ignoredDomains are linked by regular viewers, e.g. for timestamps.
+var ignoredDomains = map[string]bool{"youtube.com": true, "youtu.be": true, "m.youtube.com": true}
+
+// Detect finds comments that look like bot activity: waves of near-duplicate
+// text, bursts of top-level comments from one author, and link spam. Comments
+// by the video's own channel are never suspected; creators reply with the
+// same thanks and link their own sites all the time.
+func Detect(all []*models.Comment) *models.BotReport {
+	comments := make([]*models.Comment, 0, len(all))
+	for _, c := range all {
+		if c.AuthorID == "" || c.AuthorID != c.ChannelID {
+			comments = append(comments, c)
+		}
+	}
+	reasons := make(map[string][]string)
+	flag := func(id, reason string) {
+		for _, r := range reasons[id] {
+			if r == reason {
+				return
+			}
+		}
+		reasons[id] = append(reasons[id], reason)
+	}
+
+	report := &models.BotReport{
+		DuplicateGroups: duplicateGroups(comments),
+		AuthorBursts:    authorBursts(comments),
+	}
+	for _, g := range report.DuplicateGroups {
+		for _, id := range g.CommentIDs {
+			flag(id, models.BotReasonDuplicate)
+		}
+	}
+	for _, b := range report.AuthorBursts {
+		for _, id := range b.CommentIDs {
+			flag(id, models.BotReasonBurst)
+		}
+	}
+	for _, id := range linkSpam(comments) {
+		flag(id, models.BotReasonLinkSpam)
+	}
+
+	for _, c := range comments {
+		rs, ok := reasons[c.ID]
+		if !ok {
+			continue
+		}
+		report.Suspects = append(report.Suspects, models.SuspectedBot{CommentID: c.ID, Reasons: rs, Text: c.Text})
+		for _, r := range rs {
+			switch r {
+			case models.BotReasonDuplicate:
+				report.DuplicateComments++
+			case models.BotReasonBurst:
+				report.BurstComments++
+			case models.BotReasonLinkSpam:
+				report.LinkSpamComments++
+			}
+		}
+	}
+	report.SuspectedComments = int64(len(report.Suspects))
+	if len(all) > 0 {
+		report.SuspectedShare = float64(report.SuspectedComments) / float64(len(all))
+	}
+	return report
+}
+
+// Without returns a copy of the video data without the suspected comments.
+func Without(data *models.VideoData, report *models.BotReport) *models.VideoData {
+	suspected := suspectedIDs(report)
+	filtered := *data
+	filtered.Comments = make([]*models.Comment, 0, len(data.Comments)-len(suspected))
+	for _, c := range data.Comments {
+		if !suspected[c.ID] {
+			filtered.Comments = append(filtered.Comments, c)
+		}
+	}
+	return &filtered
+}
+
+// Suspected returns the suspected comments of the video data, in their order.
+func Suspected(data *models.VideoData, report *models.BotReport) []*models.Comment {
+	suspected := suspectedIDs(report)
+	var comments []*models.Comment
+	for _, c := range data.Comments {
+		if suspected[c.ID] {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+func suspectedIDs(report *models.BotReport) map[string]bool {
+	suspected := make(map[string]bool, len(report.Suspects))
+	for _, s := range report.Suspects {
+		suspected[s.CommentID] = true
+	}
+	return suspected
+}
+
+// duplicateGroups clusters comments whose MinHash signatures agree on at least
+// duplicateSimilarity of their values. Candidate pairs come from LSH buckets,
+// so the comparison is close to linear in the number of comments.
+func duplicateGroups(comments []*models.Comment) []models.DuplicateGroup {
+	var candidates []int
+	var sigs [][signatureSize]uint64
+	for i, c := range comments {
+		text := normalize(c.Text)
+		if utf8.RuneCountInString(text) < minDuplicateRunes {
+			continue
+		}
+		candidates = append(candidates, i)
+		sigs = append(sigs, signature(shingles(text)))
+	}
+
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	buckets := make(map[bandKey][]int)
+	for i := range sigs {
+		for _, key := range bandKeys(&sigs[i]) {
+			for _, j := range buckets[key] {
+				if find(i) != find(j) && similarity(&sigs[i], &sigs[j]) >= duplicateSimilarity {
+					parent[find(i)] = find(j)
+				}
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range candidates {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	var groups []models.DuplicateGroup
+	for _, m := range members {
+		if len(m) < minDuplicateGroup {
+			continue
+		}
+		group := models.DuplicateGroup{Size: int64(len(m)), Text: comments[candidates[m[0]]].Text}
+		for _, i := range m {
+			group.CommentIDs = append(group.CommentIDs, comments[candidates[i]].ID)
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].CommentIDs[0] < groups[j].CommentIDs[0]
+	})
+	return groups
+}
+
+// authorBursts finds authors posting at least minBurstComments top-level
+// comments within burstWindow. Replies are ignored, since conversations in a
+// thread are naturally fast, and so are live chat messages.
+func authorBursts(comments []*models.Comment) []models.AuthorBurst {
+	byAuthor := make(map[string][]*models.Comment)
+	var authors []string
+	for _, c := range comments {
+		if c.AuthorID == "" || c.ParentID != "" || c.Source == models.SourceLiveChat || c.PublishedAt.IsZero() {
+			continue
+		}
+		if _, ok := byAuthor[c.AuthorID]; !ok {
+			authors = append(authors, c.AuthorID)
+		}
+		byAuthor[c.AuthorID] = append(byAuthor[c.AuthorID], c)
+	}
+
+	var bursts []models.AuthorBurst
+	for _, author := range authors {
+		posts := byAuthor[author]
+		if len(posts) < minBurstComments {
+			continue
+		}
+		sort.SliceStable(posts, func(i, j int) bool { return posts[i].PublishedAt.Before(posts[j].PublishedAt) })
+
+		inBurst := make([]bool, len(posts))
+		start := 0
+		for end := range posts {
+			for posts[end].PublishedAt.Sub(posts[start].PublishedAt) > burstWindow {
+				start++
+			}
+			if end-start+1 >= minBurstComments {
+				for i := start; i <= end; i++ {
+					inBurst[i] = true
+				}
+			}
+		}
+
+		var current *models.AuthorBurst
+		for i, p := range posts {
+			if !inBurst[i] {
+				current = nil
+				continue
+			}
+			if current == nil {
+				bursts = append(bursts, models.AuthorBurst{AuthorID: author, Start: p.PublishedAt})
+				current = &bursts[len(bursts)-1]
+			}
+			current.Comments++
+			current.End = p.PublishedAt
+			current.CommentIDs = append(current.CommentIDs, p.ID)
+		}
+	}
+	sort.SliceStable(bursts, func(i, j int) bool { return bursts[i].Comments > bursts[j].Comments })
+	return bursts
+}
+
+// linkSpam returns the IDs of comments that link to shorteners or messengers,
+// ask viewers to contact them on a messenger, or link a site that at least
+// minSharedDomain comments link.
+func linkSpam(comments []*models.Comment) []string {
+	domains := make(map[string][]string)
+	var spam []string
+	for _, c := range comments {
+		if shortenerPattern.MatchString(c.Text) || contactPattern.MatchString(c.Text) {
+			spam = append(spam, c.ID)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, m := range urlPattern.FindAllStringSubmatch(c.Text, -1) {
+			domain := strings.TrimPrefix(strings.ToLower(m[1]), "www.")
+			if ignoredDomains[domain] || seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			domains[domain] = append(domains[domain], c.ID)
+		}
+	}
+	for _, ids := range domains {
+		if len(ids) >= minSharedDomain {
+			spam = append(spam, ids...)
+		}
+	}
+	return spam
+}
@@ -0,0 +1,128 @@
+package bot_detection
+
+import (
+	"app/pkgs/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testChannel = "UC_owner"
+
+var testStart = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func comment(id, author, text string, offset time.Duration) *models.Comment {
+	c := &models.Comment{ID: id, ChannelID: testChannel, AuthorID: author, Text: text}
+	if offset >= 0 {
+		c.PublishedAt = testStart.Add(offset)
+	}
+	return c
+}
+
+func TestDetect(t *testing.T) {
+	const spam = "Check out my channel for free gift cards every day"
+	tests := []struct {
+		name     string
+		comments []*models.Comment
+		// want maps the suspected comment IDs to their reasons.
+		want map[string][]string
+	}{
+		{
+			name: "near-duplicate wave",
+			comments: []*models.Comment{
+				comment("a", "UC_a", spam+"!!", -1),
+				comment("b", "UC_b", "CHECK OUT my channel, for free gift cards every day", -1),
+				comment("c", "UC_c", spam+" 🎁🎁", -1),
+				comment("d", "UC_d", "The editing in the second half was much better", -1),
+			},
+			want: map[string][]string{
+				"a": {models.BotReasonDuplicate},
+				"b": {models.BotReasonDuplicate},
+				"c": {models.BotReasonDuplicate},
+			},
+		},
+		{
+			name: "sub-threshold pair",
+			comments: []*models.Comment{
+				comment("a", "UC_a", spam, -1),
+				comment("b", "UC_b", spam+"!", -1),
+				comment("c", "UC_c", "The editing in the second half was much better", -1),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "owner exemption",
+			comments: []*models.Comment{
+				comment("a", testChannel, "Thanks so much for watching, see you next week!", 0),
+				comment("b", testChannel, "Thanks so much for watching, see you next week", time.Minute),
+				comment("c", testChannel, "thanks so much for watching - see you next week", 2*time.Minute),
+				comment("d", testChannel, "Merch is up at https://shop.example.com", 3*time.Minute),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "burst at the window edge",
+			comments: []*models.Comment{
+				comment("a", "UC_a", "first", 0),
+				comment("b", "UC_a", "second", 5*time.Minute),
+				comment("c", "UC_a", "third", burstWindow),
+			},
+			want: map[string][]string{
+				"a": {models.BotReasonBurst},
+				"b": {models.BotReasonBurst},
+				"c": {models.BotReasonBurst},
+			},
+		},
+		{
+			name: "burst just past the window",
+			comments: []*models.Comment{
+				comment("a", "UC_a", "first", 0),
+				comment("b", "UC_a", "second", 5*time.Minute),
+				comment("c", "UC_a", "third", burstWindow+time.Second),
+			},
+			want: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Detect(tt.comments)
+			got := make(map[string][]string, len(report.Suspects))
+			for _, s := range report.Suspects {
+				got[s.CommentID] = s.Reasons
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suspects = %v, want %v", got, tt.want)
+			}
+			if report.SuspectedComments != int64(len(tt.want)) {
+				t.Errorf("SuspectedComments = %d, want %d", report.SuspectedComments, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSuspectedAndWithout(t *testing.T) {
+	data := &models.VideoData{Comments: []*models.Comment{
+		comment("a", "UC_a", "first", -1),
+		comment("b", "UC_b", "second", -1),
+		comment("c", "UC_c", "third", -1),
+	}}
+	report := &models.BotReport{Suspects: []models.SuspectedBot{{CommentID: "c"}, {CommentID: "a"}}}
+
+	ids := func(comments []*models.Comment) []string {
+		var ids []string
+		for _, c := range comments {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	if got := ids(Suspected(data, report)); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Suspected = %v, want [a c]", got)
+	}
+	if got := ids(Without(data, report).Comments); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Without = %v, want [b]", got)
+	}
+	if len(data.Comments) != 3 {
+		t.Errorf("Without changed the input to %d comments", len(data.Comments))
+	}
+}
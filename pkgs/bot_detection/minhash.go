@@ -0,0 +1,108 @@
+package bot_detection
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize is the length in runes of the character shingles.
+	shingleSize = 5
+	// signatureSize is the number of MinHash values per comment.
+	signatureSize = 64
+	// bands and rows split the signature for locality-sensitive hashing. Pairs
+	// with a Jaccard similarity of 0.8 share a band with a probability of over
+	// 99.9%, pairs at 0.3 with about 12%.
+	bands = 16
+	rows  = signatureSize / bands
+)
+
+// normalize lowercases text and reduces it to letters and digits separated by
+// single spaces, so that copies differing in punctuation, case or emoji match.
+func normalize(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// shingles returns the hashes of the distinct character shingles of a
+// normalized text.
+func shingles(text string) map[uint64]bool {
+	runes := []rune(text)
+	set := make(map[uint64]bool)
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i : i+shingleSize])))
+		set[h.Sum64()] = true
+	}
+	return set
+}
+
+// mix is the SplitMix64 finalizer, used to derive the hash functions of the
+// signature from a single shingle hash.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// signature computes the MinHash signature of a shingle set.
+func signature(set map[uint64]bool) [signatureSize]uint64 {
+	var sig [signatureSize]uint64
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for s := range set {
+		for i := range sig {
+			if h := mix(s + uint64(i)*0x9e3779b97f4a7c15); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// similarity estimates the Jaccard similarity of two shingle sets from their
+// signatures.
+func similarity(a, b *[signatureSize]uint64) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
+
+// bandKey identifies one band of a signature for bucketing.
+type bandKey struct {
+	band int
+	hash uint64
+}
+
+func bandKeys(sig *[signatureSize]uint64) []bandKey {
+	keys := make([]bandKey, bands)
+	for b := range bands {
+		h := uint64(b)
+		for _, v := range sig[b*rows : (b+1)*rows] {
+			h = mix(h ^ v)
+		}
+		keys[b] = bandKey{band: b, hash: h}
+	}
+	return keys
+}
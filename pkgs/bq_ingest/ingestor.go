@@ -17,13 +17,17 @@ import (
 )
 
 func recordExists(ctx context.Context, client *bigquery.Client, project, dataset, table, trackingID string) (bool, error) {
+	return rowExists(ctx, client, project, dataset, table, "tracking_id", trackingID)
+}
+
+func rowExists(ctx context.Context, client *bigquery.Client, project, dataset, table, column, value string) (bool, error) {
 	queryStr := fmt.Sprintf(
-		"SELECT COUNT(1) as count FROM `%s.%s.%s` WHERE tracking_id = @trackingID",
-		project, dataset, table,
+		"SELECT COUNT(1) as count FROM `%s.%s.%s` WHERE %s = @value",
+		project, dataset, table, column,
 	)
 	q := client.Query(queryStr)
 	q.Parameters = []bigquery.QueryParameter{
-		{Name: "trackingID", Value: trackingID},
+		{Name: "value", Value: value},
 	}
 
 	it, err := q.Read(ctx)
@@ -39,6 +43,198 @@ func recordExists(ctx context.Context, client *bigquery.Client, project, dataset
 	return row.Count > 0, nil
 }
 
+// Ingest inserts the video, its comments and, when record is not nil, its
+// analysis into BigQuery. Rows that already exist for the tracking ID are
+// skipped. It reports whether anything was inserted along with a message per step.
+func Ingest(ctx context.Context, cfg *models.AppConfig, fullData *models.VideoData, record *models.AnalysisRecord) (bool, []string, error) {
+	trackingID := fullData.TrackingID
+	client, err := bigquery.NewClient(ctx, cfg.GCPProject)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	var messages []string
+	var ingestionOccurred bool
+
+	rawExists, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "videos", trackingID)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not query for existing raw data: %w", err)
+	}
+
+	if rawExists {
+		shared.Logger.Info("Raw data already exists in BigQuery. Skipping.", "trackingId", trackingID)
+		messages = append(messages, fmt.Sprintf("Raw data for tracking ID %s already exists in BigQuery. Skipping.", trackingID))
+	} else {
+		shared.Logger.Info("New raw data tracking ID. Proceeding with ingestion.", "trackingId", trackingID)
+		videoForBQ := models.VideoRecord{
+			ID:            fullData.ID,
+			ChannelID:     fullData.ChannelID,
+			ChannelTitle:  fullData.ChannelTitle,
+			TrackingID:    fullData.TrackingID,
+			RunDate:       fullData.RunDate,
+			Title:         fullData.Title,
+			Description:   fullData.Description,
+			ThumbnailURL:  fullData.ThumbnailURL,
+			Duration:      fullData.Duration,
+			CategoryID:    fullData.CategoryID,
+			ViewCount:     fullData.ViewCount,
+			LikeCount:     fullData.LikeCount,
+			FavoriteCount: fullData.FavoriteCount,
+			CommentCount:  fullData.CommentCount,
+		}
+		videoInserter := client.Dataset(cfg.BQDataset).Table("videos").Inserter()
+		if err := videoInserter.Put(ctx, &videoForBQ); err != nil {
+			return ingestionOccurred, messages, fmt.Errorf("could not insert video data into BigQuery: %w", err)
+		}
+		messages = append(messages, fmt.Sprintf("Successfully ingested video data for video ID %s.", fullData.ID))
+		ingestionOccurred = true
+
+		if len(fullData.Comments) > 0 {
+			commentsForBQ := fullData.Comments
+			for i := range commentsForBQ {
+				commentsForBQ[i].VideoID = fullData.ID
+			}
+			commentsInserter := client.Dataset(cfg.BQDataset).Table("comments").Inserter()
+			if err := commentsInserter.Put(ctx, commentsForBQ); err != nil {
+				return ingestionOccurred, messages, fmt.Errorf("could not insert comments data into BigQuery: %w", err)
+			}
+			messages = append(messages, fmt.Sprintf("Successfully ingested %d comments.", len(commentsForBQ)))
+		}
+	}
+
+	if record == nil {
+		return ingestionOccurred, messages, nil
+	}
+
+	analyzedExists, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "analyzed", trackingID)
+	if err != nil {
+		return ingestionOccurred, messages, fmt.Errorf("could not query for existing analyzed data: %w", err)
+	}
+
+	if analyzedExists {
+		shared.Logger.Info("Analyzed data already exists in BigQuery. Skipping.", "trackingId", trackingID)
+		messages = append(messages, fmt.Sprintf("Analyzed data for tracking ID %s already exists in BigQuery. Skipping.", trackingID))
+	} else {
+		inserter := client.Dataset(cfg.BQDataset).Table("analyzed").Inserter()
+		if err := inserter.Put(ctx, record); err != nil {
+			return ingestionOccurred, messages, fmt.Errorf("could not insert analyzed data into BigQuery: %w", err)
+		}
+		shared.Logger.Info("Successfully ingested analyzed data.", "trackingId", trackingID)
+		messages = append(messages, fmt.Sprintf("Successfully ingested analyzed data for tracking ID %s.", trackingID))
+		ingestionOccurred = true
+	}
+
+	if len(record.Mentions) > 0 {
+		mentionsExist, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "mentions", trackingID)
+		if err != nil {
+			return ingestionOccurred, messages, fmt.Errorf("could not query for existing mentions: %w", err)
+		}
+		if mentionsExist {
+			shared.Logger.Info("Mentions already exist in BigQuery. Skipping.", "trackingId", trackingID)
+			messages = append(messages, fmt.Sprintf("Mentions for tracking ID %s already exist in BigQuery. Skipping.", trackingID))
+		} else {
+			rows := make([]*models.MentionRecord, 0, len(record.Mentions))
+			for _, m := range record.Mentions {
+				rows = append(rows, &models.MentionRecord{
+					TrackingID:    trackingID,
+					VideoID:       fullData.ID,
+					ChannelID:     fullData.ChannelID,
+					RunDate:       record.RunDate,
+					Entity:        m.Entity,
+					Type:          m.Type,
+					Mentions:      m.Mentions,
+					Share:         m.Share,
+					Positive:      m.Positive,
+					Negative:      m.Negative,
+					Neutral:       m.Neutral,
+					LikeTotal:     m.LikeTotal,
+					InDescription: m.InDescription,
+					TopQuotes:     m.TopQuotes,
+				})
+			}
+			mentionsInserter := client.Dataset(cfg.BQDataset).Table("mentions").Inserter()
+			if err := mentionsInserter.Put(ctx, rows); err != nil {
+				return ingestionOccurred, messages, fmt.Errorf("could not insert mentions into BigQuery: %w", err)
+			}
+			messages = append(messages, fmt.Sprintf("Successfully ingested %d entity mentions.", len(rows)))
+			ingestionOccurred = true
+		}
+	}
+
+	if len(record.AspectScorecard) > 0 {
+		aspectsExist, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "aspects", trackingID)
+		if err != nil {
+			return ingestionOccurred, messages, fmt.Errorf("could not query for existing aspect scores: %w", err)
+		}
+		if aspectsExist {
+			shared.Logger.Info("Aspect scores already exist in BigQuery. Skipping.", "trackingId", trackingID)
+			messages = append(messages, fmt.Sprintf("Aspect scores for tracking ID %s already exist in BigQuery. Skipping.", trackingID))
+		} else {
+			rows := make([]*models.AspectRecord, 0, len(record.AspectScorecard))
+			for _, a := range record.AspectScorecard {
+				rows = append(rows, &models.AspectRecord{
+					TrackingID:   trackingID,
+					VideoID:      fullData.ID,
+					ChannelID:    fullData.ChannelID,
+					RunDate:      record.RunDate,
+					Taxonomy:     record.AspectTaxonomy,
+					Aspect:       a.Aspect,
+					Mentions:     a.Mentions,
+					Share:        a.Share,
+					Positive:     a.Positive,
+					Negative:     a.Negative,
+					Neutral:      a.Neutral,
+					NetSentiment: a.NetSentiment,
+					TopQuotes:    a.TopQuotes,
+				})
+			}
+			aspectsInserter := client.Dataset(cfg.BQDataset).Table("aspects").Inserter()
+			if err := aspectsInserter.Put(ctx, rows); err != nil {
+				return ingestionOccurred, messages, fmt.Errorf("could not insert aspect scores into BigQuery: %w", err)
+			}
+			messages = append(messages, fmt.Sprintf("Successfully ingested %d aspect scores.", len(rows)))
+			ingestionOccurred = true
+		}
+	}
+
+	if len(record.MomentHeatmap) > 0 {
+		momentsExist, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "moments", trackingID)
+		if err != nil {
+			return ingestionOccurred, messages, fmt.Errorf("could not query for existing moments: %w", err)
+		}
+		if momentsExist {
+			shared.Logger.Info("Moments already exist in BigQuery. Skipping.", "trackingId", trackingID)
+			messages = append(messages, fmt.Sprintf("Moments for tracking ID %s already exist in BigQuery. Skipping.", trackingID))
+		} else {
+			moments := make([]*models.MomentRecord, 0, len(record.MomentHeatmap))
+			for _, m := range record.MomentHeatmap {
+				moments = append(moments, &models.MomentRecord{
+					TrackingID:   trackingID,
+					VideoID:      fullData.ID,
+					RunDate:      record.RunDate,
+					StartSeconds: int64(m.StartSeconds),
+					EndSeconds:   int64(m.EndSeconds),
+					Label:        m.Label,
+					PeakSecond:   int64(m.PeakSecond),
+					Mentions:     m.Mentions,
+					Positive:     m.Positive,
+					Negative:     m.Negative,
+					Neutral:      m.Neutral,
+					TopQuotes:    m.TopQuotes,
+				})
+			}
+			momentsInserter := client.Dataset(cfg.BQDataset).Table("moments").Inserter()
+			if err := momentsInserter.Put(ctx, moments); err != nil {
+				return ingestionOccurred, messages, fmt.Errorf("could not insert moments into BigQuery: %w", err)
+			}
+			messages = append(messages, fmt.Sprintf("Successfully ingested %d moment segments.", len(moments)))
+			ingestionOccurred = true
+		}
+	}
+	return ingestionOccurred, messages, nil
+}
+
 func IngestData(cfg *models.AppConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
@@ -51,136 +247,37 @@ func IngestData(cfg *models.AppConfig) http.HandlerFunc {
 		}
 		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
 
-		client, err := bigquery.NewClient(ctx, cfg.GCPProject)
+		fullData, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
 		if err != nil {
-			err = fmt.Errorf("could not create BigQuery client: %w", err)
+			err = fmt.Errorf("could not load raw data from GCS: %w", err)
 			shared.Logger.Error(err.Error(), "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to connect to BigQuery")
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve raw data file")
 			return
 		}
-		defer client.Close()
+		fullData.TrackingID = trackingID
 
 		var messages []string
-		var ingestionOccurred bool
-
-		rawExists, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "videos", trackingID)
+		analysisRecord, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
 		if err != nil {
-			err = fmt.Errorf("could not query for existing raw data: %w", err)
-			shared.Logger.Error(err.Error(), "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to query BigQuery for raw data")
-			return
-		}
-
-		if rawExists {
-			shared.Logger.Info("Raw data already exists in BigQuery. Skipping.", "trackingId", trackingID)
-			messages = append(messages, fmt.Sprintf("Raw data for tracking ID %s already exists in BigQuery. Skipping.", trackingID))
-		} else {
-			shared.Logger.Info("New raw data tracking ID. Proceeding with ingestion.", "trackingId", trackingID)
-			objectName := fmt.Sprintf("%s.json", trackingID)
-			fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, objectName)
-			if err != nil {
-				err = fmt.Errorf("could not get raw data file from GCS: %w", err)
-				shared.Logger.Error(err.Error(), "trackingId", trackingID)
-				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve raw data file")
-				return
-			}
-
-			var fullData models.VideoData
-			if err := json.Unmarshal(fileData, &fullData); err != nil {
-				err = fmt.Errorf("could not unmarshal raw data JSON: %w", err)
+			if !errors.Is(err, storage.ErrObjectNotExist) {
+				err = fmt.Errorf("could not load analyzed data from GCS: %w", err)
 				shared.Logger.Error(err.Error(), "trackingId", trackingID)
-				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Invalid raw data format")
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve analyzed data file")
 				return
 			}
-
-			videoForBQ := models.VideoRecord{
-				ID:            fullData.ID,
-				ChannelID:     fullData.ChannelID,
-				ChannelTitle:  fullData.ChannelTitle,
-				TrackingID:    fullData.TrackingID,
-				RunDate:       fullData.RunDate,
-				Title:         fullData.Title,
-				Description:   fullData.Description,
-				ThumbnailURL:  fullData.ThumbnailURL,
-				Duration:      fullData.Duration,
-				CategoryID:    fullData.CategoryID,
-				ViewCount:     fullData.ViewCount,
-				LikeCount:     fullData.LikeCount,
-				FavoriteCount: fullData.FavoriteCount,
-				CommentCount:  fullData.CommentCount,
-			}
-			videoInserter := client.Dataset(cfg.BQDataset).Table("videos").Inserter()
-			if err := videoInserter.Put(ctx, &videoForBQ); err != nil {
-				err = fmt.Errorf("could not insert video data into BigQuery: %w", err)
-				shared.Logger.Error(err.Error(), "trackingId", trackingID)
-				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to ingest video data")
-				return
-			}
-			messages = append(messages, fmt.Sprintf("Successfully ingested video data for video ID %s.", fullData.ID))
-			ingestionOccurred = true
-
-			if len(fullData.Comments) > 0 {
-				commentsForBQ := fullData.Comments
-				for i := range commentsForBQ {
-					commentsForBQ[i].VideoID = fullData.ID
-				}
-				commentsInserter := client.Dataset(cfg.BQDataset).Table("comments").Inserter()
-				if err := commentsInserter.Put(ctx, commentsForBQ); err != nil {
-					err = fmt.Errorf("could not insert comments data into BigQuery: %w", err)
-					shared.Logger.Error(err.Error(), "trackingId", trackingID)
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to ingest comments data")
-					return
-				}
-				messages = append(messages, fmt.Sprintf("Successfully ingested %d comments.", len(commentsForBQ)))
-			}
+			msg := fmt.Sprintf("Analyzed data file %s_analyzed.json not found in GCS. Skipping.", trackingID)
+			shared.Logger.Info(msg, "trackingId", trackingID)
+			messages = append(messages, msg)
+			analysisRecord = nil
 		}
 
-		analyzedExists, err := recordExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "analyzed", trackingID)
+		ingestionOccurred, ingestMessages, err := Ingest(ctx, cfg, fullData, analysisRecord)
 		if err != nil {
-			err = fmt.Errorf("could not query for existing analyzed data: %w", err)
 			shared.Logger.Error(err.Error(), "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to query BigQuery for analyzed data")
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to ingest data into BigQuery")
 			return
 		}
-
-		if analyzedExists {
-			shared.Logger.Info("Analyzed data already exists in BigQuery. Skipping.", "trackingId", trackingID)
-			messages = append(messages, fmt.Sprintf("Analyzed data for tracking ID %s already exists in BigQuery. Skipping.", trackingID))
-		} else {
-			analyzedObjectName := fmt.Sprintf("%s_analyzed.json", trackingID)
-			fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, analyzedObjectName)
-			if err != nil {
-				if errors.Is(err, storage.ErrObjectNotExist) {
-					msg := fmt.Sprintf("Analyzed data file %s not found in GCS. Skipping.", analyzedObjectName)
-					shared.Logger.Info(msg, "trackingId", trackingID)
-					messages = append(messages, msg)
-				} else {
-					err = fmt.Errorf("could not get analyzed file from GCS: %w", err)
-					shared.Logger.Error(err.Error(), "trackingId", trackingID)
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve analyzed data file")
-					return
-				}
-			} else {
-				var analysisRecord models.AnalysisRecord
-				if err := json.Unmarshal(fileData, &analysisRecord); err != nil {
-					err = fmt.Errorf("could not unmarshal analyzed data JSON: %w", err)
-					shared.Logger.Error(err.Error(), "trackingId", trackingID)
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Invalid analyzed data format")
-					return
-				}
-
-				inserter := client.Dataset(cfg.BQDataset).Table("analyzed").Inserter()
-				if err := inserter.Put(ctx, &analysisRecord); err != nil {
-					err = fmt.Errorf("could not insert analyzed data into BigQuery: %w", err)
-					shared.Logger.Error(err.Error(), "trackingId", trackingID)
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to ingest analyzed data")
-					return
-				}
-				shared.Logger.Info("Successfully ingested analyzed data.", "trackingId", trackingID)
-				messages = append(messages, fmt.Sprintf("Successfully ingested analyzed data for tracking ID %s.", trackingID))
-				ingestionOccurred = true
-			}
-		}
+		messages = append(ingestMessages, messages...)
 
 		status := "skipped"
 		if ingestionOccurred {
@@ -202,4 +299,4 @@ func IngestData(cfg *models.AppConfig) http.HandlerFunc {
 			shared.Logger.Error("could not write JSON response", "error", err, "trackingId", trackingID)
 		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,84 @@
+package bq_ingest
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// IngestChannelReport ingests <reportId>_channel_report.json into the
+// 'channel_reports' table. Like IngestData it is idempotent per report ID.
+func IngestChannelReport(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		reportID := r.URL.Query().Get("reportId")
+		if reportID == "" {
+			shared.Logger.Warn("Missing 'reportId' query parameter")
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'reportId' query parameter")
+			return
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "reportId", reportID)
+
+		client, err := bigquery.NewClient(ctx, cfg.GCPProject)
+		if err != nil {
+			err = fmt.Errorf("could not create BigQuery client: %w", err)
+			shared.Logger.Error(err.Error(), "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to connect to BigQuery")
+			return
+		}
+		defer client.Close()
+
+		exists, err := rowExists(ctx, client, cfg.GCPProject, cfg.BQDataset, "channel_reports", "report_id", reportID)
+		if err != nil {
+			err = fmt.Errorf("could not query for existing channel report: %w", err)
+			shared.Logger.Error(err.Error(), "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to query BigQuery for channel report")
+			return
+		}
+
+		response := models.APIResponse{
+			TrackingID: reportID,
+			Status:     "skipped",
+			Message:    fmt.Sprintf("Channel report %s already exists in BigQuery. Skipping.", reportID),
+		}
+
+		if !exists {
+			objectName := fmt.Sprintf("%s_channel_report.json", reportID)
+			fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, objectName)
+			if err != nil {
+				err = fmt.Errorf("could not get channel report file from GCS: %w", err)
+				shared.Logger.Error(err.Error(), "reportId", reportID)
+				shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to retrieve channel report file")
+				return
+			}
+
+			var report models.ChannelReport
+			if err := json.Unmarshal(fileData, &report); err != nil {
+				err = fmt.Errorf("could not unmarshal channel report JSON: %w", err)
+				shared.Logger.Error(err.Error(), "reportId", reportID)
+				shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Invalid channel report format")
+				return
+			}
+
+			inserter := client.Dataset(cfg.BQDataset).Table("channel_reports").Inserter()
+			if err := inserter.Put(ctx, &report); err != nil {
+				err = fmt.Errorf("could not insert channel report into BigQuery: %w", err)
+				shared.Logger.Error(err.Error(), "reportId", reportID)
+				shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to ingest channel report")
+				return
+			}
+			shared.Logger.Info("Successfully ingested channel report.", "reportId", reportID)
+			response.Status = "success"
+			response.Message = fmt.Sprintf("Successfully ingested channel report %s for channel %s.", reportID, report.ChannelID)
+		}
+
+		response.ProcessingTime = time.Since(startTime).String()
+		shared.JSONResponse(w, reportID, http.StatusOK, response)
+	}
+}
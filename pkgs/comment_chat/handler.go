@@ -0,0 +1,142 @@
+package comment_chat
+
+import (
+	"app/pkgs/gemini_magic"
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+)
+
+// Chat answers questions about the comments of an analyzed run with
+// GET /runs/{id}/chat?q=<question>&sessionId=<sessionId>. The answer is
+// streamed as server-sent events and cites the comments it relies on. Omitting
+// sessionId starts a new session; omitting q returns the stored session.
+func Chat(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		trackingID := r.PathValue("id")
+		question := strings.TrimSpace(r.URL.Query().Get("q"))
+		sessionID := r.URL.Query().Get("sessionId")
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID, "sessionId", sessionID)
+
+		if sessionID != "" {
+			if _, err := uuid.Parse(sessionID); err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "'sessionId' must be a UUID")
+				return
+			}
+		}
+		if question == "" {
+			if sessionID == "" {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'q' or 'sessionId' query parameter")
+				return
+			}
+			session, err := LoadSession(ctx, cfg, trackingID, sessionID)
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Chat session not found")
+				return
+			}
+			if err != nil {
+				shared.Logger.Error("Failed to load chat session", "error", err, "trackingId", trackingID, "sessionId", sessionID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to load chat session")
+				return
+			}
+			shared.JSONResponse(w, trackingID, http.StatusOK, session)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+			return
+		}
+		send := func(event models.ChatEvent) {
+			sendSSEMessage(w, flusher, event)
+		}
+
+		if err := answer(ctx, cfg, trackingID, sessionID, question, send); err != nil {
+			shared.Logger.Error("Failed to answer chat question", "error", err, "trackingId", trackingID, "sessionId", sessionID)
+			send(models.ChatEvent{Status: "error", Message: err.Error()})
+		}
+	}
+}
+
+// answer retrieves the comments relevant to a question, streams the answer
+// and appends both to the session.
+func answer(ctx context.Context, cfg *models.AppConfig, trackingID, sessionID, question string, send func(models.ChatEvent)) error {
+	data, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return errors.New("run not found")
+	}
+	if err != nil {
+		return fmt.Errorf("could not load raw data: %w", err)
+	}
+	record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return errors.New("run has not been analyzed yet")
+	}
+	if err != nil {
+		return fmt.Errorf("could not load analysis: %w", err)
+	}
+
+	now := time.Now()
+	session := &models.ChatSession{SessionID: uuid.New().String(), TrackingID: trackingID, CreatedAt: now}
+	if sessionID != "" {
+		session, err = LoadSession(ctx, cfg, trackingID, sessionID)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return errors.New("chat session not found")
+		}
+		if err != nil {
+			return fmt.Errorf("could not load chat session: %w", err)
+		}
+	}
+
+	comments, err := retrieve(ctx, cfg, trackingID, data, session.Messages, question)
+	if err != nil {
+		return fmt.Errorf("could not retrieve comments: %w", err)
+	}
+	send(models.ChatEvent{Status: "processing", Message: fmt.Sprintf("Found %d relevant comments", len(comments)), SessionID: session.SessionID})
+
+	text, err := gemini_magic.StreamChatAnswer(ctx, cfg, data, record, session.Messages, question, comments, func(delta string) {
+		send(models.ChatEvent{Status: "delta", Text: delta})
+	})
+	if err != nil {
+		return fmt.Errorf("could not generate answer: %w", err)
+	}
+	cited := citations(text, comments)
+
+	session.Messages = append(session.Messages,
+		models.ChatMessage{Role: models.ChatRoleUser, Text: question, CreatedAt: now},
+		models.ChatMessage{Role: models.ChatRoleModel, Text: text, Citations: cited, CreatedAt: time.Now()},
+	)
+	session.UpdatedAt = time.Now()
+	if err := saveSession(ctx, cfg, session); err != nil {
+		// The answer has been delivered; only the follow-up context is lost.
+		shared.Logger.Error("Failed to store chat session", "error", err, "trackingId", trackingID, "sessionId", session.SessionID)
+	}
+
+	send(models.ChatEvent{Status: "complete", SessionID: session.SessionID, Citations: cited})
+	return nil
+}
+
+func sendSSEMessage(w http.ResponseWriter, flusher http.Flusher, data interface{}) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		shared.Logger.Error("Failed to marshal SSE data", "error", err)
+		return
+	}
+
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+	flusher.Flush()
+}
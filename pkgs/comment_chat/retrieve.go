@@ -0,0 +1,88 @@
+package comment_chat
+
+import (
+	"app/pkgs/comment_search"
+	"app/pkgs/models"
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxContextComments bounds the comments sent with a question.
+const maxContextComments = 30
+
+// retrieve returns the comments most relevant to a question. With an
+// embedding model configured they are found by semantic search, otherwise
+// by the number of question keywords they contain. The previous question is
+// included in the query, so follow-ups like "and the lighting?" keep their
+// context.
+func retrieve(ctx context.Context, cfg *models.AppConfig, trackingID string, data *models.VideoData, history []models.ChatMessage, question string) ([]*models.Comment, error) {
+	query := question
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == models.ChatRoleUser {
+			query = history[i].Text + "\n" + question
+			break
+		}
+	}
+
+	if !comment_search.Enabled(cfg) {
+		return keywordSearch(data.Comments, query, maxContextComments), nil
+	}
+	results, err := comment_search.SearchComments(ctx, cfg, trackingID, data, query, maxContextComments, -1)
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]*models.Comment, 0, len(results))
+	for _, r := range results {
+		comments = append(comments, r.Comment)
+	}
+	return comments, nil
+}
+
+// keywordSearch ranks comments by the number of distinct query words of four
+// or more letters they contain, then by likes. Without any match the most
+// liked comments are returned.
+func keywordSearch(comments []*models.Comment, query string, limit int) []*models.Comment {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) }) {
+		if utf8.RuneCountInString(word) >= 4 && !seen[word] {
+			seen[word] = true
+			keywords = append(keywords, word)
+		}
+	}
+
+	type scored struct {
+		comment *models.Comment
+		score   int
+	}
+	var ranked []scored
+	for _, c := range comments {
+		text := strings.ToLower(c.Text)
+		score := 0
+		for _, k := range keywords {
+			if strings.Contains(text, k) {
+				score++
+			}
+		}
+		ranked = append(ranked, scored{c, score})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].comment.LikeCount > ranked[j].comment.LikeCount
+	})
+
+	matched := len(ranked) > 0 && ranked[0].score > 0
+	var result []*models.Comment
+	for _, r := range ranked {
+		if len(result) == limit || (matched && r.score == 0) {
+			break
+		}
+		result = append(result, r.comment)
+	}
+	return result
+}
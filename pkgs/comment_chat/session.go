@@ -0,0 +1,63 @@
+package comment_chat
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// citationPattern matches bracketed references like [Ugz123] or [Ugz1, Ugz2].
+var citationPattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+func sessionObjectName(trackingID, sessionID string) string {
+	return fmt.Sprintf("chat/%s/%s.json", trackingID, sessionID)
+}
+
+// LoadSession returns a stored chat session.
+func LoadSession(ctx context.Context, cfg *models.AppConfig, trackingID, sessionID string) (*models.ChatSession, error) {
+	data, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, sessionObjectName(trackingID, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	var session models.ChatSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chat session JSON: %w", err)
+	}
+	return &session, nil
+}
+
+func saveSession(ctx context.Context, cfg *models.AppConfig, session *models.ChatSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("could not marshal chat session JSON: %w", err)
+	}
+	return shared.UploadToGCS(ctx, cfg.GCSBucketName, sessionObjectName(session.TrackingID, session.SessionID), data)
+}
+
+// citations returns the comments an answer cites, in order of first citation.
+// References to comments that were not retrieved for the question are
+// ignored, so made-up IDs and timestamps like [3:41] never become citations.
+func citations(answer string, comments []*models.Comment) []models.ChatCitation {
+	byID := make(map[string]*models.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+	seen := make(map[string]bool)
+	var cited []models.ChatCitation
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, id := range strings.Split(match[1], ",") {
+			id = strings.TrimSpace(id)
+			c, ok := byID[id]
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			cited = append(cited, models.ChatCitation{CommentID: c.ID, Text: c.Text, LikeCount: c.LikeCount})
+		}
+	}
+	return cited
+}
@@ -0,0 +1,174 @@
+package comment_import
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"app/pkgs/yt_video"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxUploadBytes limits the size of an uploaded export.
+const maxUploadBytes = 100 << 20
+
+// Import converts an exported comment file into VideoData. Video metadata is
+// taken from the request, or from the YouTube API when FetchMetadata is set.
+func Import(ctx context.Context, cfg *models.AppConfig, data []byte, req models.ImportRequest, trackingID string) (*models.VideoData, error) {
+	if req.Format == "" {
+		req.Format = FormatCSV
+	}
+	comments, videoID, err := Parse(data, req.Format, req.Mapping, req.VideoID)
+	if err != nil {
+		return nil, err
+	}
+
+	var video *models.VideoData
+	if req.FetchMetadata {
+		if videoID == "" {
+			return nil, fmt.Errorf("a video ID is required to fetch metadata")
+		}
+		if video, err = yt_video.FetchDetails(ctx, cfg, videoID, trackingID); err != nil {
+			return nil, fmt.Errorf("could not fetch video metadata: %w", err)
+		}
+	} else {
+		if videoID == "" {
+			videoID = "import-" + trackingID
+		}
+		video = &models.VideoData{
+			ID:           videoID,
+			ChannelID:    req.ChannelID,
+			ChannelTitle: req.ChannelTitle,
+			TrackingID:   trackingID,
+			RunDate:      time.Now().Format("2006-01-02"),
+			Title:        req.Title,
+			Description:  req.Description,
+		}
+	}
+
+	if len(comments) > cfg.MaxCommentsToFetch {
+		shared.Logger.Info("Import exceeds comment limit. Truncating.", "limit", cfg.MaxCommentsToFetch, "count", len(comments), "trackingId", trackingID)
+		comments = comments[:cfg.MaxCommentsToFetch]
+	}
+	for _, comment := range comments {
+		comment.ChannelID = video.ChannelID
+		comment.TrackingID = trackingID
+		comment.RunDate = video.RunDate
+	}
+	video.Comments = comments
+	if video.CommentCount == 0 {
+		video.CommentCount = int64(len(comments))
+	}
+	return video, nil
+}
+
+// requestFromForm reads the import options from the multipart form fields.
+func requestFromForm(r *http.Request, fileName string) (models.ImportRequest, error) {
+	req := models.ImportRequest{
+		Format:       strings.ToLower(r.FormValue("format")),
+		VideoID:      strings.TrimSpace(r.FormValue("video_id")),
+		Title:        r.FormValue("title"),
+		Description:  r.FormValue("description"),
+		ChannelID:    r.FormValue("channel_id"),
+		ChannelTitle: r.FormValue("channel_title"),
+	}
+	if req.Format == "" {
+		req.Format = DetectFormat(fileName)
+	}
+	if strings.Contains(req.VideoID, "/") {
+		videoID, err := yt_video.ExtractVideoID(req.VideoID)
+		if err != nil {
+			return req, err
+		}
+		req.VideoID = videoID
+	}
+	if value := r.FormValue("fetch_metadata"); value != "" {
+		fetch, err := strconv.ParseBool(value)
+		if err != nil {
+			return req, fmt.Errorf("invalid 'fetch_metadata' value: %w", err)
+		}
+		req.FetchMetadata = fetch
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return req, fmt.Errorf("invalid 'mapping' JSON: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// ImportComments accepts a multipart upload of a CSV, JSONL or Takeout export,
+// converts it into VideoData and stores it as <trackingId>.json so that /magic
+// and /ingest can process it like fetched data.
+func ImportComments(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		trackingID := uuid.New().String()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		if r.Method != http.MethodPost {
+			shared.JSONErrorResponse(w, trackingID, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, fmt.Sprintf("Invalid multipart form: %v", err))
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'file' form field")
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Failed to read uploaded file")
+			return
+		}
+
+		req, err := requestFromForm(r, header.Filename)
+		if err != nil {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		video, err := Import(ctx, cfg, data, req, trackingID)
+		if err != nil {
+			shared.Logger.Warn("Failed to import comments", "error", err, "file", header.Filename, "format", req.Format, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, fmt.Sprintf("Failed to import comments: %v", err))
+			return
+		}
+
+		jsonData, err := json.Marshal(video)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal imported data", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to marshal data for storage")
+			return
+		}
+		objectName := trackingID + ".json"
+		if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, objectName, jsonData); err != nil {
+			shared.Logger.Error("GCS upload failed", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to save data to GCS")
+			return
+		}
+		shared.Logger.Info("Successfully imported comments", "count", len(video.Comments), "format", req.Format, "videoId", video.ID, "object", objectName, "trackingId", trackingID)
+
+		shared.JSONResponse(w, trackingID, http.StatusOK, models.APIResponse{
+			TrackingID:     trackingID,
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "success",
+			Message:        fmt.Sprintf("Successfully imported %d comments from %s.", len(video.Comments), header.Filename),
+			NextActionURI:  fmt.Sprintf("/magic?trackingId=%s", trackingID),
+		})
+	}
+}
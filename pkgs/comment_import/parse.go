@@ -0,0 +1,345 @@
+package comment_import
+
+import (
+	"app/pkgs/models"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatTakeout = "takeout"
+)
+
+// defaultMappings holds the column names used when a request does not map a
+// field. CSV and JSONL default to the JSON field names of models.Comment;
+// Takeout uses the headers of the comments.csv file in a YouTube Takeout export.
+var defaultMappings = map[string]models.ColumnMapping{
+	FormatCSV: {
+		ID: "id", ParentID: "parent_id", Text: "text",
+		LikeCount: "like_count", ReplyCount: "reply_count", VideoID: "video_id",
+		PublishedAt: "published_at", AuthorID: "author_channel_id",
+		Hearted: "hearted", Pinned: "pinned",
+	},
+	FormatJSONL: {
+		ID: "id", ParentID: "parent_id", Text: "text",
+		LikeCount: "like_count", ReplyCount: "reply_count", VideoID: "video_id",
+		PublishedAt: "published_at", AuthorID: "author_channel_id",
+		Hearted: "hearted", Pinned: "pinned",
+	},
+	FormatTakeout: {
+		ID: "Comment ID", ParentID: "Parent Comment ID", Text: "Comment Text",
+		VideoID: "Video ID", PublishedAt: "Comment Create Timestamp", AuthorID: "Channel ID",
+	},
+}
+
+// DetectFormat guesses the import format from a file name.
+func DetectFormat(fileName string) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".zip":
+		return FormatTakeout
+	default:
+		return FormatCSV
+	}
+}
+
+// resolveMapping overlays the non-empty fields of mapping on the format defaults.
+func resolveMapping(format string, mapping models.ColumnMapping) (models.ColumnMapping, error) {
+	resolved, ok := defaultMappings[format]
+	if !ok {
+		return resolved, fmt.Errorf("unsupported import format %q, expected csv, jsonl or takeout", format)
+	}
+	overlay := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	overlay(&resolved.ID, mapping.ID)
+	overlay(&resolved.ParentID, mapping.ParentID)
+	overlay(&resolved.Text, mapping.Text)
+	overlay(&resolved.LikeCount, mapping.LikeCount)
+	overlay(&resolved.ReplyCount, mapping.ReplyCount)
+	overlay(&resolved.VideoID, mapping.VideoID)
+	overlay(&resolved.PublishedAt, mapping.PublishedAt)
+	overlay(&resolved.AuthorID, mapping.AuthorID)
+	overlay(&resolved.Hearted, mapping.Hearted)
+	overlay(&resolved.Pinned, mapping.Pinned)
+	return resolved, nil
+}
+
+// row gives access to the fields of one CSV record or JSONL object by name.
+type row func(name string) string
+
+// rowComment converts a row into a comment and the ID of the video it belongs to.
+func rowComment(get row, mapping models.ColumnMapping, takeout bool) (*models.Comment, string, error) {
+	text := get(mapping.Text)
+	if takeout {
+		text = takeoutText(text)
+	}
+	comment := &models.Comment{
+		ID:       get(mapping.ID),
+		ParentID: get(mapping.ParentID),
+		AuthorID: get(mapping.AuthorID),
+		Text:     text,
+		Source:   models.SourceImport,
+		Hearted:  parseFlag(get(mapping.Hearted)),
+		Pinned:   parseFlag(get(mapping.Pinned)),
+	}
+	var err error
+	if value := get(mapping.PublishedAt); value != "" {
+		if comment.PublishedAt, err = parseTime(value); err != nil {
+			return nil, "", fmt.Errorf("invalid %s: %w", mapping.PublishedAt, err)
+		}
+	}
+	if comment.LikeCount, err = parseCount(get(mapping.LikeCount)); err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", mapping.LikeCount, err)
+	}
+	if comment.ReplyCount, err = parseCount(get(mapping.ReplyCount)); err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", mapping.ReplyCount, err)
+	}
+	return comment, get(mapping.VideoID), nil
+}
+
+// timeLayouts are the timestamp formats accepted for published_at.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", value)
+}
+
+func parseCount(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(f), nil
+}
+
+// parseFlag reads a boolean column such as hearted or pinned. Anything but a
+// clear yes counts as no.
+func parseFlag(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "y":
+		return true
+	}
+	return false
+}
+
+// takeoutText joins the text segments of a Takeout comment, which are stored
+// as a comma-separated list of JSON objects such as {"text":"Nice "},{"text":"video"}.
+func takeoutText(raw string) string {
+	var segments []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte("["+raw+"]"), &segments); err != nil {
+		return raw
+	}
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+func parseCSV(r io.Reader, mapping models.ColumnMapping, takeout bool) ([]*models.Comment, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns[mapping.Text]; !ok {
+		return nil, nil, fmt.Errorf("CSV has no %q column for the comment text", mapping.Text)
+	}
+
+	var comments []*models.Comment
+	var videoIDs []string
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		comment, videoID, err := rowComment(get, mapping, takeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		comments = append(comments, comment)
+		videoIDs = append(videoIDs, videoID)
+	}
+	return comments, videoIDs, nil
+}
+
+func parseJSONL(r io.Reader, mapping models.ColumnMapping) ([]*models.Comment, []string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var comments []*models.Comment
+	var videoIDs []string
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		get := func(name string) string {
+			switch v := object[name].(type) {
+			case nil:
+				return ""
+			case string:
+				return strings.TrimSpace(v)
+			default:
+				return fmt.Sprint(v)
+			}
+		}
+		comment, videoID, err := rowComment(get, mapping, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		comments = append(comments, comment)
+		videoIDs = append(videoIDs, videoID)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return comments, videoIDs, nil
+}
+
+// maxExtractedBytes limits the size of the comments CSV extracted from a
+// Takeout archive, so a small zip cannot expand without bound in memory.
+const maxExtractedBytes = 256 << 20
+
+// takeoutCSV returns the comments CSV of a Takeout archive, or the data itself
+// when it is not a zip file.
+func takeoutCSV(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return data, nil
+	}
+	for _, f := range archive.File {
+		name := strings.ToLower(path.Base(f.Name))
+		if !strings.HasPrefix(name, "comments") || path.Ext(name) != ".csv" {
+			continue
+		}
+		if f.UncompressedSize64 > maxExtractedBytes {
+			return nil, fmt.Errorf("%s in archive exceeds %d MB", f.Name, maxExtractedBytes>>20)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s in archive: %w", f.Name, err)
+		}
+		defer rc.Close()
+		// The declared size is not trusted, so the read is capped as well.
+		csvData, err := io.ReadAll(io.LimitReader(rc, maxExtractedBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s in archive: %w", f.Name, err)
+		}
+		if len(csvData) > maxExtractedBytes {
+			return nil, fmt.Errorf("%s in archive exceeds %d MB", f.Name, maxExtractedBytes>>20)
+		}
+		return csvData, nil
+	}
+	return nil, errors.New("archive does not contain a comments CSV file")
+}
+
+// Parse converts an export into comments for a single video. Files covering
+// several videos, such as Takeout exports, are filtered to videoID; when
+// videoID is empty the file must contain exactly one video. It returns the
+// comments and the resolved video ID.
+func Parse(data []byte, format string, mapping models.ColumnMapping, videoID string) ([]*models.Comment, string, error) {
+	mapping, err := resolveMapping(format, mapping)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var comments []*models.Comment
+	var videoIDs []string
+	switch format {
+	case FormatJSONL:
+		comments, videoIDs, err = parseJSONL(bytes.NewReader(data), mapping)
+	case FormatTakeout:
+		var csvData []byte
+		if csvData, err = takeoutCSV(data); err == nil {
+			comments, videoIDs, err = parseCSV(bytes.NewReader(csvData), mapping, true)
+		}
+	default:
+		comments, videoIDs, err = parseCSV(bytes.NewReader(data), mapping, false)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if videoID == "" {
+		seen := make(map[string]bool)
+		for _, id := range videoIDs {
+			if id != "" {
+				seen[id] = true
+			}
+		}
+		if len(seen) > 1 {
+			return nil, "", fmt.Errorf("file contains comments for %d videos; specify the video ID to import", len(seen))
+		}
+		for id := range seen {
+			videoID = id
+		}
+	}
+
+	var filtered []*models.Comment
+	for i, comment := range comments {
+		if videoIDs[i] != "" && videoIDs[i] != videoID {
+			continue
+		}
+		if comment.Text == "" {
+			continue
+		}
+		if comment.ID == "" {
+			comment.ID = fmt.Sprintf("import-%d", i+1)
+		}
+		filtered = append(filtered, comment)
+	}
+	if len(filtered) == 0 {
+		return nil, "", errors.New("no comments found in file")
+	}
+	return filtered, videoID, nil
+}
@@ -0,0 +1,81 @@
+package comment_search
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Search ranks the comments of a run by semantic similarity to a free-text
+// query with GET /runs/{id}/search?q=<query>&limit=<n>&min_score=<score>.
+// The first search of a run builds its embedding index.
+func Search(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		trackingID := r.PathValue("id")
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		if query == "" {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'q' query parameter")
+			return
+		}
+		if !Enabled(cfg) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotImplemented, "Search requires EMBEDDING_MODEL to be set")
+			return
+		}
+		limit := defaultLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxLimit {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, fmt.Sprintf("'limit' must be between 1 and %d", maxLimit))
+				return
+			}
+			limit = n
+		}
+		var minScore float32 = -1
+		if value := r.URL.Query().Get("min_score"); value != "" {
+			score, err := strconv.ParseFloat(value, 32)
+			if err != nil || score < -1 || score > 1 {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "'min_score' must be between -1 and 1")
+				return
+			}
+			minScore = float32(score)
+		}
+
+		data, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Run not found")
+			return
+		}
+		if err != nil {
+			shared.Logger.Error("could not load raw data from GCS", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve raw data file")
+			return
+		}
+
+		results, err := SearchComments(ctx, cfg, trackingID, data, query, limit, minScore)
+		if err != nil {
+			shared.Logger.Error("Failed to search comments", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to search comments")
+			return
+		}
+
+		response := models.SearchResponse{TrackingID: trackingID, Query: query, Results: results}
+		response.ProcessingTime = time.Since(startTime).String()
+		shared.JSONResponse(w, trackingID, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,293 @@
+package comment_search
+
+import (
+	"app/pkgs/gemini_magic"
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// indexMagic identifies version 1 of the index blob format.
+const indexMagic = "YTSAEMB1"
+
+// maxCachedIndexes bounds the indexes kept in memory. A 5000-comment index
+// with 768 dimensions takes about 15 MB.
+const maxCachedIndexes = 8
+
+// Index holds the normalized embedding of every comment of a run. Search is
+// brute force, which is fast enough for the few thousand comments of a run.
+type Index struct {
+	Model   string
+	IDs     []string
+	Vectors [][]float32
+}
+
+var (
+	cacheMu    sync.Mutex
+	cache      = make(map[string]*Index)
+	cacheOrder []string
+)
+
+// Enabled reports whether an embedding model is configured for search.
+func Enabled(cfg *models.AppConfig) bool {
+	return cfg.EmbeddingModel != "" && cfg.EmbeddingModel != "none"
+}
+
+func indexObjectName(trackingID string) string {
+	return fmt.Sprintf("%s_embeddings.bin", trackingID)
+}
+
+// encode writes the index as: magic, model name, count, dimensions, then per
+// comment its ID and vector. Strings are length-prefixed and all numbers are
+// little endian.
+func (idx *Index) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	dims := 0
+	if len(idx.Vectors) > 0 {
+		dims = len(idx.Vectors[0])
+	}
+	writeString(idx.Model)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(idx.IDs)))
+	binary.Write(&buf, binary.LittleEndian, uint32(dims))
+	for i, id := range idx.IDs {
+		if len(idx.Vectors[i]) != dims {
+			return nil, fmt.Errorf("embedding %d has %d dimensions, expected %d", i, len(idx.Vectors[i]), dims)
+		}
+		writeString(id)
+		binary.Write(&buf, binary.LittleEndian, idx.Vectors[i])
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeIndex(data []byte) (*Index, error) {
+	r := bytes.NewReader(data)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != indexMagic {
+		return nil, errors.New("not an embedding index")
+	}
+	readString := func() (string, error) {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", err
+		}
+		if int64(n) > int64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
+		}
+		s := make([]byte, n)
+		_, err := io.ReadFull(r, s)
+		return string(s), err
+	}
+
+	var idx Index
+	var count, dims uint32
+	var err error
+	if idx.Model, err = readString(); err != nil {
+		return nil, fmt.Errorf("corrupt embedding index: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("corrupt embedding index: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &dims); err != nil {
+		return nil, fmt.Errorf("corrupt embedding index: %w", err)
+	}
+	// Every entry takes at least its ID length and its vector.
+	if int64(count)*(4+4*int64(dims)) > int64(r.Len()) {
+		return nil, fmt.Errorf("corrupt embedding index: %w", io.ErrUnexpectedEOF)
+	}
+	idx.IDs = make([]string, count)
+	idx.Vectors = make([][]float32, count)
+	for i := range idx.IDs {
+		if idx.IDs[i], err = readString(); err != nil {
+			return nil, fmt.Errorf("corrupt embedding index: %w", err)
+		}
+		idx.Vectors[i] = make([]float32, dims)
+		if err := binary.Read(r, binary.LittleEndian, idx.Vectors[i]); err != nil {
+			return nil, fmt.Errorf("corrupt embedding index: %w", err)
+		}
+	}
+	return &idx, nil
+}
+
+// BuildIndex embeds the comments of a run as retrieval documents.
+func BuildIndex(ctx context.Context, cfg *models.AppConfig, data *models.VideoData) (*Index, error) {
+	client, embedder, err := gemini_magic.NewEmbedder(ctx, cfg, genai.TaskTypeRetrievalDocument)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return buildIndex(ctx, embedder, cfg.EmbeddingModel, data)
+}
+
+// buildIndex embeds the comments with text. The embedding API rejects empty
+// content, so comments without text get a zero vector, which Search never
+// returns, to keep the vectors aligned with the comments.
+func buildIndex(ctx context.Context, embedder gemini_magic.Embedder, model string, data *models.VideoData) (*Index, error) {
+	idx := &Index{
+		Model:   model,
+		IDs:     make([]string, len(data.Comments)),
+		Vectors: make([][]float32, len(data.Comments)),
+	}
+	var texts []string
+	var positions []int
+	for i, c := range data.Comments {
+		idx.IDs[i] = c.ID
+		if strings.TrimSpace(c.Text) != "" {
+			texts = append(texts, c.Text)
+			positions = append(positions, i)
+		}
+	}
+	dims := 0
+	if len(texts) > 0 {
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(texts) {
+			return nil, fmt.Errorf("got %d embeddings for %d comments", len(vectors), len(texts))
+		}
+		for j, v := range vectors {
+			idx.Vectors[positions[j]] = gemini_magic.Normalize(v)
+		}
+		dims = len(vectors[0])
+	}
+	for i, v := range idx.Vectors {
+		if v == nil {
+			idx.Vectors[i] = make([]float32, dims)
+		}
+	}
+	return idx, nil
+}
+
+// LoadIndex returns the embedding index of a run from the in-memory cache or
+// GCS, building and storing it as <trackingId>_embeddings.bin on first use.
+// Indexes built with another embedding model or for a different set of
+// comments are rebuilt.
+func LoadIndex(ctx context.Context, cfg *models.AppConfig, trackingID string, data *models.VideoData) (*Index, error) {
+	cacheMu.Lock()
+	idx, ok := cache[trackingID]
+	cacheMu.Unlock()
+	if ok && idx.matches(cfg, data) {
+		return idx, nil
+	}
+
+	blob, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, indexObjectName(trackingID))
+	switch {
+	case err == nil:
+		if idx, err = decodeIndex(blob); err != nil {
+			shared.Logger.Warn("Discarding unreadable embedding index", "error", err, "trackingId", trackingID)
+		}
+	case !errors.Is(err, storage.ErrObjectNotExist):
+		return nil, fmt.Errorf("could not read embedding index: %w", err)
+	}
+
+	if err != nil || !idx.matches(cfg, data) {
+		shared.Logger.Info("Building embedding index", "comments", len(data.Comments), "model", cfg.EmbeddingModel, "trackingId", trackingID)
+		if idx, err = BuildIndex(ctx, cfg, data); err != nil {
+			return nil, err
+		}
+		encoded, err := idx.encode()
+		if err != nil {
+			return nil, err
+		}
+		if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, indexObjectName(trackingID), encoded); err != nil {
+			return nil, fmt.Errorf("could not store embedding index: %w", err)
+		}
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if _, ok := cache[trackingID]; !ok {
+		cacheOrder = append(cacheOrder, trackingID)
+	}
+	cache[trackingID] = idx
+	for len(cacheOrder) > maxCachedIndexes {
+		delete(cache, cacheOrder[0])
+		cacheOrder = cacheOrder[1:]
+	}
+	return idx, nil
+}
+
+func (idx *Index) matches(cfg *models.AppConfig, data *models.VideoData) bool {
+	if idx == nil || idx.Model != cfg.EmbeddingModel || len(idx.IDs) != len(data.Comments) {
+		return false
+	}
+	for i, c := range data.Comments {
+		if idx.IDs[i] != c.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// Hit is a comment position in the index with its cosine similarity to the query.
+type Hit struct {
+	Position int
+	Score    float32
+}
+
+// Search returns the limit comments most similar to the normalized query
+// vector with a score of at least minScore, best first.
+func (idx *Index) Search(query []float32, limit int, minScore float32) []Hit {
+	var hits []Hit
+	for i, v := range idx.Vectors {
+		if isZero(v) {
+			continue
+		}
+		if score := gemini_magic.Dot(query, v); score >= minScore {
+			hits = append(hits, Hit{Position: i, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits[:min(len(hits), limit)]
+}
+
+// isZero reports whether a vector is the placeholder of a comment without text.
+func isZero(v []float32) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchComments embeds the query and returns the limit comments of a run most
+// similar to it with a score of at least minScore, best first.
+func SearchComments(ctx context.Context, cfg *models.AppConfig, trackingID string, data *models.VideoData, query string, limit int, minScore float32) ([]models.SearchResult, error) {
+	idx, err := LoadIndex(ctx, cfg, trackingID, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not load embedding index: %w", err)
+	}
+
+	client, embedder, err := gemini_magic.NewEmbedder(ctx, cfg, genai.TaskTypeRetrievalQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	vectors, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("could not embed query: %w", err)
+	}
+
+	results := []models.SearchResult{}
+	for _, hit := range idx.Search(gemini_magic.Normalize(vectors[0]), limit, minScore) {
+		results = append(results, models.SearchResult{Comment: data.Comments[hit.Position], Score: hit.Score})
+	}
+	return results, nil
+}
@@ -0,0 +1,143 @@
+package comment_search
+
+import (
+	"app/pkgs/models"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testIndex() *Index {
+	return &Index{
+		Model:   "text-embedding-004",
+		IDs:     []string{"c1", "c2"},
+		Vectors: [][]float32{{0.6, 0.8, 0}, {0, 0, 1}},
+	}
+}
+
+// header returns the magic, model and count and dimension fields of an index
+// blob with the given values.
+func header(model string, count, dims uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(model)))
+	buf.WriteString(model)
+	binary.Write(&buf, binary.LittleEndian, count)
+	binary.Write(&buf, binary.LittleEndian, dims)
+	return buf.Bytes()
+}
+
+func TestDecodeIndexRoundTrip(t *testing.T) {
+	for _, idx := range []*Index{testIndex(), {Model: "m", IDs: []string{}, Vectors: [][]float32{}}} {
+		data, err := idx.encode()
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		got, err := decodeIndex(data)
+		if err != nil {
+			t.Fatalf("decodeIndex: %v", err)
+		}
+		if !reflect.DeepEqual(got, idx) {
+			t.Errorf("decodeIndex = %+v, want %+v", got, idx)
+		}
+	}
+}
+
+func TestDecodeIndexRejectsCorruptData(t *testing.T) {
+	valid, err := testIndex().encode()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	// A string length far beyond the data must not allocate it.
+	hugeID := append(header("m", 1, 1), 0xff, 0xff, 0xff, 0x7f)
+	hugeID = append(hugeID, make([]byte, 8)...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "wrong magic", data: append([]byte("YTSAEMB0"), valid[len(indexMagic):]...)},
+		{name: "huge model name", data: append([]byte(indexMagic), 0xff, 0xff, 0xff, 0xff)},
+		{name: "huge count", data: header("m", 0xffffffff, 768)},
+		{name: "huge count without dimensions", data: header("m", 0xffffffff, 0)},
+		{name: "vectors beyond the data", data: append(header("m", 2, 3), make([]byte, 20)...)},
+		{name: "huge comment ID", data: hugeID},
+	}
+	// Every truncation of a valid index is corrupt as well.
+	for n := range len(valid) {
+		tests = append(tests, struct {
+			name string
+			data []byte
+		}{name: "truncated", data: valid[:n]})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if idx, err := decodeIndex(tt.data); err == nil {
+				t.Errorf("decodeIndex(%d bytes) = %+v, want an error", len(tt.data), idx)
+			}
+		})
+	}
+}
+
+// fakeEmbedder returns the vector of each text from a fixed table and rejects
+// empty texts like the embedding API.
+type fakeEmbedder map[string][]float32
+
+func (e fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			return nil, errors.New("empty content")
+		}
+		vectors[i] = append([]float32(nil), e[text]...)
+	}
+	return vectors, nil
+}
+
+func TestBuildIndexSkipsEmptyTexts(t *testing.T) {
+	embedder := fakeEmbedder{"audio": {3, 4}, "lighting": {0, 2}}
+	data := &models.VideoData{Comments: []*models.Comment{
+		{ID: "c1", Text: "audio"},
+		{ID: "c2", Text: " "},
+		{ID: "c3", Text: "lighting"},
+	}}
+	idx, err := buildIndex(context.Background(), embedder, "m", data)
+	if err != nil {
+		t.Fatalf("buildIndex: %v", err)
+	}
+	want := &Index{
+		Model:   "m",
+		IDs:     []string{"c1", "c2", "c3"},
+		Vectors: [][]float32{{0.6, 0.8}, {0, 0}, {0, 1}},
+	}
+	if !reflect.DeepEqual(idx, want) {
+		t.Fatalf("buildIndex = %+v, want %+v", idx, want)
+	}
+	if _, err := idx.encode(); err != nil {
+		t.Errorf("encode: %v", err)
+	}
+	hits := idx.Search([]float32{1, 0}, 10, -1)
+	if len(hits) != 2 || hits[0].Position != 0 || hits[1].Position != 2 {
+		t.Errorf("Search = %+v, want the comments with text, best first", hits)
+	}
+}
+
+func TestBuildIndexWithoutTexts(t *testing.T) {
+	data := &models.VideoData{Comments: []*models.Comment{{ID: "c1"}}}
+	idx, err := buildIndex(context.Background(), fakeEmbedder{}, "m", data)
+	if err != nil {
+		t.Fatalf("buildIndex: %v", err)
+	}
+	if !idx.matches(&models.AppConfig{EmbeddingModel: "m"}, data) {
+		t.Errorf("index %+v does not match the comments", idx)
+	}
+	if hits := idx.Search(nil, 10, -1); len(hits) != 0 {
+		t.Errorf("Search = %+v, want no hits", hits)
+	}
+}
@@ -1,25 +1,30 @@
 package gemini_magic
 
 import (
+	"app/pkgs/aspects"
+	"app/pkgs/bot_detection"
+	"app/pkgs/mentions"
 	"app/pkgs/models"
 	"app/pkgs/shared"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"cloud.google.com/go/storage"
 	"github.com/google/generative-ai-go/genai"
 	"golang.org/x/time/rate"
-	"google.golang.org/api/option"
 )
 
 const mapPrompt = `
 	You are an expert YouTube marketing strategist and data analyst. Your task is to perform a partial analysis of the provided YouTube video data and a chunk of its comments. The goal is to produce a concise summary of this specific chunk, which will be used in a later step for a full analysis.
 
 	**Input Data:**
-	A JSON object containing details about a YouTube video and its comments will be provided.
+	A JSON object containing details about a YouTube video and its comments will be provided. The comments may be written in several languages. Analyze every comment in its own language, write your summaries in English and copy comment texts verbatim.
 
 	%s
 
@@ -51,7 +56,7 @@ const reducePrompt = `
 	%s
 
 	**Partial Comment Analyses:**
-	This is an array of JSON objects, where each object is a summary of a chunk of comments from the video.
+	This is an array of JSON objects, where each object is a summary of a chunk of comments from the video. The comments may be written in several languages; the same theme discussed in different languages is one theme.
 	%s
 
 	**Analysis Tasks & Final Output Structure:**
@@ -118,13 +123,11 @@ func chunkComments(comments []*models.Comment, chunkSize int) [][]*models.Commen
 	return chunks
 }
 
-func cleanAndFinalizeAnalysis(rawResponse string, trackingID string, runDate string) ([]byte, error) {
-	start := strings.Index(rawResponse, "{")
-	end := strings.LastIndex(rawResponse, "}")
-	if start == -1 || end == -1 || end < start {
-		return nil, fmt.Errorf("could not find valid JSON object in response: %s", rawResponse)
+func cleanAndFinalizeAnalysis(rawResponse string, trackingID string, runDate string) (*models.AnalysisRecord, error) {
+	cleanedJSONStr, err := extractJSONObject(rawResponse)
+	if err != nil {
+		return nil, err
 	}
-	cleanedJSONStr := rawResponse[start : end+1]
 
 	// Use a decoder to be more robust against trailing characters.
 	// This handles cases where the LLM returns a valid JSON object followed by a comma or other text.
@@ -142,7 +145,226 @@ func cleanAndFinalizeAnalysis(rawResponse string, trackingID string, runDate str
 	record.TrackingID = trackingID
 	record.RunDate = runDate
 
-	return json.Marshal(record)
+	return &record, nil
+}
+
+// Analyze runs the map-reduce analysis of a video and its comments with Gemini.
+// With a transcript in opts, the questions and criticism found in the comments
+// are checked against what is said in the video. With opts.Packaging, the
+// title and thumbnail are critiqued as well. Suspected bot comments are only
+// checked for moderation flags unless opts.IncludeBots is set. With
+// opts.OutputLanguage, the report text is written in that language. With
+// opts.Clusters, the comments are also clustered into themes by their
+// embeddings, and questions are grouped by their embeddings instead of word
+// overlap.
+func Analyze(ctx context.Context, cfg *models.AppConfig, fullData *models.VideoData, trackingID string, opts models.AnalyzeOptions) (*models.AnalysisRecord, error) {
+	runDate := time.Now().Format("2006-01-02")
+	if opts.OutputLanguage != "" && !ValidOutputLanguage(opts.OutputLanguage) {
+		return nil, fmt.Errorf("invalid output language %q", opts.OutputLanguage)
+	}
+
+	botReport := bot_detection.Detect(fullData.Comments)
+	shared.Logger.Info("Detected suspected bot comments", "suspected", botReport.SuspectedComments, "duplicateGroups", len(botReport.DuplicateGroups), "bursts", len(botReport.AuthorBursts), "excluded", !opts.IncludeBots, "trackingId", trackingID)
+	// Suspected comments still go through the map step so that the spam and
+	// abuse among them is flagged for moderation, but only their flags are
+	// kept: they are left out of the sentiment, the reduce step and every
+	// aggregate.
+	var suspectComments []*models.Comment
+	if !opts.IncludeBots {
+		botReport.Excluded = true
+		suspectComments = bot_detection.Suspected(fullData, botReport)
+		fullData = bot_detection.Without(fullData, botReport)
+	}
+	moderatedComments := len(fullData.Comments) + len(suspectComments)
+
+	client, model, err := newGeminiModel(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	defer client.Close()
+
+	shared.Logger.Info("Sending request to Gemini model for analysis...", "model", cfg.GEMINIModel, "trackingId", trackingID)
+
+	const commentChunkSize = 100
+	commentChunks := chunkComments(fullData.Comments, commentChunkSize)
+	// Chunks from moderationOnly on hold suspected comments.
+	moderationOnly := len(commentChunks)
+	commentChunks = append(commentChunks, chunkComments(suspectComments, commentChunkSize)...)
+	shared.Logger.Info("Split comments into chunks", "commentCount", moderatedComments, "chunkCount", len(commentChunks), "moderationOnlyChunks", len(commentChunks)-moderationOnly, "chunkSize", commentChunkSize, "trackingId", trackingID)
+
+	limiter := rate.NewLimiter(rate.Every(600*time.Millisecond), 1)
+
+	var wg sync.WaitGroup
+	analysisChunksChan := make(chan string, len(commentChunks))
+	annotationsChan := make(chan []models.CommentAnnotation, len(commentChunks))
+	flagsChan := make(chan []models.ModerationFlag, len(commentChunks))
+	errChan := make(chan error, len(commentChunks))
+
+	baseVideoData := *fullData
+	baseVideoData.Comments = nil
+
+	for i, chunk := range commentChunks {
+		wg.Add(1)
+
+		go func(chunkIndex int, commentChunk []*models.Comment) {
+			defer wg.Done()
+
+			if err := limiter.Wait(ctx); err != nil {
+				errChan <- fmt.Errorf("rate limiter wait error: %w", err)
+				return
+			}
+			chunkDataForPrompt := baseVideoData
+			chunkDataForPrompt.Comments = commentChunk
+			chunkDataBytes, err := json.Marshal(chunkDataForPrompt)
+			if err != nil {
+				errChan <- fmt.Errorf("chunk %d marshal error: %w", chunkIndex, err)
+				return
+			}
+
+			mapPromptFormatted := fmt.Sprintf(mapPrompt, string(chunkDataBytes))
+
+			shared.Logger.Info("Analyzing comment chunk", "chunk", chunkIndex+1, "totalChunks", len(commentChunks), "trackingId", trackingID)
+			summary, annotations, flags, err := analyzeChunk(ctx, model, mapPromptFormatted, commentChunk, opts, trackingID)
+			if err != nil {
+				errChan <- fmt.Errorf("chunk %d: %w", chunkIndex, err)
+				return
+			}
+			flagsChan <- flags
+			if chunkIndex >= moderationOnly {
+				return
+			}
+			analysisChunksChan <- summary
+			annotationsChan <- annotations
+		}(i, chunk)
+	}
+
+	wg.Wait()
+	close(analysisChunksChan)
+	close(annotationsChan)
+	close(flagsChan)
+	close(errChan)
+
+	if len(errChan) > 0 {
+		for e := range errChan {
+			shared.Logger.Error("Error during chunk analysis", "error", e, "trackingId", trackingID)
+		}
+		return nil, fmt.Errorf("one or more chunks failed analysis, see logs for details")
+	}
+
+	var analysisChunks []string
+	for analysis := range analysisChunksChan {
+		analysisChunks = append(analysisChunks, analysis)
+	}
+	var annotations []models.CommentAnnotation
+	for chunkAnnotations := range annotationsChan {
+		annotations = append(annotations, chunkAnnotations...)
+	}
+	var flags []models.ModerationFlag
+	for chunkFlags := range flagsChan {
+		flags = append(flags, chunkFlags...)
+	}
+	combinedAnalyses := "[" + strings.Join(analysisChunks, ",") + "]"
+	if opts.OutputLanguage != "" {
+		// The map summaries stay in English; everything generated from here
+		// on is part of the report.
+		model.SystemInstruction = genai.NewUserContent(genai.Text(fmt.Sprintf(outputLanguageInstruction, opts.OutputLanguage)))
+	}
+	shared.Logger.Info("All chunks analyzed. Starting final reduction step.", "trackingId", trackingID)
+
+	baseVideoDataBytes, err := json.Marshal(baseVideoData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare data for final analysis: %w", err)
+	}
+	reducePromptFormatted := fmt.Sprintf(reducePrompt, string(baseVideoDataBytes), combinedAnalyses)
+
+	const maxRetries = 3
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		shared.Logger.Info("Generating final analysis from Gemini.", "attempt", attempt, "maxRetries", maxRetries, "trackingId", trackingID)
+		geminiStartTime := time.Now()
+		resp, err := model.GenerateContent(ctx, genai.Text(reducePromptFormatted))
+		if err != nil {
+			shared.Logger.Warn("Gemini API call failed", "attempt", attempt, "error", err, "trackingId", trackingID)
+			if attempt == maxRetries {
+				return nil, fmt.Errorf("failed to generate content from Gemini after %d retries: %w", maxRetries, err)
+			}
+			time.Sleep(2 * time.Second)
+			continue
+		}
+
+		if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+			shared.Logger.Warn("Received empty response from Gemini", "attempt", attempt, "trackingId", trackingID)
+			if attempt == maxRetries {
+				return nil, fmt.Errorf("received empty response from Gemini after %d retries", maxRetries)
+			}
+			continue
+		}
+
+		analysisPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+		if !ok {
+			shared.Logger.Warn("Gemini response part is not text", "attempt", attempt, "trackingId", trackingID)
+			if attempt == maxRetries {
+				return nil, fmt.Errorf("invalid response format from Gemini after %d retries", maxRetries)
+			}
+			continue
+		}
+		shared.Logger.Info("Successfully received analysis from Gemini.", "duration", time.Since(geminiStartTime).String(), "trackingId", trackingID)
+
+		record, err := cleanAndFinalizeAnalysis(string(analysisPart), trackingID, runDate)
+		if err == nil {
+			shared.Logger.Info("Successfully parsed and validated Gemini response.", "trackingId", trackingID)
+			record.Annotations = annotations
+			record.SentimentTimeline = buildTimeline(fullData.Comments, annotations)
+			record.MomentHeatmap = buildMomentHeatmap(fullData, annotations)
+			record.ChapterSentiment = buildChapterSentiment(ctx, model, fullData, annotations, trackingID)
+			record.Moderation = buildModerationReport(flags, moderatedComments)
+			record.BotReport = botReport
+			record.Languages = buildLanguageStats(annotations)
+			record.OutputLanguage = opts.OutputLanguage
+			record.Mentions = buildMentionStats(fullData.Comments, annotations, descriptionMentions(ctx, model, fullData, opts.Dictionary, trackingID))
+			if opts.Aspects != nil {
+				record.AspectTaxonomy = opts.Aspects.Name
+				record.AspectScorecard = buildAspectScorecard(opts.Aspects, fullData.Comments, annotations)
+			}
+			if clusteringEnabled(cfg, fullData, opts) {
+				embedder := newGeminiEmbedder(client, cfg.EmbeddingModel, genai.TaskTypeClustering)
+				// Clustering complements KeyThemes, so a failure keeps the analysis.
+				if record.ThemeClusters, err = clusterThemes(ctx, embedder, model, fullData, annotations, trackingID); err != nil {
+					shared.Logger.Warn("Failed to cluster comment themes. Continuing without them.", "error", err, "trackingId", trackingID)
+				}
+			}
+			// Questions are grouped by word overlap unless embeddings were
+			// requested for the run.
+			var questionEmbedder Embedder
+			if opts.Clusters && cfg.EmbeddingModel != "" && cfg.EmbeddingModel != "none" {
+				questionEmbedder = newGeminiEmbedder(client, cfg.EmbeddingModel, genai.TaskTypeClustering)
+			}
+			record.Questions = trackQuestions(ctx, questionEmbedder, model, fullData, annotations, trackingID)
+			if opts.Transcript != nil {
+				// The review refines the report, so a failure keeps the
+				// unreviewed analysis.
+				if err := reviewTranscript(ctx, model, record, fullData, opts.Transcript, trackingID); err != nil {
+					shared.Logger.Warn("Failed to review the analysis against the transcript. Continuing without the review.", "error", err, "trackingId", trackingID)
+				}
+			}
+			if opts.Packaging {
+				critique, err := critiquePackaging(ctx, model, record, fullData, trackingID)
+				if err != nil {
+					shared.Logger.Warn("Failed to critique the title and thumbnail. Continuing without the critique.", "error", err, "trackingId", trackingID)
+				} else {
+					record.PackagingCritique = critique
+				}
+			}
+			return record, nil
+		}
+
+		shared.Logger.Warn("Failed to clean and validate Gemini response", "attempt", attempt, "error", err, "rawResponse", string(analysisPart), "trackingId", trackingID)
+		if attempt == maxRetries {
+			return nil, fmt.Errorf("failed to process analysis from Gemini after %d retries: %w", maxRetries, err)
+		}
+		time.Sleep(2 * time.Second) // Wait before retrying
+	}
+	return nil, fmt.Errorf("failed to generate analysis after %d retries", maxRetries)
 }
 
 func AnalyzeData(cfg *models.AppConfig) http.HandlerFunc {
@@ -155,7 +377,6 @@ func AnalyzeData(cfg *models.AppConfig) http.HandlerFunc {
 			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'trackingId' query parameter")
 			return
 		}
-		runDate := time.Now().Format("2006-01-02")
 		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
 
 		objectName := fmt.Sprintf("%s.json", trackingID)
@@ -175,164 +396,69 @@ func AnalyzeData(cfg *models.AppConfig) http.HandlerFunc {
 		}
 		shared.Logger.Info("Successfully unmarshaled JSON data", "videoId", fullData.ID, "trackingId", trackingID)
 
-		client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.GEMINIApiKey))
-		if err != nil {
-			shared.Logger.Error("Failed to create Gemini client", "error", err, "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to create Gemini client")
-			return
+		opts := models.AnalyzeOptions{
+			Packaging:      r.URL.Query().Get("packaging") == "true",
+			IncludeBots:    r.URL.Query().Get("includeBots") == "true",
+			OutputLanguage: r.URL.Query().Get("language"),
+			Clusters:       r.URL.Query().Get("clusters") == "true",
 		}
-		defer client.Close()
-
-		model := client.GenerativeModel(cfg.GEMINIModel)
-		model.SafetySettings = []*genai.SafetySetting{
-			{
-				Category:  genai.HarmCategoryHarassment,
-				Threshold: genai.HarmBlockNone,
-			},
-			{
-				Category:  genai.HarmCategoryHateSpeech,
-				Threshold: genai.HarmBlockNone,
-			},
-			{
-				Category:  genai.HarmCategorySexuallyExplicit,
-				Threshold: genai.HarmBlockNone,
-			},
-			{
-				Category:  genai.HarmCategoryDangerousContent,
-				Threshold: genai.HarmBlockNone,
-			},
-		}
-
-		shared.Logger.Info("Sending request to Gemini model for analysis...", "model", cfg.GEMINIModel, "trackingId", trackingID)
-
-		const commentChunkSize = 100
-		commentChunks := chunkComments(fullData.Comments, commentChunkSize)
-		shared.Logger.Info("Split comments into chunks", "commentCount", len(fullData.Comments), "chunkCount", len(commentChunks), "chunkSize", commentChunkSize, "trackingId", trackingID)
-
-		limiter := rate.NewLimiter(rate.Every(600*time.Millisecond), 1)
-
-		var wg sync.WaitGroup
-		analysisChunksChan := make(chan string, len(commentChunks))
-		errChan := make(chan error, len(commentChunks))
-
-		baseVideoData := fullData
-		baseVideoData.Comments = nil
-
-		for i, chunk := range commentChunks {
-			wg.Add(1)
-
-			go func(chunkIndex int, commentChunk []*models.Comment) {
-				defer wg.Done()
-
-				if err := limiter.Wait(ctx); err != nil {
-					errChan <- fmt.Errorf("rate limiter wait error: %w", err)
-					return
-				}
-				chunkDataForPrompt := baseVideoData
-				chunkDataForPrompt.Comments = commentChunk
-				chunkDataBytes, err := json.Marshal(chunkDataForPrompt)
-				if err != nil {
-					errChan <- fmt.Errorf("chunk %d marshal error: %w", chunkIndex, err)
-					return
-				}
-
-				mapPromptFormatted := fmt.Sprintf(mapPrompt, string(chunkDataBytes))
-
-				shared.Logger.Info("Analyzing comment chunk", "chunk", chunkIndex+1, "totalChunks", len(commentChunks), "trackingId", trackingID)
-				resp, err := model.GenerateContent(ctx, genai.Text(mapPromptFormatted))
-				if err != nil {
-					errChan <- fmt.Errorf("chunk %d Gemini error: %w", chunkIndex, err)
-					return
-				}
-
-				if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
-					if analysis, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
-						analysisChunksChan <- string(analysis)
-					} else {
-						errChan <- fmt.Errorf("chunk %d: Gemini response part is not text", chunkIndex)
-					}
-				} else {
-					errChan <- fmt.Errorf("chunk %d: received empty response from Gemini", chunkIndex)
-				}
-			}(i, chunk)
+		if opts.OutputLanguage != "" && !ValidOutputLanguage(opts.OutputLanguage) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Invalid 'language' query parameter")
+			return
 		}
-
-		wg.Wait()
-		close(analysisChunksChan)
-		close(errChan)
-
-		if len(errChan) > 0 {
-			for e := range errChan {
-				shared.Logger.Error("Error during chunk analysis", "error", e, "trackingId", trackingID)
-			}
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "One or more chunks failed analysis. See logs for details.")
+		profile := r.URL.Query().Get("aspects")
+		if profile != "" && !aspects.ValidName(profile) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Invalid 'aspects' query parameter")
 			return
 		}
-
-		var analysisChunks []string
-		for analysis := range analysisChunksChan {
-			analysisChunks = append(analysisChunks, analysis)
+		opts.Aspects, err = aspects.Resolve(ctx, cfg, profile, fullData.ChannelID)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "No aspect taxonomy stored under this name; store one with PUT /aspects/{name} first")
+			return
 		}
-		combinedAnalyses := "[" + strings.Join(analysisChunks, ",") + "]"
-		shared.Logger.Info("All chunks analyzed. Starting final reduction step.", "trackingId", trackingID)
-
-		baseVideoDataBytes, err := json.Marshal(baseVideoData)
 		if err != nil {
-			shared.Logger.Error("Failed to marshal base video data", "error", err, "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to prepare data for final analysis")
+			shared.Logger.Error("could not load aspect taxonomy", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve aspect taxonomy")
 			return
 		}
-		reducePromptFormatted := fmt.Sprintf(reducePrompt, string(baseVideoDataBytes), combinedAnalyses)
-
-		var finalJSON []byte
-		const maxRetries = 3
-
-		for attempt := 1; attempt <= maxRetries; attempt++ {
-			shared.Logger.Info("Generating final analysis from Gemini.", "attempt", attempt, "maxRetries", maxRetries, "trackingId", trackingID)
-			geminiStartTime := time.Now()
-			resp, err := model.GenerateContent(ctx, genai.Text(reducePromptFormatted))
-			if err != nil {
-				shared.Logger.Warn("Gemini API call failed", "attempt", attempt, "error", err, "trackingId", trackingID)
-				if attempt == maxRetries {
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, fmt.Sprintf("Failed to generate content from Gemini after %d retries", maxRetries))
-					return
-				}
-				time.Sleep(2 * time.Second)
-				continue
+		if opts.Dictionary, err = mentions.LoadDictionary(ctx, cfg); err != nil {
+			shared.Logger.Error("could not load mention dictionary", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve mention dictionary")
+			return
+		}
+		if r.URL.Query().Get("transcript") == "true" {
+			opts.Transcript, err = shared.LoadTranscript(ctx, cfg.GCSBucketName, trackingID)
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "No transcript stored for this tracking ID; upload one to /transcript first")
+				return
 			}
-
-			if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-				shared.Logger.Warn("Received empty response from Gemini", "attempt", attempt, "trackingId", trackingID)
-				if attempt == maxRetries {
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, fmt.Sprintf("Received empty response from Gemini after %d retries", maxRetries))
-					return
-				}
-				continue
+			if err != nil {
+				shared.Logger.Error("could not load transcript from GCS", "error", err, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to retrieve transcript file")
+				return
 			}
+		}
 
-			analysisPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-			if !ok {
-				shared.Logger.Warn("Gemini response part is not text", "attempt", attempt, "trackingId", trackingID)
-				if attempt == maxRetries {
-					shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, fmt.Sprintf("Invalid response format from Gemini after %d retries", maxRetries))
-					return
-				}
-				continue
-			}
-			shared.Logger.Info("Successfully received analysis from Gemini.", "duration", time.Since(geminiStartTime).String(), "trackingId", trackingID)
+		record, err := Analyze(ctx, cfg, &fullData, trackingID, opts)
+		if err != nil {
+			shared.Logger.Error("Failed to analyze data", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, fmt.Sprintf("Failed to analyze data: %v", err))
+			return
+		}
 
-			finalJSON, err = cleanAndFinalizeAnalysis(string(analysisPart), trackingID, runDate)
-			if err == nil {
-				shared.Logger.Info("Successfully parsed and validated Gemini response.", "trackingId", trackingID)
-				break
-			}
+		finalJSON, err := json.Marshal(record)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal final analysis", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to marshal analysis")
+			return
+		}
 
-			shared.Logger.Warn("Failed to clean and validate Gemini response", "attempt", attempt, "error", err, "rawResponse", string(analysisPart), "trackingId", trackingID)
-			if attempt == maxRetries {
-				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, fmt.Sprintf("Failed to process analysis from Gemini after %d retries", maxRetries))
+		if len(record.Annotations) > 0 {
+			if err := shared.SaveAnnotations(ctx, cfg.GCSBucketName, trackingID, record.Annotations); err != nil {
+				shared.Logger.Error("Failed to upload comment annotations to GCS", "error", err, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to upload comment annotations to GCS")
 				return
 			}
-			time.Sleep(2 * time.Second) // Wait before retrying
 		}
 
 		analysisObjectName := fmt.Sprintf("%s_analyzed.json", trackingID)
@@ -0,0 +1,122 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// annotationPrompt is appended to every map prompt for the per-comment
+// sentiment and language. questionInstruction, mentionPrompt and, with an
+// aspect taxonomy, aspectPrompt follow it.
+const annotationPrompt = `
+	5.  **Comment Annotations ('comment_annotations'):** An array with exactly one object per comment in this chunk, in input order. Each object must have:
+	    *   'id': The 'id' of the comment, copied exactly.
+	    *   'sentiment': 'positive', 'negative' or 'neutral'.
+	    *   'language': The ISO 639-1 code of the language the comment is written in (e.g. 'en', 'sv', 'de'), or 'und' if it cannot be determined, e.g. for emoji-only comments.
+	`
+
+// analyzeChunk runs the map step for a chunk. It returns the chunk summary
+// without the per-comment output, which is what the reduce step consumes, and
+// the annotations and moderation flags of the chunk's comments.
+func analyzeChunk(ctx context.Context, model *genai.GenerativeModel, prompt string, comments []*models.Comment, opts models.AnalyzeOptions, trackingID string) (string, []models.CommentAnnotation, []models.ModerationFlag, error) {
+	prompt += moderationPrompt + annotationPrompt + questionInstruction + mentionPrompt(opts.Dictionary)
+	if opts.Aspects != nil {
+		prompt += aspectPrompt(opts.Aspects)
+	}
+	var result map[string]json.RawMessage
+	if err := generateJSON(ctx, model, prompt, trackingID, &result); err != nil {
+		return "", nil, nil, err
+	}
+
+	var rawFlags []moderationOutput
+	if data, ok := result["moderation_flags"]; ok {
+		if err := json.Unmarshal(data, &rawFlags); err != nil {
+			return "", nil, nil, fmt.Errorf("invalid moderation flags: %w", err)
+		}
+		delete(result, "moderation_flags")
+	}
+	var raw []models.CommentAnnotation
+	if data, ok := result["comment_annotations"]; ok {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return "", nil, nil, fmt.Errorf("invalid comment annotations: %w", err)
+		}
+		delete(result, "comment_annotations")
+	}
+
+	// Keep one annotation per comment of this chunk; the model occasionally
+	// invents or repeats IDs.
+	inChunk := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		inChunk[c.ID] = true
+	}
+	index := newEntityIndex(opts.Dictionary)
+	var annotations []models.CommentAnnotation
+	for _, a := range raw {
+		if !inChunk[a.ID] {
+			continue
+		}
+		inChunk[a.ID] = false
+		a.Sentiment = strings.ToLower(strings.TrimSpace(a.Sentiment))
+		a.Language = normalizeLanguage(a.Language)
+		a.Aspects = normalizeAspects(a.Aspects, opts.Aspects)
+		a.Mentions = normalizeMentions(a.Mentions, index)
+		annotations = append(annotations, a)
+	}
+
+	summary, err := json.Marshal(result)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return string(summary), annotations, moderationFlags(rawFlags, comments), nil
+}
+
+// buildTimeline buckets live chat messages by minute since the first message
+// and counts their sentiment. Minutes without messages are included so the
+// series is continuous.
+func buildTimeline(comments []*models.Comment, annotations []models.CommentAnnotation) []models.TimelineBucket {
+	sentiments := make(map[string]string, len(annotations))
+	for _, a := range annotations {
+		sentiments[a.ID] = a.Sentiment
+	}
+
+	var messages []*models.Comment
+	for _, c := range comments {
+		if c.Source == models.SourceLiveChat && !c.PublishedAt.IsZero() {
+			messages = append(messages, c)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].PublishedAt.Before(messages[j].PublishedAt)
+	})
+
+	start := messages[0].PublishedAt.Truncate(time.Minute)
+	last := int(messages[len(messages)-1].PublishedAt.Sub(start) / time.Minute)
+	timeline := make([]models.TimelineBucket, last+1)
+	for i := range timeline {
+		timeline[i].Minute = i
+		timeline[i].Start = start.Add(time.Duration(i) * time.Minute)
+	}
+	for _, m := range messages {
+		bucket := &timeline[int(m.PublishedAt.Sub(start)/time.Minute)]
+		bucket.Messages++
+		switch sentiments[m.ID] {
+		case "positive":
+			bucket.Positive++
+		case "negative":
+			bucket.Negative++
+		case "neutral":
+			bucket.Neutral++
+		}
+	}
+	return timeline
+}
@@ -0,0 +1,104 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxAspectQuotes is the number of most liked comments quoted per aspect.
+const maxAspectQuotes = 3
+
+// aspectPrompt extends the comment annotations with the aspects of a taxonomy.
+func aspectPrompt(taxonomy *models.AspectTaxonomy) string {
+	var b strings.Builder
+	b.WriteString("\t    *   'aspects': An array with one object per aspect below that the comment talks about, each with the 'aspect' name copied exactly and the comment's 'sentiment' ('positive', 'negative' or 'neutral') towards that aspect, which can differ from its overall sentiment. Use an empty array if the comment mentions none of them.\n")
+	for _, a := range taxonomy.Aspects {
+		if a.Description != "" {
+			fmt.Fprintf(&b, "\t        *   '%s': %s\n", a.Name, a.Description)
+		} else {
+			fmt.Fprintf(&b, "\t        *   '%s'\n", a.Name)
+		}
+	}
+	return b.String()
+}
+
+// normalizeAspects keeps one mention per known aspect, with the aspect name
+// spelled as in the taxonomy.
+func normalizeAspects(raw []models.AspectMention, taxonomy *models.AspectTaxonomy) []models.AspectMention {
+	if taxonomy == nil {
+		return nil
+	}
+	names := make(map[string]string, len(taxonomy.Aspects))
+	for _, a := range taxonomy.Aspects {
+		names[strings.ToLower(a.Name)] = a.Name
+	}
+	var mentions []models.AspectMention
+	for _, m := range raw {
+		key := strings.ToLower(strings.TrimSpace(m.Aspect))
+		name, ok := names[key]
+		if !ok {
+			continue
+		}
+		delete(names, key)
+		mentions = append(mentions, models.AspectMention{Aspect: name, Sentiment: strings.ToLower(strings.TrimSpace(m.Sentiment))})
+	}
+	return mentions
+}
+
+// buildAspectScorecard counts the mentions and sentiment of every aspect of
+// the taxonomy, in taxonomy order. Aspects nobody mentions are kept with zero
+// mentions, since silence about an aspect is a finding too.
+func buildAspectScorecard(taxonomy *models.AspectTaxonomy, comments []*models.Comment, annotations []models.CommentAnnotation) []models.AspectScore {
+	byID := make(map[string]*models.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+	scores := make([]models.AspectScore, len(taxonomy.Aspects))
+	positions := make(map[string]int, len(taxonomy.Aspects))
+	quoted := make([][]*models.Comment, len(taxonomy.Aspects))
+	for i, a := range taxonomy.Aspects {
+		scores[i] = models.AspectScore{Aspect: a.Name, TopQuotes: []string{}}
+		positions[a.Name] = i
+	}
+
+	for _, a := range annotations {
+		for _, m := range a.Aspects {
+			i, ok := positions[m.Aspect]
+			if !ok {
+				continue
+			}
+			s := &scores[i]
+			s.Mentions++
+			switch m.Sentiment {
+			case "positive":
+				s.Positive++
+			case "negative":
+				s.Negative++
+			case "neutral":
+				s.Neutral++
+			}
+			if c, ok := byID[a.ID]; ok {
+				quoted[i] = append(quoted[i], c)
+			}
+		}
+	}
+
+	for i := range scores {
+		s := &scores[i]
+		if len(annotations) > 0 {
+			s.Share = float64(s.Mentions) / float64(len(annotations))
+		}
+		if s.Mentions > 0 {
+			s.NetSentiment = float64(s.Positive-s.Negative) / float64(s.Mentions)
+		}
+		sort.SliceStable(quoted[i], func(a, b int) bool {
+			return quoted[i][a].LikeCount > quoted[i][b].LikeCount
+		})
+		for _, c := range quoted[i][:min(len(quoted[i]), maxAspectQuotes)] {
+			s.TopQuotes = append(s.TopQuotes, truncateRunes(c.Text, 200))
+		}
+	}
+	return scores
+}
@@ -0,0 +1,226 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// minChapters follows YouTube, which only shows chapters for lists of at
+	// least three entries starting at 0:00.
+	minChapters = 3
+	// minChapterTitleRunes keeps short titles like "Q&A" from matching
+	// unrelated comments by name.
+	minChapterTitleRunes = 4
+	// maxChapterComments bounds the comments sent to Gemini per chapter.
+	maxChapterComments = 30
+)
+
+// chapterLinePattern matches description lines such as "00:00 Intro",
+// "2:15 - Setup" or "(1:02:03) Outro".
+var chapterLinePattern = regexp.MustCompile(`^\s*[(\[]?(?:(\d{1,2}):)?(\d{1,2}):(\d{2})[)\]]?\s*(?:[-–—:|•]\s*)?(.+?)\s*$`)
+
+const chapterPrompt = `
+	You are an expert YouTube content analyst. A video is divided into chapters and you have been given the comments that refer to each chapter, either by timestamp or by name.
+
+	**Chapters:**
+	This is an array of JSON objects. Each object has the chapter 'index', its 'title' and the 'comments' referring to it.
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'chapters'**: An array with exactly one object per chapter in the input. Each object must have 'index' (copied exactly), 'themes' (an array of up to 3 short strings naming what viewers discuss about this chapter) and 'summary' (1-2 sentences on how the chapter landed with the audience).
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+// chapterInput is the per-chapter view sent to Gemini.
+type chapterInput struct {
+	Index    int      `json:"index"`
+	Title    string   `json:"title"`
+	Comments []string `json:"comments"`
+}
+
+// chapterResult holds the LLM-generated parts of the chapter report.
+type chapterResult struct {
+	Chapters []struct {
+		Index   int      `json:"index"`
+		Themes  []string `json:"themes"`
+		Summary string   `json:"summary"`
+	} `json:"chapters"`
+}
+
+// parseChapters reads the chapter list from a video description. It returns
+// nil unless the description holds at least minChapters chapters in ascending
+// order starting at 0:00, as YouTube requires.
+func parseChapters(description string, duration int) []models.Chapter {
+	var chapters []models.Chapter
+	for _, line := range strings.Split(description, "\n") {
+		m := chapterLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		start, ok := parseClock(m[1], m[2], m[3])
+		if !ok || (duration > 0 && start >= duration) {
+			continue
+		}
+		if len(chapters) == 0 && start != 0 {
+			continue
+		}
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].StartSeconds {
+			continue
+		}
+		chapters = append(chapters, models.Chapter{Title: m[4], StartSeconds: start})
+	}
+	if len(chapters) < minChapters {
+		return nil
+	}
+	for i := range chapters {
+		chapters[i].Index = i
+		if i+1 < len(chapters) {
+			chapters[i].EndSeconds = chapters[i+1].StartSeconds
+		} else {
+			chapters[i].EndSeconds = duration
+		}
+	}
+	return chapters
+}
+
+// containsTitle reports whether text mentions a chapter title as a whole phrase.
+func containsTitle(text, title string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], title)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(title)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsLetter(before) && !unicode.IsDigit(before) && !unicode.IsLetter(after) && !unicode.IsDigit(after) {
+			return true
+		}
+		offset = end
+	}
+}
+
+// chapterComments assigns comments to the chapters they refer to, by timestamp
+// or by chapter title. A comment can refer to several chapters.
+func chapterComments(data *models.VideoData, chapters []models.Chapter) [][]*models.Comment {
+	duration, ok := parseISODuration(data.Duration)
+	if !ok {
+		// Imported data may not know the duration; accept any timestamp
+		// and let it fall into the last chapter.
+		duration = 24 * 3600
+	}
+	titles := make([]string, len(chapters))
+	for i, c := range chapters {
+		if len([]rune(c.Title)) >= minChapterTitleRunes {
+			titles[i] = strings.ToLower(c.Title)
+		}
+	}
+
+	assigned := make([][]*models.Comment, len(chapters))
+	for _, comment := range data.Comments {
+		refers := make(map[int]bool)
+		for _, second := range extractTimestamps(comment.Text, duration) {
+			i := sort.Search(len(chapters), func(i int) bool { return chapters[i].StartSeconds > second }) - 1
+			if i >= 0 {
+				refers[i] = true
+			}
+		}
+		text := strings.ToLower(comment.Text)
+		for i, title := range titles {
+			if title != "" && containsTitle(text, title) {
+				refers[i] = true
+			}
+		}
+		for i := range refers {
+			assigned[i] = append(assigned[i], comment)
+		}
+	}
+	return assigned
+}
+
+// buildChapterSentiment reports the mentions and sentiment of every chapter
+// in the description, and asks Gemini for the themes of the chapters that
+// were mentioned. A failed theme request is logged and leaves the themes empty.
+func buildChapterSentiment(ctx context.Context, model *genai.GenerativeModel, data *models.VideoData, annotations []models.CommentAnnotation, trackingID string) []models.ChapterSentiment {
+	duration, _ := parseISODuration(data.Duration)
+	chapters := parseChapters(data.Description, duration)
+	if chapters == nil {
+		return nil
+	}
+	sentiments := make(map[string]string, len(annotations))
+	for _, a := range annotations {
+		sentiments[a.ID] = a.Sentiment
+	}
+
+	assigned := chapterComments(data, chapters)
+	report := make([]models.ChapterSentiment, len(chapters))
+	var inputs []chapterInput
+	for i, chapter := range chapters {
+		label := formatTimestamp(chapter.StartSeconds) + "-"
+		if chapter.EndSeconds > chapter.StartSeconds {
+			label += formatTimestamp(chapter.EndSeconds)
+		}
+		report[i] = models.ChapterSentiment{
+			Chapter:  chapter,
+			Label:    label,
+			Mentions: int64(len(assigned[i])),
+		}
+		for _, c := range assigned[i] {
+			switch sentiments[c.ID] {
+			case "positive":
+				report[i].Positive++
+			case "negative":
+				report[i].Negative++
+			case "neutral":
+				report[i].Neutral++
+			}
+		}
+		if len(assigned[i]) == 0 {
+			continue
+		}
+
+		comments := append([]*models.Comment(nil), assigned[i]...)
+		sort.SliceStable(comments, func(a, b int) bool { return comments[a].LikeCount > comments[b].LikeCount })
+		input := chapterInput{Index: i, Title: chapter.Title}
+		for _, c := range comments[:min(len(comments), maxChapterComments)] {
+			input.Comments = append(input.Comments, truncateRunes(c.Text, 300))
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) == 0 {
+		return report
+	}
+
+	inputJSON, err := json.Marshal(inputs)
+	if err != nil {
+		shared.Logger.Warn("Failed to marshal chapter input", "error", err, "trackingId", trackingID)
+		return report
+	}
+	var result chapterResult
+	if err := generateJSON(ctx, model, fmt.Sprintf(chapterPrompt, string(inputJSON)), trackingID, &result); err != nil {
+		shared.Logger.Warn("Failed to generate chapter themes. Continuing without them.", "error", err, "trackingId", trackingID)
+		return report
+	}
+	for _, c := range result.Chapters {
+		if c.Index < 0 || c.Index >= len(report) {
+			continue
+		}
+		report[c.Index].Themes = c.Themes
+		report[c.Index].Summary = c.Summary
+	}
+	return report
+}
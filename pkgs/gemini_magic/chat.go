@@ -0,0 +1,95 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// maxChatHistory bounds the previous messages sent with a question.
+const maxChatHistory = 20
+
+const chatPrompt = `
+	You are an expert YouTube audience analyst answering a creator's follow-up questions about the comments on one of their videos. You have been given the analysis report of the video and, with every question, the comments most relevant to it.
+
+	**Video Title:**
+	%s
+
+	**Analysis Report:**
+	%s
+
+	**Answering Rules:**
+	1.  Answer in a few short paragraphs or a short list of plain text. Do not use headings.
+	2.  Base every statement on the report or on the provided comments. If they do not contain the answer, say so instead of guessing.
+	3.  Cite the comments you rely on by appending their ID in square brackets, e.g. [Ugz123abc]. Only cite IDs of the provided comments and copy them exactly.
+	4.  Describe how common a view is (e.g. "a few viewers", "most comments") rather than inventing counts.
+	`
+
+const chatTurnPrompt = `**Relevant Comments:**
+Each line is a comment as [comment ID] (likes) text.
+%s
+
+**Question:**
+%s`
+
+// StreamChatAnswer answers a question about the comments of an analyzed video.
+// history holds the previous messages of the conversation and comments the
+// comments retrieved for this question. The answer is passed to onText as it
+// is generated and returned once complete. Streamed answers are not retried.
+func StreamChatAnswer(ctx context.Context, cfg *models.AppConfig, video *models.VideoData, record *models.AnalysisRecord, history []models.ChatMessage, question string, comments []*models.Comment, onText func(string)) (string, error) {
+	reportJSON, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal analysis record: %w", err)
+	}
+
+	client, model, err := newGeminiModel(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	model.SystemInstruction = genai.NewUserContent(genai.Text(fmt.Sprintf(chatPrompt, video.Title, reportJSON)))
+
+	session := model.StartChat()
+	for _, m := range history[max(0, len(history)-maxChatHistory):] {
+		session.History = append(session.History, &genai.Content{Role: m.Role, Parts: []genai.Part{genai.Text(m.Text)}})
+	}
+
+	var lines strings.Builder
+	for _, c := range comments {
+		fmt.Fprintf(&lines, "[%s] (%d) %s\n", c.ID, c.LikeCount, strings.Join(strings.Fields(c.Text), " "))
+	}
+	if len(comments) == 0 {
+		lines.WriteString("No comments matched the question.\n")
+	}
+
+	var answer strings.Builder
+	iter := session.SendMessageStream(ctx, genai.Text(fmt.Sprintf(chatTurnPrompt, lines.String(), question)))
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("Gemini API call failed: %w", err)
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok && text != "" {
+				answer.WriteString(string(text))
+				onText(string(text))
+			}
+		}
+	}
+	if answer.Len() == 0 {
+		return "", fmt.Errorf("received empty response from Gemini")
+	}
+	return answer.String(), nil
+}
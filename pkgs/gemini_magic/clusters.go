@@ -0,0 +1,306 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// minClusterComments is the smallest number of comments worth clustering.
+	minClusterComments = 30
+	// minClusterSize keeps k small enough that clusters are not fragments.
+	minClusterSize = 10
+	maxClusters    = 10
+	// kmeansIterations bounds the refinement of the cluster assignment.
+	kmeansIterations = 50
+	// silhouetteSample is the number of comments used to choose k.
+	silhouetteSample = 400
+	// clusterLabelComments is the number of centroid-nearest comments sent to
+	// Gemini to name a cluster.
+	clusterLabelComments = 10
+)
+
+const clusterPrompt = `
+	You are an expert YouTube community analyst. The comments of a video have been grouped into clusters by meaning. For each cluster you have been given the comments closest to its center.
+
+	**Clusters:**
+	This is an array of JSON objects. Each object has the 'cluster' number and its 'comments'.
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'themes'**: An array with exactly one object per cluster. Each object must have 'cluster' (copied exactly), 'theme_title' (a short, descriptive title for what the comments have in common), 'summary' (1-2 sentences describing the theme) and 'representative_comment' (the comment from the input that best represents the theme, copied exactly).
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+type clusterInput struct {
+	Cluster  int      `json:"cluster"`
+	Comments []string `json:"comments"`
+}
+
+type clusterResult struct {
+	Themes []struct {
+		Cluster               int    `json:"cluster"`
+		ThemeTitle            string `json:"theme_title"`
+		Summary               string `json:"summary"`
+		RepresentativeComment string `json:"representative_comment"`
+	} `json:"themes"`
+}
+
+// clusteringEnabled reports whether the comments of a video are clustered.
+func clusteringEnabled(cfg *models.AppConfig, data *models.VideoData, opts models.AnalyzeOptions) bool {
+	return opts.Clusters && cfg.EmbeddingModel != "" && cfg.EmbeddingModel != "none" && len(data.Comments) >= minClusterComments
+}
+
+// kmeans runs spherical k-means with k-means++ seeding on normalized vectors
+// and returns the cluster of every vector and the normalized centroids.
+func kmeans(vectors [][]float32, k int, rng *rand.Rand) ([]int, [][]float32) {
+	centroids := make([][]float32, 0, k)
+	centroids = append(centroids, append([]float32(nil), vectors[rng.IntN(len(vectors))]...))
+	// distances holds the squared distance of every vector to its nearest
+	// centroid so far.
+	distances := make([]float64, len(vectors))
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			d := max(1-float64(Dot(v, centroids[len(centroids)-1])), 0)
+			distances[i] = min(distances[i], d*d)
+			total += distances[i]
+		}
+		target := rng.Float64() * total
+		next := len(vectors) - 1
+		for i, d := range distances {
+			if target -= d; target <= 0 {
+				next = i
+				break
+			}
+		}
+		centroids = append(centroids, append([]float32(nil), vectors[next]...))
+	}
+
+	assign := make([]int, len(vectors))
+	for iteration := 0; iteration < kmeansIterations; iteration++ {
+		changed := false
+		for i, v := range vectors {
+			if best := nearestCentroid(v, centroids); best != assign[i] {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed && iteration > 0 {
+			break
+		}
+		for c := range centroids {
+			sum := make([]float32, len(vectors[0]))
+			members := 0
+			for i, v := range vectors {
+				if assign[i] != c {
+					continue
+				}
+				members++
+				for d := range v {
+					sum[d] += v[d]
+				}
+			}
+			// An empty cluster keeps its previous centroid.
+			if members > 0 {
+				centroids[c] = Normalize(sum)
+			}
+		}
+	}
+	return assign, centroids
+}
+
+func nearestCentroid(v []float32, centroids [][]float32) int {
+	best, bestSimilarity := 0, float32(-2)
+	for c, centroid := range centroids {
+		if similarity := Dot(v, centroid); similarity > bestSimilarity {
+			best, bestSimilarity = c, similarity
+		}
+	}
+	return best
+}
+
+// silhouette scores a clustering; higher is better.
+func silhouette(vectors [][]float32, assign []int, k int) float64 {
+	var total float64
+	for i := range vectors {
+		sums := make([]float64, k)
+		counts := make([]int, k)
+		for j := range vectors {
+			if i == j {
+				continue
+			}
+			sums[assign[j]] += 1 - float64(Dot(vectors[i], vectors[j]))
+			counts[assign[j]]++
+		}
+		own := assign[i]
+		if counts[own] == 0 {
+			continue
+		}
+		a := sums[own] / float64(counts[own])
+		b := -1.0
+		for c := 0; c < k; c++ {
+			if c == own || counts[c] == 0 {
+				continue
+			}
+			if mean := sums[c] / float64(counts[c]); b < 0 || mean < b {
+				b = mean
+			}
+		}
+		if b < 0 {
+			continue
+		}
+		total += (b - a) / max(a, b)
+	}
+	return total / float64(len(vectors))
+}
+
+// clusterVectors picks the number of clusters with the best silhouette score
+// on a sample of the vectors, then clusters all vectors with it. The random
+// source is seeded so that the same comments produce the same clusters.
+func clusterVectors(vectors [][]float32) ([]int, [][]float32) {
+	maxK := min(maxClusters, len(vectors)/minClusterSize)
+	if maxK < 2 {
+		return nil, nil
+	}
+	rng := rand.New(rand.NewPCG(uint64(len(vectors)), 42))
+	sample := make([][]float32, 0, min(len(vectors), silhouetteSample))
+	for _, i := range rng.Perm(len(vectors))[:cap(sample)] {
+		sample = append(sample, vectors[i])
+	}
+
+	bestK, bestScore := 2, math.Inf(-1)
+	for k := 2; k <= maxK; k++ {
+		assign, _ := kmeans(sample, k, rng)
+		if score := silhouette(sample, assign, k); score > bestScore {
+			bestK, bestScore = k, score
+		}
+	}
+	return kmeans(vectors, bestK, rng)
+}
+
+// clusterThemes embeds the comments, clusters them and asks Gemini to name
+// every cluster from the comments nearest its centroid. Each theme carries
+// the exact member count, like total and sentiment mix of its cluster,
+// largest first.
+func clusterThemes(ctx context.Context, embedder Embedder, model *genai.GenerativeModel, data *models.VideoData, annotations []models.CommentAnnotation, trackingID string) ([]models.ThemeCluster, error) {
+	themes, inputs, err := groupThemes(ctx, embedder, data, annotations)
+	if err != nil || themes == nil {
+		return nil, err
+	}
+	shared.Logger.Info("Clustered comments", "clusters", len(inputs), "trackingId", trackingID)
+
+	inputJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+	var result clusterResult
+	if err := generateJSON(ctx, model, fmt.Sprintf(clusterPrompt, string(inputJSON)), trackingID, &result); err != nil {
+		return nil, fmt.Errorf("cluster labelling failed: %w", err)
+	}
+	for _, t := range result.Themes {
+		if t.Cluster < 0 || t.Cluster >= len(themes) {
+			continue
+		}
+		themes[t.Cluster].ThemeTitle = t.ThemeTitle
+		themes[t.Cluster].Summary = t.Summary
+		themes[t.Cluster].RepresentativeComment = t.RepresentativeComment
+	}
+
+	kept := themes[:0]
+	for _, t := range themes {
+		if t.MemberCount > 0 {
+			kept = append(kept, t)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].MemberCount > kept[j].MemberCount })
+	return kept, nil
+}
+
+// groupThemes embeds and clusters the comments with text and returns the
+// unlabelled theme of every cluster, indexed by cluster, with the comments
+// nearest each centroid for labelling. It returns nil themes when there are
+// too few comments to cluster.
+func groupThemes(ctx context.Context, embedder Embedder, data *models.VideoData, annotations []models.CommentAnnotation) ([]models.ThemeCluster, []clusterInput, error) {
+	// The embedding API rejects empty content, and comments without text
+	// carry no theme anyway.
+	var comments []*models.Comment
+	var texts []string
+	for _, c := range data.Comments {
+		if strings.TrimSpace(c.Text) == "" {
+			continue
+		}
+		comments = append(comments, c)
+		texts = append(texts, c.Text)
+	}
+	if len(comments) < minClusterComments {
+		return nil, nil, nil
+	}
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range vectors {
+		Normalize(v)
+	}
+	assign, centroids := clusterVectors(vectors)
+	if assign == nil {
+		return nil, nil, nil
+	}
+
+	sentiments := make(map[string]string, len(annotations))
+	for _, a := range annotations {
+		sentiments[a.ID] = a.Sentiment
+	}
+	themes := make([]models.ThemeCluster, len(centroids))
+	members := make([][]int, len(centroids))
+	for i, c := range comments {
+		cluster := assign[i]
+		members[cluster] = append(members[cluster], i)
+		theme := &themes[cluster]
+		theme.MemberCount++
+		theme.LikeTotal += c.LikeCount
+		switch sentiments[c.ID] {
+		case "positive":
+			theme.Positive++
+		case "negative":
+			theme.Negative++
+		case "neutral":
+			theme.Neutral++
+		}
+	}
+	for i := range themes {
+		themes[i].Share = float64(themes[i].MemberCount) / float64(len(comments))
+	}
+
+	var inputs []clusterInput
+	for cluster, indices := range members {
+		if len(indices) == 0 {
+			continue
+		}
+		sort.SliceStable(indices, func(a, b int) bool {
+			return Dot(vectors[indices[a]], centroids[cluster]) > Dot(vectors[indices[b]], centroids[cluster])
+		})
+		input := clusterInput{Cluster: cluster}
+		for _, i := range indices[:min(len(indices), clusterLabelComments)] {
+			input.Comments = append(input.Comments, truncateRunes(comments[i].Text, 300))
+		}
+		inputs = append(inputs, input)
+	}
+	return themes, inputs, nil
+}
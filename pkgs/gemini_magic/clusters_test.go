@@ -0,0 +1,213 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// blobs returns n normalized vectors around each of the given directions.
+func blobs(directions [][]float32, n int, rng *rand.Rand) ([][]float32, []int) {
+	var vectors [][]float32
+	var labels []int
+	for label, direction := range directions {
+		for range n {
+			v := make([]float32, len(direction))
+			for d := range v {
+				v[d] = direction[d] + float32(rng.NormFloat64()*0.05)
+			}
+			vectors = append(vectors, Normalize(v))
+			labels = append(labels, label)
+		}
+	}
+	return vectors, labels
+}
+
+var axes = [][]float32{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}}
+
+// sameClustering reports whether two assignments group the vectors the same
+// way, regardless of the cluster numbers.
+func sameClustering(got, want []int) bool {
+	mapping := make(map[int]int)
+	seen := make(map[int]bool)
+	for i := range got {
+		w, ok := mapping[got[i]]
+		if !ok {
+			if seen[want[i]] {
+				return false
+			}
+			mapping[got[i]], seen[want[i]] = want[i], true
+			continue
+		}
+		if w != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestKmeans(t *testing.T) {
+	tests := []struct {
+		name       string
+		directions [][]float32
+		k          int
+	}{
+		{name: "two groups", directions: axes[:2], k: 2},
+		{name: "three groups", directions: axes, k: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			vectors, labels := blobs(tt.directions, 15, rng)
+			assign, centroids := kmeans(vectors, tt.k, rng)
+			if len(centroids) != tt.k {
+				t.Fatalf("got %d centroids, want %d", len(centroids), tt.k)
+			}
+			if !sameClustering(assign, labels) {
+				t.Errorf("assignment %v does not match groups %v", assign, labels)
+			}
+			for c, centroid := range centroids {
+				if norm := Dot(centroid, centroid); norm < 0.999 || norm > 1.001 {
+					t.Errorf("centroid %d has squared norm %f, want 1", c, norm)
+				}
+			}
+		})
+	}
+}
+
+func TestSilhouette(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	vectors, labels := blobs(axes[:2], 10, rng)
+	swapped := append([]int(nil), labels...)
+	for i := 0; i < len(swapped); i += 2 {
+		swapped[i] = 1 - swapped[i]
+	}
+
+	tests := []struct {
+		name     string
+		assign   []int
+		min, max float64
+	}{
+		{name: "separated groups", assign: labels, min: 0.9, max: 1},
+		{name: "mixed groups", assign: swapped, min: -1, max: 0.1},
+		{name: "single cluster", assign: make([]int, len(vectors)), min: 0, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score := silhouette(vectors, tt.assign, 2); score < tt.min || score > tt.max {
+				t.Errorf("silhouette = %f, want between %f and %f", score, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestClusterVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		perGroup int
+		wantK    int
+	}{
+		// Three groups of three leave fewer than two clusters of minClusterSize.
+		{name: "too few vectors", perGroup: 3, wantK: 0},
+		{name: "three groups", perGroup: 2 * minClusterSize, wantK: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vectors, labels := blobs(axes, tt.perGroup, rand.New(rand.NewPCG(3, 4)))
+			assign, centroids := clusterVectors(vectors)
+			if len(centroids) != tt.wantK {
+				t.Fatalf("got %d clusters, want %d", len(centroids), tt.wantK)
+			}
+			if tt.wantK > 0 && !sameClustering(assign, labels) {
+				t.Errorf("assignment %v does not match groups %v", assign, labels)
+			}
+		})
+	}
+}
+
+// fakeEmbedder embeds texts about audio and lighting along different axes and
+// rejects empty texts like the embedding API.
+type fakeEmbedder struct {
+	rng *rand.Rand
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			return nil, errors.New("empty content")
+		}
+		direction := axes[0]
+		if strings.Contains(text, "lighting") {
+			direction = axes[1]
+		}
+		v, _ := blobs([][]float32{direction}, 1, e.rng)
+		vectors[i] = v[0]
+	}
+	return vectors, nil
+}
+
+func TestGroupThemes(t *testing.T) {
+	data := &models.VideoData{}
+	var annotations []models.CommentAnnotation
+	add := func(id, text string, likes int64, sentiment string) {
+		data.Comments = append(data.Comments, &models.Comment{ID: id, Text: text, LikeCount: likes})
+		annotations = append(annotations, models.CommentAnnotation{ID: id, Sentiment: sentiment})
+	}
+	for i := range 20 {
+		sentiment := "positive"
+		if i < 5 {
+			sentiment = "neutral"
+		}
+		add(fmt.Sprintf("audio-%d", i), fmt.Sprintf("the audio mix is great %d", i), 1, sentiment)
+	}
+	for i := range 12 {
+		add(fmt.Sprintf("lighting-%d", i), fmt.Sprintf("the lighting is too dark %d", i), 3, "negative")
+	}
+	for i := range 3 {
+		add(fmt.Sprintf("empty-%d", i), "  ", 100, "neutral")
+	}
+
+	themes, inputs, err := groupThemes(context.Background(), &fakeEmbedder{rng: rand.New(rand.NewPCG(5, 6))}, data, annotations)
+	if err != nil {
+		t.Fatalf("groupThemes: %v", err)
+	}
+	if len(themes) != 2 || len(inputs) != 2 {
+		t.Fatalf("got %d themes and %d label inputs, want 2 each", len(themes), len(inputs))
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].MemberCount > themes[j].MemberCount })
+	want := []models.ThemeCluster{
+		{MemberCount: 20, Share: 20.0 / 32, LikeTotal: 20, Positive: 15, Neutral: 5},
+		{MemberCount: 12, Share: 12.0 / 32, LikeTotal: 36, Negative: 12},
+	}
+	for i := range want {
+		if themes[i] != want[i] {
+			t.Errorf("theme %d = %+v, want %+v", i, themes[i], want[i])
+		}
+	}
+	for _, input := range inputs {
+		if len(input.Comments) != clusterLabelComments {
+			t.Errorf("cluster %d has %d label comments, want %d", input.Cluster, len(input.Comments), clusterLabelComments)
+		}
+	}
+}
+
+func TestGroupThemesTooFewComments(t *testing.T) {
+	data := &models.VideoData{}
+	for i := range minClusterComments {
+		text := "the audio mix is great"
+		if i%2 == 0 {
+			text = ""
+		}
+		data.Comments = append(data.Comments, &models.Comment{ID: fmt.Sprint(i), Text: text})
+	}
+	themes, _, err := groupThemes(context.Background(), &fakeEmbedder{rng: rand.New(rand.NewPCG(5, 6))}, data, nil)
+	if err != nil || themes != nil {
+		t.Errorf("groupThemes = %v, %v; want no themes and no error", themes, err)
+	}
+}
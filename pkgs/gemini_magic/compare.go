@@ -0,0 +1,146 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxCompareVideos = 10
+
+const comparePrompt = `
+	You are an expert YouTube marketing strategist. You have been given the analysis results of several videos, typically one of our own videos and one or more competitor videos covering a similar topic. Your task is to compare how their audiences responded.
+
+	**Videos:**
+	This is an array of JSON objects. Each object contains the statistics, engagement ratios, audience analysis, key themes and feedback of one video, identified by its 'tracking_id'.
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'narrative'**: A 5-10 sentence comparative narrative explaining how the audience reception differs between the videos, which video resonated best and why.
+	2.  **'shared_themes'**: An array of themes that are discussed in more than one video. Each object must have 'theme_title', 'summary' (1-3 sentences on how the theme is received in each video) and 'tracking_ids' (an array of the tracking IDs in which it appears, copied exactly).
+	3.  **'video_highlights'**: An array with exactly one object per video. Each object must have 'tracking_id' (copied exactly), 'unique_praise' (an array of up to 3 strings describing praise that only this video received) and 'unique_criticism' (an array of up to 3 strings describing criticism that only this video received).
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+// compareInput is the per-video view sent to Gemini for comparison.
+type compareInput struct {
+	models.ComparedVideo
+	PositiveFeedback      []models.FeedbackPoint `json:"positive_feedback"`
+	ConstructiveCriticism []models.FeedbackPoint `json:"constructive_criticism"`
+}
+
+// compareResult holds the LLM-generated parts of a comparison report.
+type compareResult struct {
+	Narrative       string                  `json:"narrative"`
+	SharedThemes    []models.SharedTheme    `json:"shared_themes"`
+	VideoHighlights []models.VideoHighlight `json:"video_highlights"`
+}
+
+// CompareVideos aligns the analyses of two or more videos and asks Gemini for a
+// comparative narrative. The report is returned as JSON and stored in GCS as
+// <comparisonId>_comparison.json.
+func CompareVideos(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		comparisonID := uuid.New().String()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "comparisonId", comparisonID)
+
+		trackingIDs := shared.SplitIDs(r.URL.Query().Get("trackingIds"))
+		if len(trackingIDs) < 2 {
+			shared.JSONErrorResponse(w, comparisonID, http.StatusBadRequest, "The 'trackingIds' query parameter must contain at least two tracking IDs")
+			return
+		}
+		if len(trackingIDs) > maxCompareVideos {
+			shared.JSONErrorResponse(w, comparisonID, http.StatusBadRequest, fmt.Sprintf("At most %d videos can be compared", maxCompareVideos))
+			return
+		}
+
+		report := models.ComparisonReport{
+			ComparisonID: comparisonID,
+			RunDate:      time.Now().Format("2006-01-02"),
+		}
+		var inputs []compareInput
+		for _, trackingID := range trackingIDs {
+			video, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+			if err != nil {
+				shared.Logger.Error("could not load raw data", "error", err, "trackingId", trackingID, "comparisonId", comparisonID)
+				shared.JSONErrorResponse(w, comparisonID, http.StatusNotFound, fmt.Sprintf("Failed to retrieve raw data for tracking ID %s", trackingID))
+				return
+			}
+			record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+			if err != nil {
+				shared.Logger.Error("could not load analyzed data", "error", err, "trackingId", trackingID, "comparisonId", comparisonID)
+				shared.JSONErrorResponse(w, comparisonID, http.StatusNotFound, fmt.Sprintf("Failed to retrieve analyzed data for tracking ID %s", trackingID))
+				return
+			}
+
+			compared := models.ComparedVideo{
+				TrackingID:       trackingID,
+				VideoID:          video.ID,
+				Title:            video.Title,
+				ChannelTitle:     video.ChannelTitle,
+				RunDate:          record.RunDate,
+				VideoStatistics:  record.PerformanceMetrics.VideoStatistics,
+				EngagementRatios: record.PerformanceMetrics.EngagementRatios,
+				AudienceAnalysis: record.AudienceAnalysis,
+				KeyThemes:        record.KeyThemes,
+			}
+			report.Videos = append(report.Videos, compared)
+			inputs = append(inputs, compareInput{
+				ComparedVideo:         compared,
+				PositiveFeedback:      record.ContentFeedback.PositiveFeedback,
+				ConstructiveCriticism: record.ContentFeedback.ConstructiveCriticism,
+			})
+		}
+
+		inputBytes, err := json.Marshal(inputs)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal comparison input", "error", err, "comparisonId", comparisonID)
+			shared.JSONErrorResponse(w, comparisonID, http.StatusInternalServerError, "Failed to prepare data for comparison")
+			return
+		}
+
+		client, model, err := newGeminiModel(ctx, cfg)
+		if err != nil {
+			shared.Logger.Error("Failed to create Gemini client", "error", err, "comparisonId", comparisonID)
+			shared.JSONErrorResponse(w, comparisonID, http.StatusInternalServerError, "Failed to create Gemini client")
+			return
+		}
+		defer client.Close()
+
+		shared.Logger.Info("Generating comparison from Gemini.", "videoCount", len(trackingIDs), "comparisonId", comparisonID)
+		var result compareResult
+		if err := generateJSON(ctx, model, fmt.Sprintf(comparePrompt, string(inputBytes)), comparisonID, &result); err != nil {
+			shared.Logger.Error("Failed to generate comparison", "error", err, "comparisonId", comparisonID)
+			shared.JSONErrorResponse(w, comparisonID, http.StatusInternalServerError, "Failed to generate comparison from Gemini")
+			return
+		}
+		report.Narrative = result.Narrative
+		report.SharedThemes = result.SharedThemes
+		report.VideoHighlights = result.VideoHighlights
+
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal comparison report", "error", err, "comparisonId", comparisonID)
+			shared.JSONErrorResponse(w, comparisonID, http.StatusInternalServerError, "Failed to marshal comparison report")
+			return
+		}
+		objectName := fmt.Sprintf("%s_comparison.json", comparisonID)
+		if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, objectName, reportJSON); err != nil {
+			// The comparison is still returned to the caller; only the stored copy is missing.
+			shared.Logger.Warn("Failed to upload comparison report to GCS", "error", err, "comparisonId", comparisonID)
+		}
+
+		shared.Logger.Info("Comparison completed", "duration", time.Since(startTime).String(), "comparisonId", comparisonID)
+		shared.JSONResponse(w, comparisonID, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,90 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// maxEmbedBatch is the maximum number of texts per batch embedding request.
+const maxEmbedBatch = 100
+
+// Embedder turns texts into vectors for clustering and search.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// geminiEmbedder embeds texts with a Gemini embedding model.
+type geminiEmbedder struct {
+	model *genai.EmbeddingModel
+}
+
+// newGeminiEmbedder returns an Embedder for the given model and task, e.g.
+// genai.TaskTypeClustering.
+func newGeminiEmbedder(client *genai.Client, modelName string, taskType genai.TaskType) Embedder {
+	model := client.EmbeddingModel(modelName)
+	model.TaskType = taskType
+	return &geminiEmbedder{model: model}
+}
+
+// NewEmbedder creates a Gemini client and an Embedder for cfg.EmbeddingModel
+// for use outside the analysis, e.g. search. The caller is responsible for
+// closing the client.
+func NewEmbedder(ctx context.Context, cfg *models.AppConfig, taskType genai.TaskType) (*genai.Client, Embedder, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.GEMINIApiKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("genai.NewClient: %w", err)
+	}
+	return client, newGeminiEmbedder(client, cfg.EmbeddingModel, taskType), nil
+}
+
+func (e *geminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		batch := e.model.NewBatch()
+		for _, text := range texts[start:min(start+maxEmbedBatch, len(texts))] {
+			batch.AddContent(genai.Text(truncateRunes(text, 2000)))
+		}
+		resp, err := e.model.BatchEmbedContents(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("embedding request failed: %w", err)
+		}
+		for _, embedding := range resp.Embeddings {
+			vectors = append(vectors, embedding.Values)
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("received %d embeddings for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// Normalize scales a vector to unit length in place, so the dot product of
+// two normalized vectors is their cosine similarity.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
+
+// Dot returns the dot product of two vectors of the same length.
+func Dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,104 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+const maxGenerateRetries = 3
+
+// newGeminiModel creates a Gemini client and a model configured with the
+// service's safety settings. The caller is responsible for closing the client.
+func newGeminiModel(ctx context.Context, cfg *models.AppConfig) (*genai.Client, *genai.GenerativeModel, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.GEMINIApiKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("genai.NewClient: %w", err)
+	}
+
+	model := client.GenerativeModel(cfg.GEMINIModel)
+	model.SafetySettings = []*genai.SafetySetting{
+		{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: genai.HarmBlockNone,
+		},
+		{
+			Category:  genai.HarmCategoryHateSpeech,
+			Threshold: genai.HarmBlockNone,
+		},
+		{
+			Category:  genai.HarmCategorySexuallyExplicit,
+			Threshold: genai.HarmBlockNone,
+		},
+		{
+			Category:  genai.HarmCategoryDangerousContent,
+			Threshold: genai.HarmBlockNone,
+		},
+	}
+	return client, model, nil
+}
+
+// extractJSONObject returns the outermost JSON object embedded in an LLM response.
+func extractJSONObject(rawResponse string) (string, error) {
+	start := strings.Index(rawResponse, "{")
+	end := strings.LastIndex(rawResponse, "}")
+	if start == -1 || end == -1 || end < start {
+		return "", fmt.Errorf("could not find valid JSON object in response: %s", rawResponse)
+	}
+	return rawResponse[start : end+1], nil
+}
+
+// generateJSON sends a prompt to Gemini and decodes the JSON object from the
+// first text part of the response into target. Transport errors, empty
+// responses and responses that do not decode are retried.
+func generateJSON(ctx context.Context, model *genai.GenerativeModel, prompt string, trackingID string, target interface{}) error {
+	return generateJSONParts(ctx, model, trackingID, target, genai.Text(prompt))
+}
+
+// generateJSONParts is generateJSON for multimodal requests, e.g. an image
+// followed by a prompt.
+func generateJSONParts(ctx context.Context, model *genai.GenerativeModel, trackingID string, target interface{}, parts ...genai.Part) error {
+	var lastErr error
+	for attempt := 1; attempt <= maxGenerateRetries; attempt++ {
+		text, err := generateText(ctx, model, parts...)
+		if err == nil {
+			var jsonStr string
+			jsonStr, err = extractJSONObject(text)
+			if err == nil {
+				err = json.Unmarshal([]byte(jsonStr), target)
+			}
+			if err == nil {
+				return nil
+			}
+		}
+		lastErr = err
+		shared.Logger.Warn("Gemini generation attempt failed", "attempt", attempt, "maxRetries", maxGenerateRetries, "error", err, "trackingId", trackingID)
+		if attempt < maxGenerateRetries {
+			time.Sleep(2 * time.Second)
+		}
+	}
+	return fmt.Errorf("failed after %d retries: %w", maxGenerateRetries, lastErr)
+}
+
+// generateText performs a single Gemini call and returns the first text part.
+func generateText(ctx context.Context, model *genai.GenerativeModel, parts ...genai.Part) (string, error) {
+	resp, err := model.GenerateContent(ctx, parts...)
+	if err != nil {
+		return "", fmt.Errorf("Gemini API call failed: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("received empty response from Gemini")
+	}
+	text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	if !ok {
+		return "", fmt.Errorf("Gemini response part is not text")
+	}
+	return string(text), nil
+}
@@ -0,0 +1,75 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// undeterminedLanguage is the ISO 639 code for comments without a detectable language.
+const undeterminedLanguage = "und"
+
+// outputLanguagePattern accepts language codes and names like "sv", "de-AT"
+// or "Brazilian Portuguese", and nothing that could carry instructions.
+var outputLanguagePattern = regexp.MustCompile(`^\p{L}[\p{L} -]{1,39}$`)
+
+const outputLanguageInstruction = `Write every free-text value of your output, such as summaries, explanations, titles and recommendations, in this language: %s. Keep JSON keys and fixed values such as 'Positive', 'positive', 'supported' or 'high' exactly as specified, and copy quoted comments verbatim in their original language.`
+
+// ValidOutputLanguage reports whether a requested report language is a
+// plausible language code or name.
+func ValidOutputLanguage(language string) bool {
+	return outputLanguagePattern.MatchString(language)
+}
+
+// normalizeLanguage reduces a language tag from the model to its lowercase
+// primary subtag, e.g. "en-US" to "en".
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if language == "" {
+		return undeterminedLanguage
+	}
+	return language
+}
+
+// buildLanguageStats counts the annotated comments and their sentiment per
+// language, most common language first.
+func buildLanguageStats(annotations []models.CommentAnnotation) []models.LanguageStats {
+	byLanguage := make(map[string]*models.LanguageStats)
+	for _, a := range annotations {
+		language := a.Language
+		if language == "" {
+			language = undeterminedLanguage
+		}
+		stats, ok := byLanguage[language]
+		if !ok {
+			stats = &models.LanguageStats{Language: language}
+			byLanguage[language] = stats
+		}
+		stats.Comments++
+		switch a.Sentiment {
+		case "positive":
+			stats.Positive++
+		case "negative":
+			stats.Negative++
+		case "neutral":
+			stats.Neutral++
+		}
+	}
+
+	languages := make([]models.LanguageStats, 0, len(byLanguage))
+	for _, stats := range byLanguage {
+		stats.Share = float64(stats.Comments) / float64(len(annotations))
+		languages = append(languages, *stats)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Comments != languages[j].Comments {
+			return languages[i].Comments > languages[j].Comments
+		}
+		return languages[i].Language < languages[j].Language
+	})
+	return languages
+}
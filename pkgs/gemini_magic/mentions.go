@@ -0,0 +1,196 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// maxMentionQuotes is the number of most liked comments quoted per entity.
+const maxMentionQuotes = 3
+
+// mentionInstruction extends the comment annotations with brand and product
+// mentions. The known entities of the dictionary follow it.
+const mentionInstruction = "\t    *   'mentions': An array with one object per brand, company, product or competitor the comment names, each with the 'entity' name, its 'type' ('brand' for brands and companies, 'product' for products and services, 'competitor' for rivals of the brands or products the video is about or sponsored by) and the comment's 'sentiment' ('positive', 'negative' or 'neutral') towards it. Do not list YouTube, this channel or people. Use an empty array if the comment names none.\n"
+
+const descriptionMentionPrompt = `
+	You are an expert in brand and sponsorship analysis. Find every brand, company, product or service named in the following YouTube video description, including sponsors, affiliate links and the gear used.
+
+	**Video Description:**
+	%s
+	%s
+	**Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'mentions'**: An array with one object per entity, each with the 'entity' name and its 'type' ('brand', 'product' or 'competitor'). Do not list YouTube or social networks linked for following the channel. Return an empty array if the description names none.
+	`
+
+// mentionPrompt returns the mention instruction for the annotations, with the
+// known entities of the dictionary so the model uses their canonical names.
+func mentionPrompt(dictionary *models.MentionDictionary) string {
+	return mentionInstruction + knownEntities(dictionary)
+}
+
+func knownEntities(dictionary *models.MentionDictionary) string {
+	if dictionary == nil || len(dictionary.Entities) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\t        Known entities, as 'name' (type): aliases. Use the name for any of its aliases:\n")
+	for _, e := range dictionary.Entities {
+		fmt.Fprintf(&b, "\t        *   '%s' (%s)", e.Name, e.Type)
+		if len(e.Aliases) > 0 {
+			fmt.Fprintf(&b, ": %s", strings.Join(e.Aliases, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// entityIndex maps the lowercase names and aliases of the dictionary to their
+// entities.
+type entityIndex map[string]models.DictionaryEntity
+
+func newEntityIndex(dictionary *models.MentionDictionary) entityIndex {
+	index := make(entityIndex)
+	if dictionary == nil {
+		return index
+	}
+	for _, e := range dictionary.Entities {
+		index[strings.ToLower(e.Name)] = e
+		for _, alias := range e.Aliases {
+			index[strings.ToLower(alias)] = e
+		}
+	}
+	return index
+}
+
+// normalizeMentions maps aliases to canonical names, applies the dictionary
+// types and keeps one mention per entity.
+func normalizeMentions(raw []models.EntityMention, index entityIndex) []models.EntityMention {
+	seen := make(map[string]bool, len(raw))
+	var mentions []models.EntityMention
+	for _, m := range raw {
+		m.Entity = strings.TrimSpace(m.Entity)
+		m.Type = strings.ToLower(strings.TrimSpace(m.Type))
+		m.Sentiment = strings.ToLower(strings.TrimSpace(m.Sentiment))
+		if e, ok := index[strings.ToLower(m.Entity)]; ok {
+			m.Entity, m.Type = e.Name, e.Type
+		}
+		if m.Entity == "" {
+			continue
+		}
+		if m.Type != models.EntityProduct && m.Type != models.EntityCompetitor {
+			m.Type = models.EntityBrand
+		}
+		key := strings.ToLower(m.Entity)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, m)
+	}
+	return mentions
+}
+
+// descriptionMentions extracts the entities named in the video description.
+// Failures are logged and yield no mentions, since they only mark entities
+// as named in the description.
+func descriptionMentions(ctx context.Context, model *genai.GenerativeModel, fullData *models.VideoData, dictionary *models.MentionDictionary, trackingID string) []models.EntityMention {
+	if strings.TrimSpace(fullData.Description) == "" {
+		return nil
+	}
+	var result struct {
+		Mentions []models.EntityMention `json:"mentions"`
+	}
+	prompt := fmt.Sprintf(descriptionMentionPrompt, fullData.Description, knownEntities(dictionary))
+	if err := generateJSON(ctx, model, prompt, trackingID, &result); err != nil {
+		shared.Logger.Warn("Failed to extract mentions from the description. Continuing without them.", "error", err, "trackingId", trackingID)
+		return nil
+	}
+	return normalizeMentions(result.Mentions, newEntityIndex(dictionary))
+}
+
+// buildMentionStats counts the comments mentioning each entity with their
+// sentiment and likes, most mentioned first. Entities only named in the
+// description are kept with zero mentions. Names outside the dictionary are
+// grouped regardless of case and reported in their most common spelling.
+func buildMentionStats(comments []*models.Comment, annotations []models.CommentAnnotation, inDescription []models.EntityMention) []models.EntityStats {
+	byID := make(map[string]*models.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+	type entity struct {
+		stats     models.EntityStats
+		spellings map[string]int
+		comments  []*models.Comment
+	}
+	entities := make(map[string]*entity)
+	get := func(m models.EntityMention) *entity {
+		key := strings.ToLower(m.Entity)
+		e, ok := entities[key]
+		if !ok {
+			e = &entity{stats: models.EntityStats{Type: m.Type, TopQuotes: []string{}}, spellings: make(map[string]int)}
+			entities[key] = e
+		}
+		e.spellings[m.Entity]++
+		return e
+	}
+
+	for _, a := range annotations {
+		for _, m := range a.Mentions {
+			e := get(m)
+			e.stats.Mentions++
+			switch m.Sentiment {
+			case "positive":
+				e.stats.Positive++
+			case "negative":
+				e.stats.Negative++
+			case "neutral":
+				e.stats.Neutral++
+			}
+			if c, ok := byID[a.ID]; ok {
+				e.stats.LikeTotal += c.LikeCount
+				e.comments = append(e.comments, c)
+			}
+		}
+	}
+	for _, m := range inDescription {
+		get(m).stats.InDescription = true
+	}
+
+	stats := make([]models.EntityStats, 0, len(entities))
+	for _, e := range entities {
+		var best int
+		for spelling, count := range e.spellings {
+			if count > best || (count == best && spelling < e.stats.Entity) {
+				e.stats.Entity, best = spelling, count
+			}
+		}
+		if len(annotations) > 0 {
+			e.stats.Share = float64(e.stats.Mentions) / float64(len(annotations))
+		}
+		sort.SliceStable(e.comments, func(i, j int) bool {
+			return e.comments[i].LikeCount > e.comments[j].LikeCount
+		})
+		for _, c := range e.comments[:min(len(e.comments), maxMentionQuotes)] {
+			e.stats.TopQuotes = append(e.stats.TopQuotes, truncateRunes(c.Text, 200))
+		}
+		stats = append(stats, e.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Mentions != stats[j].Mentions {
+			return stats[i].Mentions > stats[j].Mentions
+		}
+		if stats[i].LikeTotal != stats[j].LikeTotal {
+			return stats[i].LikeTotal > stats[j].LikeTotal
+		}
+		return stats[i].Entity < stats[j].Entity
+	})
+	return stats
+}
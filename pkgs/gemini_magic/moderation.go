@@ -0,0 +1,98 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// moderationPrompt is appended to every map prompt. Only flagged comments are
+// listed, so the pass adds little output to clean chunks.
+const moderationPrompt = `
+	4.  **Moderation Flags ('moderation_flags'):** An array with one object per comment in this chunk that a community moderator should review. Do NOT list harmless comments, including plain criticism of the video; return an empty array if no comment qualifies. Each object must have:
+	    *   'id': The 'id' of the comment, copied exactly.
+	    *   'category': 'harassment' (insults, threats or bullying aimed at a person), 'hate' (attacks on a group based on identity), 'spam' (scams, phishing or suspicious links, repeated or bot-like messages) or 'self_promotion' (advertising another channel, product or service).
+	    *   'severity': 'high' (threats, slurs or scams to remove immediately), 'medium' (clear violations) or 'low' (borderline cases).
+	    *   'reason': A short explanation of the flag.
+	`
+
+// moderationCategories lists the categories in report order.
+var moderationCategories = []string{models.ModerationHarassment, models.ModerationHate, models.ModerationSpam, models.ModerationSelfPromotion}
+
+var severityRank = map[string]int{models.SeverityHigh: 3, models.SeverityMedium: 2, models.SeverityLow: 1}
+
+// moderationOutput is a flag as returned by the map step.
+type moderationOutput struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Reason   string `json:"reason"`
+}
+
+// moderationFlags keeps one valid flag per comment of a chunk and copies the
+// comment's text and likes into it.
+func moderationFlags(raw []moderationOutput, comments []*models.Comment) []models.ModerationFlag {
+	byID := make(map[string]*models.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+	var flags []models.ModerationFlag
+	for _, f := range raw {
+		c, ok := byID[f.ID]
+		category := strings.ToLower(strings.TrimSpace(f.Category))
+		severity := strings.ToLower(strings.TrimSpace(f.Severity))
+		if !ok || severityRank[severity] == 0 || !slices.Contains(moderationCategories, category) {
+			continue
+		}
+		delete(byID, f.ID)
+		flags = append(flags, models.ModerationFlag{
+			CommentID: c.ID,
+			Category:  category,
+			Severity:  severity,
+			Reason:    f.Reason,
+			Text:      c.Text,
+			LikeCount: c.LikeCount,
+		})
+	}
+	return flags
+}
+
+// buildModerationReport counts the flags per category and severity and orders
+// them so the most severe and most visible comments come first.
+func buildModerationReport(flags []models.ModerationFlag, commentCount int) *models.ModerationReport {
+	report := &models.ModerationReport{
+		FlaggedComments: int64(len(flags)),
+		Flags:           append([]models.ModerationFlag{}, flags...),
+	}
+	if commentCount > 0 {
+		report.FlaggedShare = float64(len(flags)) / float64(commentCount)
+	}
+	sort.SliceStable(report.Flags, func(i, j int) bool {
+		a, b := report.Flags[i], report.Flags[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.LikeCount > b.LikeCount
+	})
+
+	for _, category := range moderationCategories {
+		counts := models.ModerationCategory{Category: category}
+		for _, f := range flags {
+			if f.Category != category {
+				continue
+			}
+			counts.Count++
+			switch f.Severity {
+			case models.SeverityHigh:
+				counts.High++
+			case models.SeverityMedium:
+				counts.Medium++
+			case models.SeverityLow:
+				counts.Low++
+			}
+		}
+		report.Categories = append(report.Categories, counts)
+	}
+	return report
+}
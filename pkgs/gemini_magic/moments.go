@@ -0,0 +1,174 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// maxMomentQuotes is the number of quotes kept per heatmap segment.
+const maxMomentQuotes = 3
+
+var (
+	isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	// timestampPattern matches m:ss, mm:ss and h:mm:ss. The word boundaries
+	// skip clock times such as 10:30pm.
+	timestampPattern = regexp.MustCompile(`\b(?:(\d{1,2}):)?(\d{1,2}):(\d{2})\b`)
+)
+
+// parseISODuration converts a YouTube duration such as PT1H2M3S to seconds.
+func parseISODuration(value string) (int, bool) {
+	m := isoDurationPattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0, false
+	}
+	total := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		if m[i+1] != "" {
+			n, _ := strconv.Atoi(m[i+1])
+			total += n * unit
+		}
+	}
+	return total, total > 0
+}
+
+// parseClock converts a timestamp match (hours, minutes, seconds; hours may
+// be empty) to seconds.
+func parseClock(hoursPart, minutesPart, secondsPart string) (int, bool) {
+	hours, _ := strconv.Atoi(hoursPart)
+	minutes, _ := strconv.Atoi(minutesPart)
+	seconds, _ := strconv.Atoi(secondsPart)
+	if seconds >= 60 || (hoursPart != "" && minutes >= 60) {
+		return 0, false
+	}
+	return hours*3600 + minutes*60 + seconds, true
+}
+
+// extractTimestamps returns the distinct timestamps in text, in seconds, that
+// fall within a video of the given length.
+func extractTimestamps(text string, duration int) []int {
+	var seconds []int
+	seen := make(map[int]bool)
+	for _, m := range timestampPattern.FindAllStringSubmatch(text, -1) {
+		total, ok := parseClock(m[1], m[2], m[3])
+		if !ok || total > duration || seen[total] {
+			continue
+		}
+		seen[total] = true
+		seconds = append(seconds, total)
+	}
+	return seconds
+}
+
+// formatTimestamp formats seconds the way viewers write them, e.g. 3:41 or 1:02:03.
+func formatTimestamp(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// segmentLength picks the heatmap resolution for a video so long videos are
+// not spread over hundreds of sparse segments.
+func segmentLength(duration int) int {
+	switch {
+	case duration <= 10*60:
+		return 10
+	case duration <= 60*60:
+		return 30
+	default:
+		return 60
+	}
+}
+
+// buildMomentHeatmap buckets the timestamps mentioned in comments into
+// segments of the video with the sentiment of the mentioning comments and the
+// most liked quotes. Only segments with mentions are returned, in video order.
+func buildMomentHeatmap(data *models.VideoData, annotations []models.CommentAnnotation) []models.MomentBucket {
+	duration, ok := parseISODuration(data.Duration)
+	if !ok {
+		return nil
+	}
+	sentiments := make(map[string]string, len(annotations))
+	for _, a := range annotations {
+		sentiments[a.ID] = a.Sentiment
+	}
+
+	type segment struct {
+		bucket   models.MomentBucket
+		seconds  map[int]int64
+		comments []*models.Comment
+	}
+	length := segmentLength(duration)
+	segments := make(map[int]*segment)
+	for _, c := range data.Comments {
+		counted := make(map[int]bool)
+		for _, second := range extractTimestamps(c.Text, duration) {
+			// A timestamp at the very end of the video belongs to the last
+			// segment rather than an empty one starting at the duration.
+			start := min(second, duration-1) / length * length
+			s, ok := segments[start]
+			if !ok {
+				end := min(start+length, duration)
+				s = &segment{
+					bucket: models.MomentBucket{
+						StartSeconds: start,
+						EndSeconds:   end,
+						Label:        formatTimestamp(start) + "-" + formatTimestamp(end),
+					},
+					seconds: make(map[int]int64),
+				}
+				segments[start] = s
+			}
+			s.seconds[second]++
+			// A comment counts once per segment even if it names several
+			// seconds in it.
+			if counted[start] {
+				continue
+			}
+			counted[start] = true
+			s.bucket.Mentions++
+			s.comments = append(s.comments, c)
+			switch sentiments[c.ID] {
+			case "positive":
+				s.bucket.Positive++
+			case "negative":
+				s.bucket.Negative++
+			case "neutral":
+				s.bucket.Neutral++
+			}
+		}
+	}
+
+	heatmap := make([]models.MomentBucket, 0, len(segments))
+	for _, s := range segments {
+		var peakCount int64
+		for second, count := range s.seconds {
+			if count > peakCount || (count == peakCount && second < s.bucket.PeakSecond) {
+				s.bucket.PeakSecond, peakCount = second, count
+			}
+		}
+		sort.SliceStable(s.comments, func(i, j int) bool {
+			return s.comments[i].LikeCount > s.comments[j].LikeCount
+		})
+		for _, c := range s.comments[:min(len(s.comments), maxMomentQuotes)] {
+			s.bucket.TopQuotes = append(s.bucket.TopQuotes, truncateRunes(c.Text, 200))
+		}
+		heatmap = append(heatmap, s.bucket)
+	}
+	sort.Slice(heatmap, func(i, j int) bool {
+		return heatmap[i].StartSeconds < heatmap[j].StartSeconds
+	})
+	return heatmap
+}
+
+// truncateRunes shortens text to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,154 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// maxThumbnailBytes is well above the size of a maxres YouTube thumbnail.
+	maxThumbnailBytes = 5 << 20
+	// maxPackagingComments bounds the comments about the packaging sent to Gemini.
+	maxPackagingComments = 50
+)
+
+// packagingKeywords select comments that talk about the title or thumbnail.
+var packagingKeywords = []string{"thumbnail", "title", "clickbait", "click bait", "misleading", "bait"}
+
+var thumbnailClient = &http.Client{Timeout: 15 * time.Second}
+
+const packagingPrompt = `
+	You are an expert YouTube packaging strategist. You have been given the thumbnail image of a video (unless noted otherwise), its title and description, a summary of how the audience reacted, and the comments that talk about the title or thumbnail. Your task is to critique the packaging of the video.
+
+	**Thumbnail:** %s
+
+	**Title:** %s
+
+	**Description:**
+	%s
+
+	**Audience Reaction:**
+	%s
+
+	**Comments about the Packaging:**
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'summary'**: 2-4 sentences on how well the title and thumbnail represent the video and attract its audience.
+	2.  **'title_clarity'**: An object with 'score' (1-10, 10 is clearest) and 'assessment' (1-2 sentences on whether a viewer understands what the video is about from the title).
+	3.  **'thumbnail_clarity'**: An object with 'score' (1-10) and 'assessment' on the readability, focus and message of the thumbnail. Use score 0 and an empty assessment if no thumbnail was provided.
+	4.  **'clickbait_risk'**: An object with 'score' (1-10, 10 is the highest risk) and 'assessment' on whether the packaging over-promises compared to what commenters say the video delivers.
+	5.  **'audience_mismatch'**: An array of mismatches between what the packaging promises and what commenters say. Each object must have 'promise', 'reality' and 'representative_comment'.
+	6.  **'suggestions'**: An array of up to 3 concrete packaging improvements. Each object must have 'suggestion' and 'reason'.
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+// downloadThumbnail fetches a thumbnail image and returns it with its image
+// format as expected by genai.ImageData, e.g. "jpeg".
+func downloadThumbnail(ctx context.Context, thumbnailURL string) ([]byte, string, error) {
+	parsed, err := url.Parse(thumbnailURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, "", fmt.Errorf("invalid thumbnail URL %q", thumbnailURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := thumbnailClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not download thumbnail: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("thumbnail download returned status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	format, ok := strings.CutPrefix(contentType, "image/")
+	if !ok {
+		return nil, "", fmt.Errorf("thumbnail has unexpected content type %q", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read thumbnail: %w", err)
+	}
+	if len(data) > maxThumbnailBytes {
+		return nil, "", fmt.Errorf("thumbnail is larger than %d bytes", maxThumbnailBytes)
+	}
+	return data, format, nil
+}
+
+// packagingComments returns the most liked comments that mention the title,
+// thumbnail or clickbait.
+func packagingComments(comments []*models.Comment) []string {
+	var matches []*models.Comment
+	for _, c := range comments {
+		text := strings.ToLower(c.Text)
+		for _, keyword := range packagingKeywords {
+			if strings.Contains(text, keyword) {
+				matches = append(matches, c)
+				break
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].LikeCount > matches[j].LikeCount })
+	texts := make([]string, 0, min(len(matches), maxPackagingComments))
+	for _, c := range matches[:min(len(matches), maxPackagingComments)] {
+		texts = append(texts, truncateRunes(c.Text, 300))
+	}
+	return texts
+}
+
+// critiquePackaging sends the thumbnail, title and description with the
+// audience reaction to the multimodal model and returns the packaging
+// critique. When the thumbnail cannot be downloaded, only the text is reviewed.
+func critiquePackaging(ctx context.Context, model *genai.GenerativeModel, record *models.AnalysisRecord, fullData *models.VideoData, trackingID string) (*models.PackagingCritique, error) {
+	var parts []genai.Part
+	thumbnailNote := "The first part of this request is the thumbnail image."
+	image, format, err := downloadThumbnail(ctx, fullData.ThumbnailURL)
+	if err != nil {
+		shared.Logger.Warn("Failed to download thumbnail. Critiquing the title only.", "error", err, "thumbnailUrl", fullData.ThumbnailURL, "trackingId", trackingID)
+		thumbnailNote = "Not available."
+	} else {
+		parts = append(parts, genai.ImageData(format, image))
+	}
+
+	themes := make([]string, 0, len(record.KeyThemes))
+	for _, t := range record.KeyThemes {
+		themes = append(themes, t.ThemeTitle+": "+t.Summary)
+	}
+	reaction, err := json.Marshal(map[string]interface{}{
+		"summary":    record.AudienceAnalysis.Summary,
+		"sentiment":  record.AudienceAnalysis.SentimentLabel,
+		"key_themes": themes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	commentsJSON, err := json.Marshal(packagingComments(fullData.Comments))
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(packagingPrompt, thumbnailNote, fullData.Title, truncateRunes(fullData.Description, 5000), string(reaction), string(commentsJSON))
+	parts = append(parts, genai.Text(prompt))
+	var critique models.PackagingCritique
+	if err := generateJSONParts(ctx, model, trackingID, &critique, parts...); err != nil {
+		return nil, fmt.Errorf("packaging critique failed: %w", err)
+	}
+	critique.ThumbnailReviewed = image != nil
+	return &critique, nil
+}
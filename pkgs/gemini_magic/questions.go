@@ -0,0 +1,220 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// questionSimilarity is the cosine similarity of question embeddings that
+	// puts two questions in one cluster.
+	questionSimilarity = 0.85
+	// questionWordOverlap replaces questionSimilarity when no embeddings are
+	// available.
+	questionWordOverlap = 0.5
+	// maxQuestionExamples is the number of comment IDs listed per cluster.
+	maxQuestionExamples = 5
+	// maxLabelledQuestions bounds the clusters phrased by Gemini; smaller
+	// clusters keep the text of their most liked comment.
+	maxLabelledQuestions = 50
+)
+
+// questionInstruction extends the comment annotations with question detection.
+const questionInstruction = "\t    *   'question': true if the comment asks the creator a genuine question they could answer, e.g. about the content, gear, plans or where to find something; false for rhetorical questions, jokes and questions to other viewers.\n"
+
+const questionPrompt = `
+	You are an expert YouTube community analyst. Viewers asked the creator questions in the comments, and questions that mean the same have been grouped.
+
+	**Question Groups:**
+	This is an array of JSON objects. Each object has the 'group' number and the 'questions' asked, most liked first.
+	%s
+
+	**Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'groups'**: An array with exactly one object per group. Each object must have 'group' (copied exactly) and 'question' (the question of the group as one short, clear sentence ending with a question mark).
+	`
+
+type questionGroupInput struct {
+	Group     int      `json:"group"`
+	Questions []string `json:"questions"`
+}
+
+type questionGroupResult struct {
+	Groups []struct {
+		Group    int    `json:"group"`
+		Question string `json:"question"`
+	} `json:"groups"`
+}
+
+// answersOf returns the replies to a question that count as answers: replies
+// by the channel owner and pinned or hearted replies.
+func answersOf(question *models.Comment, replies []*models.Comment, ownerID string) []models.QuestionAnswer {
+	var answers []models.QuestionAnswer
+	for _, r := range replies {
+		var by string
+		switch {
+		case ownerID != "" && r.AuthorID == ownerID:
+			by = models.AnsweredByOwner
+		case r.Pinned:
+			by = models.AnsweredByPinnedReply
+		case r.Hearted:
+			by = models.AnsweredByHeartedReply
+		default:
+			continue
+		}
+		answers = append(answers, models.QuestionAnswer{QuestionID: question.ID, ReplyID: r.ID, AnsweredBy: by})
+	}
+	return answers
+}
+
+// groupQuestions clusters questions greedily, most liked first: a question
+// joins the first cluster whose leading question it is similar to, or starts
+// a new one. With vectors, similarity is cosine similarity; without, it is
+// word overlap.
+func groupQuestions(questions []*models.Comment, vectors [][]float32) [][]int {
+	var groups [][]int
+	for i := range questions {
+		joined := false
+		for g, members := range groups {
+			leader := members[0]
+			var similar bool
+			if vectors != nil {
+				similar = Dot(vectors[i], vectors[leader]) >= questionSimilarity
+			} else {
+				similar = shared.SimilarText(questions[i].Text, questions[leader].Text, questionWordOverlap)
+			}
+			if similar {
+				groups[g] = append(groups[g], i)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, []int{i})
+		}
+	}
+	return groups
+}
+
+// trackQuestions finds the top-level comments annotated as questions, checks
+// their replies for answers and groups them into clusters, open clusters
+// first. Embedding or phrasing failures fall back to word overlap and the
+// comment text, so the tracker never fails the analysis.
+func trackQuestions(ctx context.Context, embedder Embedder, model *genai.GenerativeModel, data *models.VideoData, annotations []models.CommentAnnotation, trackingID string) *models.QuestionReport {
+	isQuestion := make(map[string]bool)
+	for _, a := range annotations {
+		if a.Question {
+			isQuestion[a.ID] = true
+		}
+	}
+	var questions []*models.Comment
+	replies := make(map[string][]*models.Comment)
+	for _, c := range data.Comments {
+		if c.ParentID != "" {
+			replies[c.ParentID] = append(replies[c.ParentID], c)
+		} else if isQuestion[c.ID] && c.Source != models.SourceLiveChat && c.AuthorID != data.ChannelID {
+			questions = append(questions, c)
+		}
+	}
+	report := &models.QuestionReport{Questions: int64(len(questions)), Clusters: []models.QuestionCluster{}}
+	if len(questions) == 0 {
+		return report
+	}
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].LikeCount > questions[j].LikeCount })
+
+	var vectors [][]float32
+	if embedder != nil {
+		texts := make([]string, len(questions))
+		for i, q := range questions {
+			texts[i] = q.Text
+		}
+		var err error
+		if vectors, err = embedder.Embed(ctx, texts); err != nil {
+			shared.Logger.Warn("Failed to embed questions. Grouping them by word overlap.", "error", err, "trackingId", trackingID)
+			vectors = nil
+		}
+		for _, v := range vectors {
+			Normalize(v)
+		}
+	}
+
+	for _, members := range groupQuestions(questions, vectors) {
+		leader := questions[members[0]]
+		cluster := models.QuestionCluster{ID: leader.ID, Question: truncateRunes(leader.Text, 300), Status: models.QuestionOpen, ExampleCommentIDs: []string{}}
+		for _, i := range members {
+			q := questions[i]
+			cluster.Comments++
+			cluster.LikeTotal += q.LikeCount
+			if answers := answersOf(q, replies[q.ID], data.ChannelID); len(answers) > 0 {
+				cluster.Answers = append(cluster.Answers, answers...)
+				report.Answered++
+			}
+			if len(cluster.ExampleCommentIDs) < maxQuestionExamples {
+				cluster.ExampleCommentIDs = append(cluster.ExampleCommentIDs, q.ID)
+			}
+		}
+		if len(cluster.Answers) > 0 {
+			cluster.Status = models.QuestionAnswered
+		}
+		report.Clusters = append(report.Clusters, cluster)
+	}
+	report.Open = report.Questions - report.Answered
+
+	sort.SliceStable(report.Clusters, func(i, j int) bool {
+		a, b := report.Clusters[i], report.Clusters[j]
+		if a.Status != b.Status {
+			return a.Status == models.QuestionOpen
+		}
+		if a.Comments != b.Comments {
+			return a.Comments > b.Comments
+		}
+		return a.LikeTotal > b.LikeTotal
+	})
+	phraseQuestions(ctx, model, report.Clusters, questions, trackingID)
+	shared.Logger.Info("Tracked questions", "questions", report.Questions, "answered", report.Answered, "clusters", len(report.Clusters), "trackingId", trackingID)
+	return report
+}
+
+// phraseQuestions asks Gemini to phrase the question of each cluster that
+// has more than one comment. Single questions keep their own text.
+func phraseQuestions(ctx context.Context, model *genai.GenerativeModel, clusters []models.QuestionCluster, questions []*models.Comment, trackingID string) {
+	byID := make(map[string]*models.Comment, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+	var inputs []questionGroupInput
+	for i, c := range clusters {
+		if c.Comments < 2 || len(inputs) == maxLabelledQuestions {
+			continue
+		}
+		input := questionGroupInput{Group: i}
+		for _, id := range c.ExampleCommentIDs {
+			input.Questions = append(input.Questions, truncateRunes(byID[id].Text, 300))
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) == 0 {
+		return
+	}
+	inputJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return
+	}
+	var result questionGroupResult
+	if err := generateJSON(ctx, model, fmt.Sprintf(questionPrompt, string(inputJSON)), trackingID, &result); err != nil {
+		shared.Logger.Warn("Failed to phrase question clusters. Keeping the comment texts.", "error", err, "trackingId", trackingID)
+		return
+	}
+	for _, g := range result.Groups {
+		if g.Group >= 0 && g.Group < len(clusters) && g.Question != "" {
+			clusters[g.Group].Question = g.Question
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxRollupVideos = 50
+
+const rollupPrompt = `
+	You are an expert YouTube marketing strategist. You have been given condensed analysis reports for several videos from the same channel. Your task is to synthesize them into a channel-level report that explains what the channel's audience says across these videos.
+
+	**Channel:** %s
+
+	**Per-Video Digests:**
+	This is an array of JSON objects. Each object summarizes the analysis of one video and is identified by its 'tracking_id'.
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'executive_summary'**: A 5-10 sentence overview of how the audience responds to the channel as a whole, including how sentiment has evolved across the videos.
+	2.  **'recurring_themes'**: An array of up to 10 themes that appear in more than one video. Each object must have 'theme_title', 'summary' (2-3 sentences) and 'video_count' (integer number of videos in which the theme appears).
+	3.  **'persistent_criticism'**: An array of up to 5 criticisms that are repeated across videos. Each object must have 'point', 'video_count' (integer) and 'representative_comment'.
+	4.  **'best_performers'**: An array of up to 3 videos that performed best, considering both audience sentiment and engagement ratios. Each object must have 'tracking_id' (copied exactly from the digest) and 'reason'.
+	5.  **'worst_performers'**: An array of up to 3 videos that performed worst. Each object must have 'tracking_id' (copied exactly from the digest) and 'reason'.
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+// rollupDigest is the condensed view of a single video analysis sent to Gemini.
+type rollupDigest struct {
+	TrackingID            string                  `json:"tracking_id"`
+	Title                 string                  `json:"title"`
+	RunDate               string                  `json:"run_date"`
+	ExecutiveSummary      string                  `json:"executive_summary"`
+	EngagementRatios      models.EngagementRatios `json:"engagement_ratios"`
+	AudienceAnalysis      models.AudienceAnalysis `json:"audience_analysis"`
+	KeyThemes             []models.KeyTheme       `json:"key_themes"`
+	PositiveFeedback      []models.FeedbackPoint  `json:"positive_feedback"`
+	ConstructiveCriticism []models.FeedbackPoint  `json:"constructive_criticism"`
+}
+
+// rollupResult holds the LLM-generated parts of a channel report.
+type rollupResult struct {
+	ExecutiveSummary    string                       `json:"executive_summary"`
+	RecurringThemes     []models.RecurringTheme      `json:"recurring_themes"`
+	PersistentCriticism []models.PersistentCriticism `json:"persistent_criticism"`
+	BestPerformers      []models.VideoPerformance    `json:"best_performers"`
+	WorstPerformers     []models.VideoPerformance    `json:"worst_performers"`
+}
+
+// resolvePerformers fills in video IDs and titles for the tracking IDs returned by
+// Gemini and drops any entry that does not refer to a video in the report.
+func resolvePerformers(performers []models.VideoPerformance, points map[string]models.VideoSentimentPoint) []models.VideoPerformance {
+	resolved := []models.VideoPerformance{}
+	for _, p := range performers {
+		point, ok := points[p.TrackingID]
+		if !ok {
+			continue
+		}
+		p.VideoID = point.VideoID
+		p.Title = point.Title
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
+
+// RollupChannel reduces the analyses of several videos from one channel into a
+// single channel report and stores it in GCS as <reportId>_channel_report.json.
+func RollupChannel(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		reportID := uuid.New().String()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "reportId", reportID)
+
+		trackingIDs := shared.SplitIDs(r.URL.Query().Get("trackingIds"))
+		if len(trackingIDs) == 0 {
+			shared.JSONErrorResponse(w, reportID, http.StatusBadRequest, "Missing 'trackingIds' query parameter")
+			return
+		}
+		if len(trackingIDs) > maxRollupVideos {
+			shared.JSONErrorResponse(w, reportID, http.StatusBadRequest, fmt.Sprintf("A channel report can include at most %d videos", maxRollupVideos))
+			return
+		}
+
+		report := models.ChannelReport{
+			ReportID:    reportID,
+			RunDate:     time.Now().Format("2006-01-02"),
+			TrackingIDs: trackingIDs,
+		}
+		var digests []rollupDigest
+		points := make(map[string]models.VideoSentimentPoint)
+
+		for _, trackingID := range trackingIDs {
+			video, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+			if err != nil {
+				shared.Logger.Error("could not load raw data", "error", err, "trackingId", trackingID, "reportId", reportID)
+				shared.JSONErrorResponse(w, reportID, http.StatusNotFound, fmt.Sprintf("Failed to retrieve raw data for tracking ID %s", trackingID))
+				return
+			}
+			record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+			if err != nil {
+				shared.Logger.Error("could not load analyzed data", "error", err, "trackingId", trackingID, "reportId", reportID)
+				shared.JSONErrorResponse(w, reportID, http.StatusNotFound, fmt.Sprintf("Failed to retrieve analyzed data for tracking ID %s", trackingID))
+				return
+			}
+
+			if report.ChannelID == "" {
+				report.ChannelID = video.ChannelID
+				report.ChannelTitle = video.ChannelTitle
+			} else if video.ChannelID != report.ChannelID {
+				shared.JSONErrorResponse(w, reportID, http.StatusBadRequest, fmt.Sprintf("Tracking ID %s belongs to channel %s, expected %s", trackingID, video.ChannelID, report.ChannelID))
+				return
+			}
+
+			point := shared.SentimentPoint(trackingID, video.ID, video.Title, record)
+			points[trackingID] = point
+			report.SentimentTrend = append(report.SentimentTrend, point)
+			digests = append(digests, rollupDigest{
+				TrackingID:            trackingID,
+				Title:                 video.Title,
+				RunDate:               record.RunDate,
+				ExecutiveSummary:      record.ExecutiveSummary,
+				EngagementRatios:      record.PerformanceMetrics.EngagementRatios,
+				AudienceAnalysis:      record.AudienceAnalysis,
+				KeyThemes:             record.KeyThemes,
+				PositiveFeedback:      record.ContentFeedback.PositiveFeedback,
+				ConstructiveCriticism: record.ContentFeedback.ConstructiveCriticism,
+			})
+		}
+		report.VideoCount = int64(len(trackingIDs))
+		sort.SliceStable(report.SentimentTrend, func(i, j int) bool {
+			return report.SentimentTrend[i].RunDate < report.SentimentTrend[j].RunDate
+		})
+
+		digestBytes, err := json.Marshal(digests)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal video digests", "error", err, "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to prepare data for channel report")
+			return
+		}
+
+		client, model, err := newGeminiModel(ctx, cfg)
+		if err != nil {
+			shared.Logger.Error("Failed to create Gemini client", "error", err, "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to create Gemini client")
+			return
+		}
+		defer client.Close()
+
+		shared.Logger.Info("Generating channel report from Gemini.", "channelId", report.ChannelID, "videoCount", report.VideoCount, "reportId", reportID)
+		prompt := fmt.Sprintf(rollupPrompt, report.ChannelTitle, string(digestBytes))
+		var result rollupResult
+		if err := generateJSON(ctx, model, prompt, reportID, &result); err != nil {
+			shared.Logger.Error("Failed to generate channel report", "error", err, "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to generate channel report from Gemini")
+			return
+		}
+
+		report.ExecutiveSummary = result.ExecutiveSummary
+		report.RecurringThemes = result.RecurringThemes
+		report.PersistentCriticism = result.PersistentCriticism
+		report.BestPerformers = resolvePerformers(result.BestPerformers, points)
+		report.WorstPerformers = resolvePerformers(result.WorstPerformers, points)
+
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			shared.Logger.Error("Failed to marshal channel report", "error", err, "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to marshal channel report")
+			return
+		}
+
+		objectName := fmt.Sprintf("%s_channel_report.json", reportID)
+		if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, objectName, reportJSON); err != nil {
+			shared.Logger.Error("Failed to upload channel report to GCS", "error", err, "reportId", reportID)
+			shared.JSONErrorResponse(w, reportID, http.StatusInternalServerError, "Failed to upload channel report to GCS")
+			return
+		}
+		shared.Logger.Info("Successfully uploaded channel report to GCS", "bucket", cfg.GCSBucketName, "object", objectName, "reportId", reportID)
+
+		response := models.APIResponse{
+			TrackingID:     reportID,
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "success",
+			Message:        fmt.Sprintf("Successfully rolled up %d videos into %s", report.VideoCount, objectName),
+			NextActionURI:  fmt.Sprintf("/ingest/rollup?reportId=%s", reportID),
+		}
+		shared.JSONResponse(w, reportID, http.StatusOK, response)
+	}
+}
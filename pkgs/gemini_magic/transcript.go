@@ -0,0 +1,124 @@
+package gemini_magic
+
+import (
+	"app/pkgs/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// maxTranscriptRunes keeps very long transcripts within the context window.
+	maxTranscriptRunes = 400000
+	// transcriptParagraphSeconds merges caption cues into paragraphs of about this length.
+	transcriptParagraphSeconds = 30
+	// maxReviewComments bounds the question comments sent with the transcript.
+	maxReviewComments = 100
+)
+
+const transcriptPrompt = `
+	You are an expert YouTube content analyst and fact checker. You have been given the transcript of a video, the questions and criticism our analysis found in its comments, and the most liked comments that ask questions. Your task is to check them against what is actually said in the video.
+
+	**Transcript:**
+	Each line starts with the [m:ss] timestamp at which it is spoken.
+	%s
+
+	**Unanswered Questions from the Analysis:**
+	%s
+
+	**Constructive Criticism from the Analysis:**
+	%s
+
+	**Question Comments:**
+	%s
+
+	**Analysis Tasks & Output Structure:**
+	Your entire output MUST be a single, minified JSON object. Your response must be raw JSON, starting with '{' and ending with '}'. All string values must be properly escaped.
+
+	1.  **'unanswered_questions'**: The questions from the analysis and the question comments that the transcript genuinely does NOT answer. Each object must have 'question' and 'representative_comment'.
+	2.  **'answered_questions'**: The questions that the transcript DOES answer. Each object must have 'question', 'representative_comment', 'transcript_timestamp' (the [m:ss] where it is answered, without brackets) and 'evidence' (a short paraphrase of the answer).
+	3.  **'factual_disputes'**: Claims in the criticism or comments about what the video says or leaves out, e.g. "he never explained X" or "she said Y, which is wrong". Each object must have 'claim', 'representative_comment', 'verdict' ('supported' if the transcript confirms the viewer's claim, 'contradicted' if it refutes it, 'not_addressed' if it cannot be decided from the transcript), 'transcript_timestamp' (empty when not addressed) and 'evidence'.
+
+	If you do not have enough information to populate a field, return it with an empty value; do NOT omit the field.
+	`
+
+// transcriptResult is the Gemini output of the transcript review.
+type transcriptResult struct {
+	UnansweredQuestions []models.QuestionPoint    `json:"unanswered_questions"`
+	AnsweredQuestions   []models.AnsweredQuestion `json:"answered_questions"`
+	FactualDisputes     []models.FactualDispute   `json:"factual_disputes"`
+}
+
+// formatTranscript renders a transcript as timestamped paragraphs.
+func formatTranscript(transcript *models.Transcript) string {
+	var b strings.Builder
+	var paragraph []string
+	paragraphStart := -1.0
+	flush := func() {
+		if len(paragraph) > 0 {
+			fmt.Fprintf(&b, "[%s] %s\n", formatTimestamp(int(paragraphStart)), strings.Join(paragraph, " "))
+		}
+		paragraph = nil
+	}
+	for _, s := range transcript.Segments {
+		if paragraphStart < 0 || s.Start-paragraphStart >= transcriptParagraphSeconds {
+			flush()
+			paragraphStart = s.Start
+		}
+		paragraph = append(paragraph, s.Text)
+	}
+	flush()
+	return truncateRunes(b.String(), maxTranscriptRunes)
+}
+
+// questionComments returns the most liked comments that ask a question.
+func questionComments(comments []*models.Comment) []string {
+	var questions []*models.Comment
+	for _, c := range comments {
+		if strings.Contains(c.Text, "?") {
+			questions = append(questions, c)
+		}
+	}
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].LikeCount > questions[j].LikeCount })
+	texts := make([]string, 0, min(len(questions), maxReviewComments))
+	for _, c := range questions[:min(len(questions), maxReviewComments)] {
+		texts = append(texts, truncateRunes(c.Text, 300))
+	}
+	return texts
+}
+
+// reviewTranscript cross-references the questions and criticism of an
+// analysis with the transcript. Questions the transcript answers are moved
+// from the record's unanswered questions into its transcript review, together
+// with the verdict on factual disputes.
+func reviewTranscript(ctx context.Context, model *genai.GenerativeModel, record *models.AnalysisRecord, fullData *models.VideoData, transcript *models.Transcript, trackingID string) error {
+	questionsJSON, err := json.Marshal(record.ContentFeedback.UnansweredQuestions)
+	if err != nil {
+		return err
+	}
+	criticismJSON, err := json.Marshal(record.ContentFeedback.ConstructiveCriticism)
+	if err != nil {
+		return err
+	}
+	commentsJSON, err := json.Marshal(questionComments(fullData.Comments))
+	if err != nil {
+		return err
+	}
+
+	prompt := fmt.Sprintf(transcriptPrompt, formatTranscript(transcript), string(questionsJSON), string(criticismJSON), string(commentsJSON))
+	var result transcriptResult
+	if err := generateJSON(ctx, model, prompt, trackingID, &result); err != nil {
+		return fmt.Errorf("transcript review failed: %w", err)
+	}
+
+	record.ContentFeedback.UnansweredQuestions = result.UnansweredQuestions
+	record.TranscriptReview = &models.TranscriptReview{
+		AnsweredQuestions: result.AnsweredQuestions,
+		FactualDisputes:   result.FactualDisputes,
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package live_chat
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/pipeline"
+	"app/pkgs/shared"
+	"app/pkgs/yt_video"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMinutes = 60
+	// maxMinutes bounds a single capture; longer streams can be captured in parts.
+	maxMinutes = 360
+	// staleGrace is how long a capture may keep collecting past its minutes
+	// while the stored chat is analyzed and ingested.
+	staleGrace = 30 * time.Minute
+)
+
+const (
+	StatusCollecting = "collecting"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+func statusObjectName(trackingID string) string {
+	return fmt.Sprintf("livechat/%s.json", trackingID)
+}
+
+func saveStatus(ctx context.Context, cfg *models.AppConfig, capture *models.LiveChatCapture) {
+	data, err := json.Marshal(capture)
+	if err == nil {
+		err = shared.UploadToGCS(ctx, cfg.GCSBucketName, statusObjectName(capture.TrackingID), data)
+	}
+	if err != nil {
+		shared.Logger.Error("Failed to store live chat status", "error", err, "trackingId", capture.TrackingID)
+	}
+}
+
+// markStale marks a capture as failed when it is still collecting long after
+// it should have finished. The capture runs in the instance that started it,
+// so this happens when the instance was shut down or its CPU was throttled.
+// It reports whether the capture was marked.
+func markStale(capture *models.LiveChatCapture, now time.Time) bool {
+	minutes := capture.Minutes
+	if minutes == 0 {
+		minutes = maxMinutes
+	}
+	if capture.Status != StatusCollecting || now.Before(capture.StartedAt.Add(time.Duration(minutes)*time.Minute+staleGrace)) {
+		return false
+	}
+	capture.Status = StatusFailed
+	capture.Message = "capture did not finish; the instance running it was likely shut down"
+	return true
+}
+
+// Capture collects the live chat of a video and stores it as <trackingId>.json.
+// When analyze is set, the stored chat is passed through the analyze and
+// ingest steps of the pipeline. The status object is updated when the capture
+// finishes.
+func Capture(ctx context.Context, cfg *models.AppConfig, capture *models.LiveChatCapture, maxDuration time.Duration) error {
+	err := collect(ctx, cfg, capture, maxDuration)
+	capture.FinishedAt = time.Now()
+	if err != nil {
+		capture.Status = StatusFailed
+		capture.Message = err.Error()
+	} else {
+		capture.Status = StatusCompleted
+	}
+	saveStatus(context.Background(), cfg, capture)
+	return err
+}
+
+func collect(ctx context.Context, cfg *models.AppConfig, capture *models.LiveChatCapture, maxDuration time.Duration) error {
+	trackingID := capture.TrackingID
+	data, err := yt_video.CollectLiveChat(ctx, cfg, capture.VideoID, trackingID, maxDuration)
+	if err != nil {
+		return err
+	}
+	capture.Messages = len(data.Comments)
+	if len(data.Comments) == 0 {
+		return errors.New("no live chat messages were collected")
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("could not marshal live chat data: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, trackingID+".json", jsonData); err != nil {
+		return fmt.Errorf("could not save live chat data to GCS: %w", err)
+	}
+	if !capture.Analyze {
+		return nil
+	}
+
+	_, err = pipeline.Run(ctx, cfg, pipeline.Request{
+		VideoID:    capture.VideoID,
+		Trigger:    models.SourceLiveChat,
+		TrackingID: trackingID,
+		SkipFetch:  true,
+	}, nil)
+	return err
+}
+
+// LiveChat starts a live chat capture in the background with
+// /livechat?videoId=<id>&minutes=<n>&analyze=true and reports the status of a
+// capture with /livechat?trackingId=<id>.
+func LiveChat(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		query := r.URL.Query()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		if trackingID := query.Get("trackingId"); trackingID != "" {
+			data, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, statusObjectName(trackingID))
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Live chat capture not found")
+				return
+			}
+			if err != nil {
+				shared.Logger.Error("Failed to read live chat status", "error", err, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to read live chat status")
+				return
+			}
+			var capture models.LiveChatCapture
+			if err := json.Unmarshal(data, &capture); err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to decode live chat status")
+				return
+			}
+			if markStale(&capture, time.Now()) {
+				shared.Logger.Warn("Marking unfinished live chat capture as failed", "startedAt", capture.StartedAt, "minutes", capture.Minutes, "trackingId", trackingID)
+				saveStatus(ctx, cfg, &capture)
+			}
+			shared.JSONResponse(w, trackingID, http.StatusOK, capture)
+			return
+		}
+
+		ref := query.Get("videoId")
+		if ref == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'videoId' or 'trackingId' query parameter")
+			return
+		}
+		videoID, err := yt_video.ParseVideoRef(ref)
+		if err != nil {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, fmt.Sprintf("Invalid 'videoId': %v", err))
+			return
+		}
+		minutes := defaultMinutes
+		if value := query.Get("minutes"); value != "" {
+			minutes, err = strconv.Atoi(value)
+			if err != nil || minutes < 1 || minutes > maxMinutes {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, fmt.Sprintf("'minutes' must be between 1 and %d", maxMinutes))
+				return
+			}
+		}
+
+		trackingID := uuid.New().String()
+		if _, err := yt_video.LiveChatID(ctx, cfg, videoID); err != nil {
+			switch {
+			case errors.Is(err, yt_video.ErrVideoNotFound):
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Video not found")
+			case errors.Is(err, yt_video.ErrNoLiveChat):
+				shared.JSONErrorResponse(w, trackingID, http.StatusConflict, "Video has no active live chat")
+			default:
+				shared.Logger.Error("Failed to look up live chat", "error", err, "videoId", videoID, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to look up live chat")
+			}
+			return
+		}
+
+		capture := &models.LiveChatCapture{
+			TrackingID: trackingID,
+			VideoID:    videoID,
+			Status:     StatusCollecting,
+			Analyze:    query.Get("analyze") == "true",
+			StartedAt:  time.Now(),
+			Minutes:    minutes,
+		}
+		saveStatus(ctx, cfg, capture)
+		go func() {
+			if err := Capture(context.Background(), cfg, capture, time.Duration(minutes)*time.Minute); err != nil {
+				shared.Logger.Error("Live chat capture failed", "error", err, "videoId", videoID, "trackingId", trackingID)
+			}
+		}()
+
+		shared.Logger.Info("Started live chat capture", "videoId", videoID, "minutes", minutes, "analyze", capture.Analyze, "trackingId", trackingID)
+		shared.JSONResponse(w, trackingID, http.StatusAccepted, models.APIResponse{
+			TrackingID:     trackingID,
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "accepted",
+			Message:        fmt.Sprintf("Collecting live chat of video %s for up to %d minutes", videoID, minutes),
+			NextActionURI:  fmt.Sprintf("/livechat?trackingId=%s", trackingID),
+		})
+	}
+}
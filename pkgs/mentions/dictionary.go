@@ -0,0 +1,87 @@
+package mentions
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+const dictionaryObjectName = "mention_dictionary.json"
+
+// maxEntities keeps the dictionary listed in the map prompt short enough.
+const maxEntities = 200
+
+// LoadDictionary reads the mention dictionary from GCS. Until one is stored,
+// it returns an empty dictionary and mentions keep the names the model uses.
+func LoadDictionary(ctx context.Context, cfg *models.AppConfig) (*models.MentionDictionary, error) {
+	data, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, dictionaryObjectName)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return &models.MentionDictionary{Entities: []models.DictionaryEntity{}}, nil
+		}
+		return nil, fmt.Errorf("could not get mention dictionary from GCS: %w", err)
+	}
+	var dictionary models.MentionDictionary
+	if err := json.Unmarshal(data, &dictionary); err != nil {
+		return nil, fmt.Errorf("could not unmarshal mention dictionary JSON: %w", err)
+	}
+	return &dictionary, nil
+}
+
+// Validate trims the names and aliases of a dictionary and checks that every
+// entity has a known type and that no name or alias belongs to two entities.
+func Validate(dictionary *models.MentionDictionary) error {
+	if len(dictionary.Entities) > maxEntities {
+		return fmt.Errorf("at most %d entities are allowed", maxEntities)
+	}
+	owners := make(map[string]string)
+	for i := range dictionary.Entities {
+		e := &dictionary.Entities[i]
+		e.Name = strings.TrimSpace(e.Name)
+		e.Type = strings.ToLower(strings.TrimSpace(e.Type))
+		if e.Name == "" {
+			return errors.New("every entity needs a 'name'")
+		}
+		if e.Type != models.EntityBrand && e.Type != models.EntityProduct && e.Type != models.EntityCompetitor {
+			return fmt.Errorf("entity %q: 'type' must be 'brand', 'product' or 'competitor'", e.Name)
+		}
+		aliases := e.Aliases[:0]
+		for _, alias := range append([]string{e.Name}, e.Aliases...) {
+			alias = strings.TrimSpace(alias)
+			key := strings.ToLower(alias)
+			if alias == "" {
+				continue
+			}
+			if owner, ok := owners[key]; ok {
+				if owner == e.Name {
+					continue
+				}
+				return fmt.Errorf("%q is used by both %q and %q", alias, owner, e.Name)
+			}
+			owners[key] = e.Name
+			if alias != e.Name {
+				aliases = append(aliases, alias)
+			}
+		}
+		e.Aliases = aliases
+	}
+	return nil
+}
+
+// SaveDictionary stores a validated dictionary.
+func SaveDictionary(ctx context.Context, cfg *models.AppConfig, dictionary *models.MentionDictionary) error {
+	data, err := json.Marshal(dictionary)
+	if err != nil {
+		return fmt.Errorf("could not marshal mention dictionary JSON: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, dictionaryObjectName, data); err != nil {
+		return fmt.Errorf("could not upload mention dictionary to GCS: %w", err)
+	}
+	return nil
+}
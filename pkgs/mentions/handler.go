@@ -0,0 +1,53 @@
+package mentions
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Dictionary returns (GET) or replaces (PUT) the mention dictionary.
+func Dictionary(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		switch r.Method {
+		case http.MethodGet:
+			dictionary, err := LoadDictionary(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load mention dictionary", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load mention dictionary")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, dictionary)
+
+		case http.MethodPut:
+			var dictionary models.MentionDictionary
+			if err := json.NewDecoder(r.Body).Decode(&dictionary); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if err := Validate(&dictionary); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid dictionary: "+err.Error())
+				return
+			}
+			if dictionary.Entities == nil {
+				dictionary.Entities = []models.DictionaryEntity{}
+			}
+			dictionary.UpdatedAt = time.Now()
+			if err := SaveDictionary(ctx, cfg, &dictionary); err != nil {
+				shared.Logger.Error("could not save mention dictionary", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to save mention dictionary")
+				return
+			}
+			shared.Logger.Info("Saved mention dictionary", "entities", len(dictionary.Entities))
+			shared.JSONResponse(w, "", http.StatusOK, dictionary)
+
+		default:
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type APIResponse struct {
 	TrackingID     string `json:"tracking_id"`
 	ProcessingTime string `json:"processing_time"`
@@ -16,8 +21,20 @@ type AppConfig struct {
 	GCPLocation        string
 	BQDataset          string
 	GEMINIModel        string
+	EmbeddingModel     string
 	Port               string
 	MaxCommentsToFetch int
+	SchedulerInterval  int
+	StatsPollInterval  int
+	AlertWebhookURL    string
+	AlertSlackURL      string
+	AlertSMTPHost      string
+	AlertSMTPPort      int
+	AlertSMTPUsername  string
+	AlertSMTPPassword  string
+	AlertEmailFrom     string
+	AlertEmailTo       string
+	WebhookSecret      string
 }
 
 type VideoData struct {
@@ -38,16 +55,28 @@ type VideoData struct {
 	Comments      []*Comment `json:"comments"`
 }
 
+// Comment sources.
+const (
+	SourceComments = "comments"
+	SourceLiveChat = "live_chat"
+	SourceImport   = "import"
+)
+
 type Comment struct {
-	VideoID    string `json:"-" bigquery:"video_id"`
-	ID         string `json:"id" bigquery:"id"`
-	ParentID   string `json:"parent_id,omitempty" bigquery:"parent_id"`
-	ChannelID  string `json:"channel_id" bigquery:"channel_id"`
-	Text       string `json:"text" bigquery:"text"`
-	LikeCount  int64  `json:"like_count" bigquery:"like_count"`
-	ReplyCount int64  `json:"reply_count" bigquery:"reply_count"`
-	TrackingID string `json:"tracking_id" bigquery:"tracking_id"`
-	RunDate    string `json:"run_date" bigquery:"run_date"`
+	VideoID     string    `json:"-" bigquery:"video_id"`
+	ID          string    `json:"id" bigquery:"id"`
+	ParentID    string    `json:"parent_id,omitempty" bigquery:"parent_id"`
+	ChannelID   string    `json:"channel_id" bigquery:"channel_id"`
+	Text        string    `json:"text" bigquery:"text"`
+	LikeCount   int64     `json:"like_count" bigquery:"like_count"`
+	ReplyCount  int64     `json:"reply_count" bigquery:"reply_count"`
+	TrackingID  string    `json:"tracking_id" bigquery:"tracking_id"`
+	RunDate     string    `json:"run_date" bigquery:"run_date"`
+	Source      string    `json:"source,omitempty" bigquery:"source"`
+	PublishedAt time.Time `json:"published_at,omitzero" bigquery:"published_at"`
+	AuthorID    string    `json:"author_channel_id,omitempty" bigquery:"author_channel_id"`
+	Hearted     bool      `json:"hearted,omitempty" bigquery:"-"`
+	Pinned      bool      `json:"pinned,omitempty" bigquery:"-"`
 }
 
 type VideoRecord struct {
@@ -75,9 +104,456 @@ type AnalysisRecord struct {
 	AudienceAnalysis          AudienceAnalysis          `json:"audience_analysis" bigquery:"audience_analysis"`
 	ContentFeedback           ContentFeedback           `json:"content_feedback" bigquery:"content_feedback"`
 	KeyThemes                 []KeyTheme                `json:"key_themes" bigquery:"key_themes"`
+	ThemeClusters             []ThemeCluster            `json:"theme_clusters,omitempty" bigquery:"theme_clusters"`
 	EngagementHighlights      []EngagementHighlight     `json:"engagement_highlights" bigquery:"engagement_highlights"`
 	SWOTAnalysis              SWOTAnalysis              `json:"swot_analysis" bigquery:"swot_analysis"`
 	ActionableRecommendations ActionableRecommendations `json:"actionable_recommendations" bigquery:"actionable_recommendations"`
+	SentimentTimeline         []TimelineBucket          `json:"sentiment_timeline,omitempty" bigquery:"-"`
+	// MomentHeatmap is ingested into the moments table rather than analyzed.
+	MomentHeatmap     []MomentBucket     `json:"moment_heatmap,omitempty" bigquery:"-"`
+	ChapterSentiment  []ChapterSentiment `json:"chapter_sentiment,omitempty" bigquery:"-"`
+	TranscriptReview  *TranscriptReview  `json:"transcript_review,omitempty" bigquery:"-"`
+	PackagingCritique *PackagingCritique `json:"packaging_critique,omitempty" bigquery:"-"`
+	Moderation        *ModerationReport  `json:"moderation,omitempty" bigquery:"-"`
+	BotReport         *BotReport         `json:"bot_report,omitempty" bigquery:"-"`
+	Languages         []LanguageStats    `json:"languages,omitempty" bigquery:"-"`
+	OutputLanguage    string             `json:"output_language,omitempty" bigquery:"-"`
+	// AspectScorecard is ingested into the aspects table rather than analyzed.
+	AspectTaxonomy  string        `json:"aspect_taxonomy,omitempty" bigquery:"-"`
+	AspectScorecard []AspectScore `json:"aspect_scorecard,omitempty" bigquery:"-"`
+	// Mentions is ingested into the mentions table rather than analyzed.
+	Mentions  []EntityStats   `json:"mentions,omitempty" bigquery:"-"`
+	Questions *QuestionReport `json:"questions,omitempty" bigquery:"-"`
+	// Annotations holds the per-comment output of the map step. It is stored
+	// separately as <trackingId>_annotations.json.
+	Annotations []CommentAnnotation `json:"-" bigquery:"-"`
+}
+
+// CommentAnnotation is the analysis of a single comment produced in the map step.
+type CommentAnnotation struct {
+	ID        string `json:"id"`
+	Sentiment string `json:"sentiment"`
+	Language  string `json:"language,omitempty"`
+	// Aspects lists the taxonomy aspects the comment mentions, if a taxonomy
+	// was used.
+	Aspects []AspectMention `json:"aspects,omitempty"`
+	// Mentions lists the brands, products and competitors the comment names.
+	Mentions []EntityMention `json:"mentions,omitempty"`
+	// Question is set when the comment asks the creator a genuine question.
+	Question bool `json:"question,omitempty"`
+}
+
+// Question cluster statuses and the ways a question counts as answered.
+const (
+	QuestionOpen     = "open"
+	QuestionAnswered = "answered"
+
+	AnsweredByOwner        = "owner_reply"
+	AnsweredByPinnedReply  = "pinned_reply"
+	AnsweredByHeartedReply = "hearted_reply"
+)
+
+// QuestionReport tracks the questions viewers ask in top-level comments and
+// whether they were answered.
+type QuestionReport struct {
+	Questions int64             `json:"questions"`
+	Answered  int64             `json:"answered"`
+	Open      int64             `json:"open"`
+	Clusters  []QuestionCluster `json:"clusters"`
+}
+
+// QuestionCluster groups the comments asking the same question. It is
+// answered when any of them was answered.
+type QuestionCluster struct {
+	// ID is the ID of the most liked comment of the cluster, so it stays the
+	// same when the analysis is read again.
+	ID        string `json:"id"`
+	Question  string `json:"question"`
+	Status    string `json:"status"`
+	Comments  int64  `json:"comments"`
+	LikeTotal int64  `json:"like_total"`
+	// ExampleCommentIDs lists the most liked comments of the cluster.
+	ExampleCommentIDs []string         `json:"example_comment_ids"`
+	Answers           []QuestionAnswer `json:"answers,omitempty"`
+}
+
+// QuestionList is the response of GET /runs/{id}/questions: the question
+// report of a run with the clusters of the requested status.
+type QuestionList struct {
+	TrackingID string `json:"tracking_id"`
+	Status     string `json:"status"`
+	QuestionReport
+}
+
+// QuestionAnswer is a reply that answers a question of a cluster.
+type QuestionAnswer struct {
+	QuestionID string `json:"question_id"`
+	ReplyID    string `json:"reply_id"`
+	AnsweredBy string `json:"answered_by"`
+}
+
+// Entity types of brand and product mentions.
+const (
+	EntityBrand      = "brand"
+	EntityProduct    = "product"
+	EntityCompetitor = "competitor"
+)
+
+// EntityMention is a brand, product or competitor named in a comment, with
+// the comment's sentiment towards it.
+type EntityMention struct {
+	Entity    string `json:"entity"`
+	Type      string `json:"type"`
+	Sentiment string `json:"sentiment"`
+}
+
+// MentionDictionary maps the aliases of known entities to their canonical
+// names. It is stored in GCS as mention_dictionary.json.
+type MentionDictionary struct {
+	Entities  []DictionaryEntity `json:"entities"`
+	UpdatedAt time.Time          `json:"updated_at,omitzero"`
+}
+
+// DictionaryEntity is a known brand, product or competitor. Mentions of any
+// alias are counted under Name, and Type overrides the type the model assigns.
+type DictionaryEntity struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// EntityStats aggregates the mentions of one entity in a run.
+type EntityStats struct {
+	Entity   string  `json:"entity"`
+	Type     string  `json:"type"`
+	Mentions int64   `json:"mentions"`
+	Share    float64 `json:"share"`
+	Positive int64   `json:"positive"`
+	Negative int64   `json:"negative"`
+	Neutral  int64   `json:"neutral"`
+	// LikeTotal sums the likes of the mentioning comments.
+	LikeTotal int64 `json:"like_total"`
+	// InDescription is set when the video description names the entity, e.g.
+	// as a sponsor.
+	InDescription bool     `json:"in_description"`
+	TopQuotes     []string `json:"top_quotes"`
+}
+
+// MentionRecord is a row of the mentions table.
+type MentionRecord struct {
+	TrackingID    string   `bigquery:"tracking_id"`
+	VideoID       string   `bigquery:"video_id"`
+	ChannelID     string   `bigquery:"channel_id"`
+	RunDate       string   `bigquery:"run_date"`
+	Entity        string   `bigquery:"entity"`
+	Type          string   `bigquery:"type"`
+	Mentions      int64    `bigquery:"mentions"`
+	Share         float64  `bigquery:"share"`
+	Positive      int64    `bigquery:"positive"`
+	Negative      int64    `bigquery:"negative"`
+	Neutral       int64    `bigquery:"neutral"`
+	LikeTotal     int64    `bigquery:"like_total"`
+	InDescription bool     `bigquery:"in_description"`
+	TopQuotes     []string `bigquery:"top_quotes"`
+}
+
+// AspectMention is the sentiment of a comment towards one aspect.
+type AspectMention struct {
+	Aspect    string `json:"aspect"`
+	Sentiment string `json:"sentiment"`
+}
+
+// Moderation categories and severities.
+const (
+	ModerationHarassment    = "harassment"
+	ModerationHate          = "hate"
+	ModerationSpam          = "spam"
+	ModerationSelfPromotion = "self_promotion"
+
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// ModerationFlag is a comment flagged for review in the map step.
+type ModerationFlag struct {
+	CommentID string `json:"comment_id"`
+	Category  string `json:"category"`
+	Severity  string `json:"severity"`
+	Reason    string `json:"reason"`
+	Text      string `json:"text"`
+	LikeCount int64  `json:"like_count"`
+}
+
+// ModerationCategory counts the flags of one category by severity.
+type ModerationCategory struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+	High     int64  `json:"high"`
+	Medium   int64  `json:"medium"`
+	Low      int64  `json:"low"`
+}
+
+// ModerationReport aggregates the moderation flags of an analysis. Flags are
+// ordered by severity, then by likes.
+type ModerationReport struct {
+	FlaggedComments int64                `json:"flagged_comments"`
+	FlaggedShare    float64              `json:"flagged_share"`
+	Categories      []ModerationCategory `json:"categories"`
+	Flags           []ModerationFlag     `json:"flags"`
+}
+
+// LanguageStats is the number and sentiment of the comments written in one language.
+type LanguageStats struct {
+	Language string  `json:"language"`
+	Comments int64   `json:"comments"`
+	Share    float64 `json:"share"`
+	Positive int64   `json:"positive"`
+	Negative int64   `json:"negative"`
+	Neutral  int64   `json:"neutral"`
+}
+
+// AspectTaxonomy is a team-defined list of product or content aspects, stored
+// per channel ID or profile name as aspects/<name>.json.
+type AspectTaxonomy struct {
+	Name      string      `json:"name"`
+	Aspects   []AspectDef `json:"aspects"`
+	UpdatedAt time.Time   `json:"updated_at,omitzero"`
+}
+
+// AspectDef is an aspect of a taxonomy. The description tells the model what
+// counts as a mention, e.g. "price, cost, value for money".
+type AspectDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// AspectScore aggregates the comments that mention one aspect.
+type AspectScore struct {
+	Aspect   string  `json:"aspect"`
+	Mentions int64   `json:"mentions"`
+	Share    float64 `json:"share"`
+	Positive int64   `json:"positive"`
+	Negative int64   `json:"negative"`
+	Neutral  int64   `json:"neutral"`
+	// NetSentiment is (positive - negative) / mentions, from -1 to 1.
+	NetSentiment float64  `json:"net_sentiment"`
+	TopQuotes    []string `json:"top_quotes"`
+}
+
+// AspectRecord is a row of the aspects table.
+type AspectRecord struct {
+	TrackingID   string   `bigquery:"tracking_id"`
+	VideoID      string   `bigquery:"video_id"`
+	ChannelID    string   `bigquery:"channel_id"`
+	RunDate      string   `bigquery:"run_date"`
+	Taxonomy     string   `bigquery:"taxonomy"`
+	Aspect       string   `bigquery:"aspect"`
+	Mentions     int64    `bigquery:"mentions"`
+	Share        float64  `bigquery:"share"`
+	Positive     int64    `bigquery:"positive"`
+	Negative     int64    `bigquery:"negative"`
+	Neutral      int64    `bigquery:"neutral"`
+	NetSentiment float64  `bigquery:"net_sentiment"`
+	TopQuotes    []string `bigquery:"top_quotes"`
+}
+
+// Bot detection reasons.
+const (
+	BotReasonDuplicate = "duplicate"
+	BotReasonBurst     = "burst"
+	BotReasonLinkSpam  = "link_spam"
+)
+
+// BotReport summarizes the comments suspected to come from bots. Excluded
+// reports whether they were left out of the sentiment aggregates and themes.
+type BotReport struct {
+	SuspectedComments int64            `json:"suspected_comments"`
+	SuspectedShare    float64          `json:"suspected_share"`
+	Excluded          bool             `json:"excluded"`
+	DuplicateComments int64            `json:"duplicate_comments"`
+	BurstComments     int64            `json:"burst_comments"`
+	LinkSpamComments  int64            `json:"link_spam_comments"`
+	DuplicateGroups   []DuplicateGroup `json:"duplicate_groups"`
+	AuthorBursts      []AuthorBurst    `json:"author_bursts"`
+	Suspects          []SuspectedBot   `json:"suspects"`
+}
+
+// DuplicateGroup is a set of near-identical comments.
+type DuplicateGroup struct {
+	Size       int64    `json:"size"`
+	Text       string   `json:"text"`
+	CommentIDs []string `json:"comment_ids"`
+}
+
+// AuthorBurst is a series of top-level comments posted by one author within minutes.
+type AuthorBurst struct {
+	AuthorID   string    `json:"author_channel_id"`
+	Comments   int64     `json:"comments"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	CommentIDs []string  `json:"comment_ids"`
+}
+
+// SuspectedBot is a comment suspected to come from a bot and the reasons for it.
+type SuspectedBot struct {
+	CommentID string   `json:"comment_id"`
+	Reasons   []string `json:"reasons"`
+	Text      string   `json:"text"`
+}
+
+// TimelineBucket aggregates the sentiment of the comments published in one minute.
+type TimelineBucket struct {
+	Minute   int       `json:"minute"`
+	Start    time.Time `json:"start"`
+	Messages int64     `json:"messages"`
+	Positive int64     `json:"positive"`
+	Negative int64     `json:"negative"`
+	Neutral  int64     `json:"neutral"`
+}
+
+// TranscriptReview is the result of cross-referencing comments with the
+// transcript. Questions the transcript answers are moved here from
+// ContentFeedback.UnansweredQuestions.
+type TranscriptReview struct {
+	AnsweredQuestions []AnsweredQuestion `json:"answered_questions"`
+	FactualDisputes   []FactualDispute   `json:"factual_disputes"`
+}
+
+type AnsweredQuestion struct {
+	Question              string `json:"question"`
+	RepresentativeComment string `json:"representative_comment"`
+	TranscriptTimestamp   string `json:"transcript_timestamp"`
+	Evidence              string `json:"evidence"`
+}
+
+// FactualDispute is a claim made in the comments about the video's content.
+// Verdict is "supported", "contradicted" or "not_addressed" by the transcript.
+type FactualDispute struct {
+	Claim                 string `json:"claim"`
+	RepresentativeComment string `json:"representative_comment"`
+	Verdict               string `json:"verdict"`
+	TranscriptTimestamp   string `json:"transcript_timestamp"`
+	Evidence              string `json:"evidence"`
+}
+
+// PackagingCritique reviews the title and thumbnail of a video against what
+// its commenters say about them.
+type PackagingCritique struct {
+	Summary string `json:"summary"`
+	// ThumbnailReviewed is false when the thumbnail could not be downloaded
+	// and only the title and description were reviewed.
+	ThumbnailReviewed bool            `json:"thumbnail_reviewed"`
+	TitleClarity      PackagingScore  `json:"title_clarity"`
+	ThumbnailClarity  PackagingScore  `json:"thumbnail_clarity"`
+	ClickbaitRisk     PackagingScore  `json:"clickbait_risk"`
+	AudienceMismatch  []MismatchPoint `json:"audience_mismatch"`
+	Suggestions       []PackagingIdea `json:"suggestions"`
+}
+
+// PackagingScore is a 1-10 score; for clickbait risk, higher is riskier.
+type PackagingScore struct {
+	Score      int    `json:"score"`
+	Assessment string `json:"assessment"`
+}
+
+// MismatchPoint is a difference between what the packaging promises and what
+// commenters say the video delivers.
+type MismatchPoint struct {
+	Promise               string `json:"promise"`
+	Reality               string `json:"reality"`
+	RepresentativeComment string `json:"representative_comment"`
+}
+
+type PackagingIdea struct {
+	Suggestion string `json:"suggestion"`
+	Reason     string `json:"reason"`
+}
+
+// AnalyzeOptions enables optional inputs of the analysis.
+type AnalyzeOptions struct {
+	// Transcript enables cross-referencing comments with what is said in the video.
+	Transcript *Transcript
+	// Packaging enables the multimodal title and thumbnail critique.
+	Packaging bool
+	// IncludeBots keeps suspected bot comments in the analysis.
+	IncludeBots bool
+	// OutputLanguage is the language of the report text, e.g. "sv" or
+	// "German". Empty means English.
+	OutputLanguage string
+	// Aspects enables the aspect scorecard for the aspects of the taxonomy.
+	Aspects *AspectTaxonomy
+	// Dictionary normalizes the aliases of brand and product mentions.
+	Dictionary *MentionDictionary
+	// Clusters enables embedding-based theme clustering. It needs
+	// AppConfig.EmbeddingModel and costs an embedding per comment.
+	Clusters bool
+}
+
+// Transcript is the spoken content of a video, stored as <trackingId>_transcript.json.
+type Transcript struct {
+	VideoID  string              `json:"video_id"`
+	Source   string              `json:"source"`
+	Language string              `json:"language,omitempty"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// TranscriptSegment is a single caption cue; times are in seconds.
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// MomentBucket aggregates the comments that mention a timestamp within one
+// segment of the video.
+type MomentBucket struct {
+	StartSeconds int      `json:"start_seconds"`
+	EndSeconds   int      `json:"end_seconds"`
+	Label        string   `json:"label"`
+	PeakSecond   int      `json:"peak_second"`
+	Mentions     int64    `json:"mentions"`
+	Positive     int64    `json:"positive"`
+	Negative     int64    `json:"negative"`
+	Neutral      int64    `json:"neutral"`
+	TopQuotes    []string `json:"top_quotes"`
+}
+
+// Chapter is an entry of the chapter list in a video description.
+type Chapter struct {
+	Index        int    `json:"index"`
+	Title        string `json:"title"`
+	StartSeconds int    `json:"start_seconds"`
+	EndSeconds   int    `json:"end_seconds"`
+}
+
+// ChapterSentiment reports how the comments referring to a chapter, by
+// timestamp or by name, received it.
+type ChapterSentiment struct {
+	Chapter
+	Label    string   `json:"label"`
+	Mentions int64    `json:"mentions"`
+	Positive int64    `json:"positive"`
+	Negative int64    `json:"negative"`
+	Neutral  int64    `json:"neutral"`
+	Themes   []string `json:"themes"`
+	Summary  string   `json:"summary"`
+}
+
+// MomentRecord is a row of the moments table.
+type MomentRecord struct {
+	TrackingID   string   `bigquery:"tracking_id"`
+	VideoID      string   `bigquery:"video_id"`
+	RunDate      string   `bigquery:"run_date"`
+	StartSeconds int64    `bigquery:"start_seconds"`
+	EndSeconds   int64    `bigquery:"end_seconds"`
+	Label        string   `bigquery:"label"`
+	PeakSecond   int64    `bigquery:"peak_second"`
+	Mentions     int64    `bigquery:"mentions"`
+	Positive     int64    `bigquery:"positive"`
+	Negative     int64    `bigquery:"negative"`
+	Neutral      int64    `bigquery:"neutral"`
+	TopQuotes    []string `bigquery:"top_quotes"`
 }
 
 type PerformanceMetrics struct {
@@ -123,6 +599,20 @@ type KeyTheme struct {
 	RepresentativeComment string `json:"representative_comment" bigquery:"representative_comment"`
 }
 
+// ThemeCluster is a theme found by clustering comment embeddings. Unlike
+// KeyTheme, its size and sentiment are counted from the member comments.
+type ThemeCluster struct {
+	ThemeTitle            string  `json:"theme_title" bigquery:"theme_title"`
+	Summary               string  `json:"summary" bigquery:"summary"`
+	RepresentativeComment string  `json:"representative_comment" bigquery:"representative_comment"`
+	MemberCount           int64   `json:"member_count" bigquery:"member_count"`
+	Share                 float64 `json:"share" bigquery:"share"`
+	LikeTotal             int64   `json:"like_total" bigquery:"like_total"`
+	Positive              int64   `json:"positive" bigquery:"positive"`
+	Negative              int64   `json:"negative" bigquery:"negative"`
+	Neutral               int64   `json:"neutral" bigquery:"neutral"`
+}
+
 type EngagementHighlight struct {
 	CommentText         string `json:"comment_text" bigquery:"comment_text"`
 	EngagementCount     int64  `json:"engagement_count" bigquery:"engagement_count"`
@@ -162,3 +652,464 @@ type ActionableRecommendations struct {
 	CommunityManagement       string                           `json:"community_management" bigquery:"community_management"`
 	MonetizationOpportunities []MonetizationOpportunity        `json:"monetization_opportunities" bigquery:"monetization_opportunities"`
 }
+
+type ChannelReport struct {
+	ReportID            string                `json:"report_id" bigquery:"report_id"`
+	RunDate             string                `json:"run_date" bigquery:"run_date"`
+	ChannelID           string                `json:"channel_id" bigquery:"channel_id"`
+	ChannelTitle        string                `json:"channel_title" bigquery:"channel_title"`
+	VideoCount          int64                 `json:"video_count" bigquery:"video_count"`
+	TrackingIDs         []string              `json:"tracking_ids" bigquery:"tracking_ids"`
+	ExecutiveSummary    string                `json:"executive_summary" bigquery:"executive_summary"`
+	RecurringThemes     []RecurringTheme      `json:"recurring_themes" bigquery:"recurring_themes"`
+	PersistentCriticism []PersistentCriticism `json:"persistent_criticism" bigquery:"persistent_criticism"`
+	SentimentTrend      []VideoSentimentPoint `json:"sentiment_trend" bigquery:"sentiment_trend"`
+	BestPerformers      []VideoPerformance    `json:"best_performers" bigquery:"best_performers"`
+	WorstPerformers     []VideoPerformance    `json:"worst_performers" bigquery:"worst_performers"`
+}
+
+type RecurringTheme struct {
+	ThemeTitle string `json:"theme_title" bigquery:"theme_title"`
+	Summary    string `json:"summary" bigquery:"summary"`
+	VideoCount int64  `json:"video_count" bigquery:"video_count"`
+}
+
+type PersistentCriticism struct {
+	Point                 string `json:"point" bigquery:"point"`
+	VideoCount            int64  `json:"video_count" bigquery:"video_count"`
+	RepresentativeComment string `json:"representative_comment" bigquery:"representative_comment"`
+}
+
+type VideoSentimentPoint struct {
+	TrackingID         string  `json:"tracking_id" bigquery:"tracking_id"`
+	VideoID            string  `json:"video_id" bigquery:"video_id"`
+	Title              string  `json:"title" bigquery:"title"`
+	RunDate            string  `json:"run_date" bigquery:"run_date"`
+	SentimentLabel     string  `json:"sentiment_label" bigquery:"sentiment_label"`
+	PositiveComments   int64   `json:"positive_comments" bigquery:"positive_comments"`
+	NegativeComments   int64   `json:"negative_comments" bigquery:"negative_comments"`
+	NeutralComments    int64   `json:"neutral_comments" bigquery:"neutral_comments"`
+	PositiveShare      float64 `json:"positive_share" bigquery:"positive_share"`
+	NegativeShare      float64 `json:"negative_share" bigquery:"negative_share"`
+	ViewCount          int64   `json:"view_count" bigquery:"view_count"`
+	LikeToViewRatio    float64 `json:"like_to_view_ratio" bigquery:"like_to_view_ratio"`
+	CommentToViewRatio float64 `json:"comment_to_view_ratio" bigquery:"comment_to_view_ratio"`
+}
+
+type VideoPerformance struct {
+	TrackingID string `json:"tracking_id" bigquery:"tracking_id"`
+	VideoID    string `json:"video_id" bigquery:"video_id"`
+	Title      string `json:"title" bigquery:"title"`
+	Reason     string `json:"reason" bigquery:"reason"`
+}
+
+type ComparisonReport struct {
+	ComparisonID    string           `json:"comparison_id"`
+	RunDate         string           `json:"run_date"`
+	Videos          []ComparedVideo  `json:"videos"`
+	Narrative       string           `json:"narrative"`
+	SharedThemes    []SharedTheme    `json:"shared_themes"`
+	VideoHighlights []VideoHighlight `json:"video_highlights"`
+}
+
+type ComparedVideo struct {
+	TrackingID       string           `json:"tracking_id"`
+	VideoID          string           `json:"video_id"`
+	Title            string           `json:"title"`
+	ChannelTitle     string           `json:"channel_title"`
+	RunDate          string           `json:"run_date"`
+	VideoStatistics  VideoStatistics  `json:"video_statistics"`
+	EngagementRatios EngagementRatios `json:"engagement_ratios"`
+	AudienceAnalysis AudienceAnalysis `json:"audience_analysis"`
+	KeyThemes        []KeyTheme       `json:"key_themes"`
+}
+
+type SharedTheme struct {
+	ThemeTitle  string   `json:"theme_title"`
+	Summary     string   `json:"summary"`
+	TrackingIDs []string `json:"tracking_ids"`
+}
+
+type VideoHighlight struct {
+	TrackingID      string   `json:"tracking_id"`
+	UniquePraise    []string `json:"unique_praise"`
+	UniqueCriticism []string `json:"unique_criticism"`
+}
+
+type RunHistory struct {
+	VideoID string     `json:"video_id"`
+	Runs    []RunEntry `json:"runs"`
+}
+
+type RunEntry struct {
+	TrackingID string    `json:"tracking_id"`
+	VideoID    string    `json:"video_id"`
+	RunDate    string    `json:"run_date"`
+	Trigger    string    `json:"trigger"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type Watchlist struct {
+	Entries []*WatchlistEntry `json:"entries"`
+}
+
+type WatchlistEntry struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	TargetID   string          `json:"target_id"`
+	Schedule   []SchedulePhase `json:"schedule"`
+	VideoCount int             `json:"video_count,omitempty"`
+	OriginID   string          `json:"origin_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	LastRunAt  time.Time       `json:"last_run_at,omitempty"`
+	NextRunAt  time.Time       `json:"next_run_at,omitempty"`
+	RunCount   int             `json:"run_count"`
+	// PublishedAt is when a discovered upload was published. Its schedule
+	// starts then instead of at CreatedAt.
+	PublishedAt time.Time `json:"published_at,omitzero"`
+}
+
+// ChannelUpload is a video in a channel's uploads playlist.
+type ChannelUpload struct {
+	VideoID     string
+	PublishedAt time.Time
+}
+
+type SchedulePhase struct {
+	Every string `json:"every"`
+	For   string `json:"for"`
+}
+
+type WatchlistRunSummary struct {
+	StartedAt        time.Time      `json:"started_at"`
+	FinishedAt       time.Time      `json:"finished_at"`
+	DiscoveredVideos int            `json:"discovered_videos"`
+	Runs             []WatchlistRun `json:"runs"`
+	// DeferredVideos lists the due videos left for the next pass because
+	// the pass reached its run limit.
+	DeferredVideos []string `json:"deferred_videos"`
+}
+
+type WatchlistRun struct {
+	EntryID    string `json:"entry_id"`
+	VideoID    string `json:"video_id"`
+	TrackingID string `json:"tracking_id"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+type VideoStatsSnapshot struct {
+	VideoID      string    `json:"video_id" bigquery:"video_id"`
+	SnapshotAt   time.Time `json:"snapshot_at" bigquery:"snapshot_at"`
+	ViewCount    int64     `json:"view_count" bigquery:"view_count"`
+	LikeCount    int64     `json:"like_count" bigquery:"like_count"`
+	CommentCount int64     `json:"comment_count" bigquery:"comment_count"`
+}
+
+type VideoVelocity struct {
+	VideoID         string          `json:"video_id"`
+	SnapshotCount   int             `json:"snapshot_count"`
+	ViewsPerHour    float64         `json:"views_per_hour"`
+	LikesPerHour    float64         `json:"likes_per_hour"`
+	CommentsPerHour float64         `json:"comments_per_hour"`
+	Series          []VelocityPoint `json:"series"`
+}
+
+type VelocityPoint struct {
+	SnapshotAt      time.Time `json:"snapshot_at"`
+	ViewCount       int64     `json:"view_count"`
+	LikeCount       int64     `json:"like_count"`
+	CommentCount    int64     `json:"comment_count"`
+	ViewsPerHour    float64   `json:"views_per_hour"`
+	LikesPerHour    float64   `json:"likes_per_hour"`
+	CommentsPerHour float64   `json:"comments_per_hour"`
+}
+
+type SentimentTrend struct {
+	VideoID     string            `json:"video_id"`
+	Threshold   float64           `json:"threshold"`
+	Points      []TrendPoint      `json:"points"`
+	Transitions []TrendTransition `json:"transitions"`
+	ShiftCount  int               `json:"shift_count"`
+}
+
+type TrendPoint struct {
+	VideoSentimentPoint
+	RanAt     time.Time `json:"ran_at"`
+	KeyThemes []string  `json:"key_themes"`
+}
+
+type TrendTransition struct {
+	FromTrackingID     string   `json:"from_tracking_id"`
+	ToTrackingID       string   `json:"to_tracking_id"`
+	FromLabel          string   `json:"from_label"`
+	ToLabel            string   `json:"to_label"`
+	PositiveShareDelta float64  `json:"positive_share_delta"`
+	NegativeShareDelta float64  `json:"negative_share_delta"`
+	Significant        bool     `json:"significant"`
+	Reasons            []string `json:"reasons"`
+	ThemesAppeared     []string `json:"themes_appeared"`
+	ThemesDisappeared  []string `json:"themes_disappeared"`
+}
+
+type AlertRules struct {
+	NegativeShareAbove     float64  `json:"negative_share_above"`
+	SentimentLabels        []string `json:"sentiment_labels"`
+	NegativeSpikeFactor    float64  `json:"negative_spike_factor"`
+	NewUnansweredQuestions bool     `json:"new_unanswered_questions"`
+	// ToxicSpikeFactor is the growth of harassment and hate comments since
+	// the previous run that raises an alert, once at least ToxicSpikeMin
+	// such comments were flagged.
+	ToxicSpikeFactor float64 `json:"toxic_spike_factor"`
+	ToxicSpikeMin    int64   `json:"toxic_spike_min"`
+}
+
+type Alert struct {
+	Rule      string  `json:"rule"`
+	Severity  string  `json:"severity"`
+	Message   string  `json:"message"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+type AlertNotification struct {
+	TrackingID  string    `json:"tracking_id"`
+	VideoID     string    `json:"video_id"`
+	VideoTitle  string    `json:"video_title"`
+	TriggeredAt time.Time `json:"triggered_at"`
+	Alerts      []Alert   `json:"alerts"`
+}
+
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookEvent struct {
+	EventID      string    `json:"event_id"`
+	Type         string    `json:"type"`
+	Stage        string    `json:"stage"`
+	Status       string    `json:"status"`
+	TrackingID   string    `json:"tracking_id"`
+	VideoID      string    `json:"video_id"`
+	ArtifactURIs []string  `json:"artifact_uris"`
+	Summary      string    `json:"summary"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type WebhookDelivery struct {
+	EventID     string    `json:"event_id"`
+	EventType   string    `json:"event_type"`
+	URL         string    `json:"url"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// PubSubPushEnvelope is the body of a Pub/Sub push delivery. Eventarc also
+// uses this shape as the data of Pub/Sub CloudEvents.
+type PubSubPushEnvelope struct {
+	Message      PubSubMessage `json:"message"`
+	Subscription string        `json:"subscription"`
+}
+
+type PubSubMessage struct {
+	Data        []byte            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	MessageID   string            `json:"messageId"`
+	MessageIDv2 string            `json:"message_id"`
+	PublishTime string            `json:"publishTime"`
+}
+
+// CloudEvent is a CloudEvents v1.0 event in structured content mode.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// VideoTrigger is the payload carried by a pipeline trigger event.
+type VideoTrigger struct {
+	VideoID     string `json:"video_id"`
+	URL         string `json:"url"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// Event marker statuses.
+const (
+	EventClaimed = "claimed"
+	EventDone    = "done"
+	EventFailed  = "failed"
+)
+
+// EventMarker records that a trigger event has been accepted, keyed by its
+// message ID, and how the run it started ended.
+type EventMarker struct {
+	EventID    string    `json:"event_id"`
+	Source     string    `json:"source"`
+	Trigger    string    `json:"trigger"`
+	VideoID    string    `json:"video_id"`
+	TrackingID string    `json:"tracking_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	// Status is claimed while the run is in progress. Markers written before
+	// statuses existed have none and count as done.
+	Status     string    `json:"status,omitempty"`
+	ClaimedAt  time.Time `json:"claimed_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Attempts   int       `json:"attempts,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SearchResponse is the result of a semantic search over the comments of a run.
+type SearchResponse struct {
+	TrackingID     string         `json:"tracking_id"`
+	Query          string         `json:"query"`
+	ProcessingTime string         `json:"processing_time"`
+	Results        []SearchResult `json:"results"`
+}
+
+// SearchResult is a comment with its cosine similarity to the query.
+type SearchResult struct {
+	Comment *Comment `json:"comment"`
+	Score   float32  `json:"score"`
+}
+
+// Moderation queue item statuses.
+const (
+	ModerationOpen    = "open"
+	ModerationHandled = "handled"
+)
+
+// ModerationQueue is the review state of the flagged comments of a run,
+// stored as moderation/<trackingId>.json.
+type ModerationQueue struct {
+	TrackingID string           `json:"tracking_id"`
+	VideoID    string           `json:"video_id"`
+	UpdatedAt  time.Time        `json:"updated_at,omitzero"`
+	Items      []ModerationItem `json:"items"`
+}
+
+// ModerationItem is a flagged comment in the moderation queue.
+type ModerationItem struct {
+	ModerationFlag
+	Status    string    `json:"status"`
+	HandledAt time.Time `json:"handled_at,omitzero"`
+	HandledBy string    `json:"handled_by,omitempty"`
+	Note      string    `json:"note,omitempty"`
+}
+
+// Chat message roles, matching the roles of the Gemini API.
+const (
+	ChatRoleUser  = "user"
+	ChatRoleModel = "model"
+)
+
+// ChatSession is a conversation about the comments of a run, stored as
+// chat/<trackingId>/<sessionId>.json.
+type ChatSession struct {
+	SessionID  string        `json:"session_id"`
+	TrackingID string        `json:"tracking_id"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	Messages   []ChatMessage `json:"messages"`
+}
+
+// ChatMessage is a question or an answer of a chat session.
+type ChatMessage struct {
+	Role      string         `json:"role"`
+	Text      string         `json:"text"`
+	Citations []ChatCitation `json:"citations,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ChatCitation is a comment that an answer cites by its ID.
+type ChatCitation struct {
+	CommentID string `json:"comment_id"`
+	Text      string `json:"text"`
+	LikeCount int64  `json:"like_count"`
+}
+
+// ChatEvent is a server-sent event of a streamed chat answer. 'delta' events
+// carry the next piece of the answer, the 'complete' event the citations.
+type ChatEvent struct {
+	Status    string         `json:"status"`
+	Message   string         `json:"message,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Citations []ChatCitation `json:"citations,omitempty"`
+}
+
+// LiveChatCapture is the status of a live chat collection, stored as livechat/<trackingId>.json.
+type LiveChatCapture struct {
+	TrackingID string    `json:"tracking_id"`
+	VideoID    string    `json:"video_id"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	Messages   int       `json:"messages"`
+	Analyze    bool      `json:"analyze"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Minutes    int       `json:"minutes,omitempty"`
+}
+
+// BatchManifest summarizes the runs of one batch task.
+type BatchManifest struct {
+	JobID      string        `json:"job_id"`
+	TaskIndex  int           `json:"task_index"`
+	TaskCount  int           `json:"task_count"`
+	Input      string        `json:"input"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Total      int           `json:"total"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	Results    []BatchResult `json:"results"`
+}
+
+type BatchResult struct {
+	Line       int    `json:"line"`
+	Input      string `json:"input"`
+	VideoID    string `json:"video_id,omitempty"`
+	TrackingID string `json:"tracking_id,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Duration   string `json:"duration"`
+}
+
+// ColumnMapping names the CSV columns or JSONL fields that hold each comment
+// field. Empty entries fall back to the defaults of the import format.
+type ColumnMapping struct {
+	ID          string `json:"id,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Text        string `json:"text,omitempty"`
+	LikeCount   string `json:"like_count,omitempty"`
+	ReplyCount  string `json:"reply_count,omitempty"`
+	VideoID     string `json:"video_id,omitempty"`
+	PublishedAt string `json:"published_at,omitempty"`
+	AuthorID    string `json:"author_channel_id,omitempty"`
+	Hearted     string `json:"hearted,omitempty"`
+	Pinned      string `json:"pinned,omitempty"`
+}
+
+// ImportRequest describes a comment file to convert into VideoData.
+type ImportRequest struct {
+	Format        string        `json:"format"`
+	Mapping       ColumnMapping `json:"mapping"`
+	VideoID       string        `json:"video_id"`
+	Title         string        `json:"title"`
+	Description   string        `json:"description"`
+	ChannelID     string        `json:"channel_id"`
+	ChannelTitle  string        `json:"channel_title"`
+	FetchMetadata bool          `json:"fetch_metadata"`
+}
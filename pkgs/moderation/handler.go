@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// updateRequest is the body accepted by POST /runs/{id}/moderation.
+type updateRequest struct {
+	CommentIDs []string `json:"comment_ids"`
+	Status     string   `json:"status"`
+	HandledBy  string   `json:"handled_by"`
+	Note       string   `json:"note"`
+}
+
+// Queue lists (GET) the flagged comments of a run and marks them as handled
+// or reopens them (POST). GET accepts 'status', 'category' and 'severity'
+// filters.
+func Queue(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		trackingID := r.PathValue("id")
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		switch r.Method {
+		case http.MethodGet:
+			queue, err := LoadQueue(ctx, cfg, trackingID)
+			if err != nil {
+				queueError(w, trackingID, err)
+				return
+			}
+			query := r.URL.Query()
+			filtered := queue.Items[:0:0]
+			for _, item := range queue.Items {
+				if matches(query.Get("status"), item.Status) && matches(query.Get("category"), item.Category) && matches(query.Get("severity"), item.Severity) {
+					filtered = append(filtered, item)
+				}
+			}
+			queue.Items = filtered
+			shared.JSONResponse(w, trackingID, http.StatusOK, queue)
+
+		case http.MethodPost:
+			var req updateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if len(req.CommentIDs) == 0 {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'comment_ids'")
+				return
+			}
+			if req.Status == "" {
+				req.Status = models.ModerationHandled
+			}
+			if req.Status != models.ModerationHandled && req.Status != models.ModerationOpen {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "'status' must be 'handled' or 'open'")
+				return
+			}
+
+			queue, missing, err := UpdateItems(ctx, cfg, trackingID, req.CommentIDs, req.Status, req.HandledBy, req.Note)
+			if err != nil {
+				queueError(w, trackingID, err)
+				return
+			}
+			if len(missing) > 0 {
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, fmt.Sprintf("Comments not in the moderation queue: %s", strings.Join(missing, ", ")))
+				return
+			}
+			shared.Logger.Info("Updated moderation queue", "comments", len(req.CommentIDs), "status", req.Status, "trackingId", trackingID)
+			shared.JSONResponse(w, trackingID, http.StatusOK, queue)
+
+		default:
+			shared.JSONErrorResponse(w, trackingID, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func matches(filter, value string) bool {
+	return filter == "" || strings.EqualFold(filter, value)
+}
+
+func queueError(w http.ResponseWriter, trackingID string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrObjectNotExist):
+		shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Analysis not found")
+	case errors.Is(err, ErrNotModerated):
+		shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "The analysis of this run has no moderation report. Re-run /magic to create one.")
+	case errors.Is(err, ErrConcurrentUpdate):
+		shared.JSONErrorResponse(w, trackingID, http.StatusConflict, "The moderation queue is being updated by other requests. Try again.")
+	default:
+		shared.Logger.Error("Failed to load moderation queue", "error", err, "trackingId", trackingID)
+		shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to load moderation queue")
+	}
+}
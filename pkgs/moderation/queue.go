@@ -0,0 +1,147 @@
+package moderation
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// ErrNotModerated is returned for runs analyzed before the moderation pass existed.
+var ErrNotModerated = errors.New("the analysis of this run has no moderation report")
+
+// ErrConcurrentUpdate is returned when other updates kept replacing the queue
+// file for all of maxUpdateAttempts.
+var ErrConcurrentUpdate = errors.New("the moderation queue was changed by concurrent updates")
+
+// maxUpdateAttempts bounds how often an update is retried after another
+// instance replaced the queue file first.
+const maxUpdateAttempts = 5
+
+// queueMu serializes read-modify-write cycles on queue files within this
+// instance. Across instances, updates are only written if the queue file is
+// unchanged since it was read.
+var queueMu sync.Mutex
+
+func queueObjectName(trackingID string) string {
+	return fmt.Sprintf("moderation/%s.json", trackingID)
+}
+
+// LoadQueue returns the moderation queue of a run. Until the first update, the
+// queue is built from the moderation report of the analysis with every item open.
+func LoadQueue(ctx context.Context, cfg *models.AppConfig, trackingID string) (*models.ModerationQueue, error) {
+	queue, _, err := loadQueue(ctx, cfg, trackingID)
+	return queue, err
+}
+
+// loadQueue returns the moderation queue of a run and the generation of its
+// queue file, or 0 when the queue has not been stored yet.
+func loadQueue(ctx context.Context, cfg *models.AppConfig, trackingID string) (*models.ModerationQueue, int64, error) {
+	data, generation, err := shared.GetFileWithGenerationFromGCS(ctx, cfg.GCSBucketName, queueObjectName(trackingID))
+	if err == nil {
+		var queue models.ModerationQueue
+		if err := json.Unmarshal(data, &queue); err != nil {
+			return nil, 0, fmt.Errorf("could not unmarshal moderation queue JSON: %w", err)
+		}
+		return &queue, generation, nil
+	}
+	if !errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, 0, fmt.Errorf("could not get moderation queue from GCS: %w", err)
+	}
+
+	record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if record.Moderation == nil {
+		return nil, 0, ErrNotModerated
+	}
+	queue := &models.ModerationQueue{TrackingID: trackingID, Items: []models.ModerationItem{}}
+	if video, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID); err == nil {
+		queue.VideoID = video.ID
+	}
+	for _, flag := range record.Moderation.Flags {
+		queue.Items = append(queue.Items, models.ModerationItem{ModerationFlag: flag, Status: models.ModerationOpen})
+	}
+	return queue, 0, nil
+}
+
+// UpdateItems sets the status of the given comments in the queue of a run and
+// returns the updated queue. Comment IDs that are not in the queue are
+// returned as missing and leave the queue unchanged. The queue file is created
+// or replaced with a precondition, and the update is applied again to a fresh
+// copy when another instance wrote the file in between.
+func UpdateItems(ctx context.Context, cfg *models.AppConfig, trackingID string, commentIDs []string, status, handledBy, note string) (*models.ModerationQueue, []string, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	objectName := queueObjectName(trackingID)
+	for attempt := 1; attempt <= maxUpdateAttempts; attempt++ {
+		queue, generation, err := loadQueue(ctx, cfg, trackingID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if missing := setStatus(queue, commentIDs, status, handledBy, note); len(missing) > 0 {
+			return nil, missing, nil
+		}
+
+		data, err := json.Marshal(queue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not marshal moderation queue JSON: %w", err)
+		}
+		var written bool
+		if generation == 0 {
+			written, err = shared.CreateGCSObjectIfAbsent(ctx, cfg.GCSBucketName, objectName, data)
+		} else {
+			written, err = shared.ReplaceGCSObjectIfGeneration(ctx, cfg.GCSBucketName, objectName, data, generation)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not upload moderation queue to GCS: %w", err)
+		}
+		if written {
+			return queue, nil, nil
+		}
+		shared.Logger.Info("Moderation queue changed during the update, retrying", "attempt", attempt, "trackingId", trackingID)
+	}
+	return nil, nil, ErrConcurrentUpdate
+}
+
+// setStatus applies an update to the queue. It returns the comment IDs that
+// are not in the queue and leaves the queue unchanged if there are any.
+func setStatus(queue *models.ModerationQueue, commentIDs []string, status, handledBy, note string) []string {
+	positions := make(map[string]int, len(queue.Items))
+	for i, item := range queue.Items {
+		positions[item.CommentID] = i
+	}
+	var missing []string
+	for _, id := range commentIDs {
+		if _, ok := positions[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return missing
+	}
+
+	now := time.Now()
+	for _, id := range commentIDs {
+		item := &queue.Items[positions[id]]
+		item.Status = status
+		item.Note = note
+		if status == models.ModerationHandled {
+			item.HandledAt = now
+			item.HandledBy = handledBy
+		} else {
+			item.HandledAt = time.Time{}
+			item.HandledBy = ""
+		}
+	}
+	queue.UpdatedAt = now
+	return nil
+}
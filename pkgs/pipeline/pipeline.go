@@ -0,0 +1,262 @@
+package pipeline
+
+import (
+	"app/pkgs/alerts"
+	"app/pkgs/bq_ingest"
+	"app/pkgs/gemini_magic"
+	"app/pkgs/models"
+	"app/pkgs/run_history"
+	"app/pkgs/shared"
+	"app/pkgs/webhooks"
+	"app/pkgs/yt_video"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notifier receives progress updates while the pipeline runs. The status is
+// one of "processing", "success" or "error".
+type Notifier func(status, message string)
+
+// Request describes a single pipeline run.
+type Request struct {
+	VideoID string
+	// Trigger describes what started the run, e.g. "ui" or "scheduler".
+	Trigger string
+	// CallbackURL optionally receives a signed webhook event per stage.
+	CallbackURL string
+	// TrackingID is generated when empty.
+	TrackingID string
+	// SkipFetch starts the run at the analyze step, for raw data that is
+	// already stored as <TrackingID>.json, e.g. a live chat capture.
+	SkipFetch bool
+	// Analyze holds the optional parts of the analysis.
+	Analyze AnalyzeOptions
+}
+
+// AnalyzeOptions are the optional parts of the analysis, passed to /magic as
+// query parameters.
+type AnalyzeOptions struct {
+	// Transcript cross-references the comments with the transcript stored
+	// for the run, so it needs a TrackingID whose transcript was uploaded.
+	Transcript bool
+	// Packaging adds the title and thumbnail critique.
+	Packaging bool
+	// IncludeBots keeps suspected bot comments in the analysis.
+	IncludeBots bool
+	// Language is the language of the report text. Empty means English.
+	Language string
+	// Aspects is the name of an aspect taxonomy profile. Empty uses the
+	// channel's taxonomy, if any.
+	Aspects string
+	// Clusters adds the theme clusters and groups questions by embeddings.
+	Clusters bool
+}
+
+// apply adds the options to an /magic action URI.
+func (o AnalyzeOptions) apply(action string) (string, error) {
+	u, err := url.Parse(action)
+	if err != nil {
+		return "", fmt.Errorf("invalid next action %q: %w", action, err)
+	}
+	query := u.Query()
+	flags := []struct {
+		name string
+		set  bool
+	}{
+		{"transcript", o.Transcript},
+		{"packaging", o.Packaging},
+		{"includeBots", o.IncludeBots},
+		{"clusters", o.Clusters},
+	}
+	for _, flag := range flags {
+		if flag.set {
+			query.Set(flag.name, "true")
+		}
+	}
+	if o.Language != "" {
+		query.Set("language", o.Language)
+	}
+	if o.Aspects != "" {
+		query.Set("aspects", o.Aspects)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Run executes the fetch, analyze and ingest steps for a video in-process,
+// records the run in the video's history and emits webhook events for every
+// stage. It returns the tracking ID of the run.
+func Run(ctx context.Context, cfg *models.AppConfig, req Request, notify Notifier) (string, error) {
+	if notify == nil {
+		notify = func(status, message string) {}
+	}
+
+	trackingID := req.TrackingID
+	if trackingID == "" {
+		trackingID = uuid.New().String()
+	}
+	entry := models.RunEntry{
+		TrackingID: trackingID,
+		VideoID:    req.VideoID,
+		RunDate:    time.Now().Format("2006-01-02"),
+		Trigger:    req.Trigger,
+		StartedAt:  time.Now(),
+	}
+	shared.Logger.Info("Starting pipeline run", "videoId", req.VideoID, "trigger", req.Trigger, "trackingId", trackingID)
+
+	err := runSteps(ctx, cfg, req, trackingID, notify)
+
+	entry.FinishedAt = time.Now()
+	entry.Status = "success"
+	summary := "All steps completed successfully."
+	if err != nil {
+		entry.Status = "error"
+		entry.Message = err.Error()
+		summary = err.Error()
+	}
+	if histErr := run_history.RecordRun(ctx, cfg, entry); histErr != nil {
+		shared.Logger.Error("Failed to record pipeline run", "error", histErr, "videoId", req.VideoID, "trackingId", trackingID)
+	}
+
+	status := webhooks.StatusCompleted
+	if err != nil {
+		status = webhooks.StatusFailed
+	}
+	webhooks.Dispatch(cfg, webhooks.NewEvent("pipeline", status, trackingID, req.VideoID, summary, artifactURIs(cfg, "pipeline", trackingID)), req.CallbackURL)
+	return trackingID, err
+}
+
+// artifactURIs lists the artifacts produced by a stage.
+func artifactURIs(cfg *models.AppConfig, stage, trackingID string) []string {
+	raw := fmt.Sprintf("gs://%s/%s.json", cfg.GCSBucketName, trackingID)
+	analyzed := fmt.Sprintf("gs://%s/%s_analyzed.json", cfg.GCSBucketName, trackingID)
+	table := func(name string) string {
+		return fmt.Sprintf("bq://%s.%s.%s", cfg.GCPProject, cfg.BQDataset, name)
+	}
+	switch stage {
+	case "fetch":
+		return []string{raw}
+	case "analyze":
+		return []string{analyzed}
+	case "ingest":
+		return []string{table("videos"), table("comments"), table("analyzed"), table("moments"), table("aspects"), table("mentions")}
+	default:
+		return []string{raw, analyzed, table("videos"), table("comments"), table("analyzed"), table("moments"), table("aspects"), table("mentions")}
+	}
+}
+
+func runSteps(ctx context.Context, cfg *models.AppConfig, req Request, trackingID string, notify Notifier) error {
+	stageDone := func(stage string, err error, summary string) {
+		if err != nil {
+			webhooks.Dispatch(cfg, webhooks.NewEvent(stage, webhooks.StatusFailed, trackingID, req.VideoID, err.Error(), nil), req.CallbackURL)
+			return
+		}
+		webhooks.Dispatch(cfg, webhooks.NewEvent(stage, webhooks.StatusCompleted, trackingID, req.VideoID, summary, artifactURIs(cfg, stage, trackingID)), req.CallbackURL)
+	}
+
+	// ---	Step 1: Fetch YouTube Data ---
+	nextAction := fmt.Sprintf("/magic?trackingId=%s", trackingID)
+	if req.SkipFetch {
+		notify("processing", "Step 1/3: Skipped, raw data is already stored.")
+	} else {
+		notify("processing", "Step 1/3: Fetching YouTube data...")
+		youtubeEndpoint := fmt.Sprintf("/youtube?videoId=%s&trackingId=%s", url.QueryEscape(req.VideoID), trackingID)
+		var step1Response models.APIResponse
+		if err := callHandler(ctx, yt_video.FetchData(cfg), youtubeEndpoint, &step1Response); err != nil {
+			notify("error", "Step 1 failed: "+err.Error())
+			stageDone("fetch", err, "")
+			return err
+		}
+		notify("success", fmt.Sprintf("Step 1/3 succeeded: %s (Tracking ID: %s, Time: %s)", step1Response.Message, step1Response.TrackingID, step1Response.ProcessingTime))
+		stageDone("fetch", nil, step1Response.Message)
+
+		nextAction = step1Response.NextActionURI
+		if nextAction == "" {
+			notify("error", "Error: Step 1 response did not contain a valid next action.")
+			err := errors.New("step 1 response did not contain a valid next action")
+			stageDone("analyze", err, "")
+			return err
+		}
+	}
+	nextAction, err := req.Analyze.apply(nextAction)
+	if err != nil {
+		notify("error", "Error: "+err.Error())
+		stageDone("analyze", err, "")
+		return err
+	}
+	notify("processing", fmt.Sprintf("Next action: %s", nextAction))
+
+	// ---	Step 2: Analyze with Gemini ---
+	notify("processing", "Step 2/3: Analyzing data with Gemini...")
+	var step2Response models.APIResponse
+	if err := callHandler(ctx, gemini_magic.AnalyzeData(cfg), nextAction, &step2Response); err != nil {
+		notify("error", "Step 2 failed: "+err.Error())
+		stageDone("analyze", err, "")
+		return err
+	}
+	notify("success", fmt.Sprintf("Step 2/3 succeeded: %s (Time: %s)", step2Response.Message, step2Response.ProcessingTime))
+	stageDone("analyze", nil, step2Response.Message)
+
+	// Alerting must never block ingestion, so failures are only reported.
+	if notification, err := alerts.Evaluate(ctx, cfg, trackingID); err != nil {
+		shared.Logger.Error("Failed to evaluate alert rules", "error", err, "trackingId", trackingID)
+		notify("processing", "Alert evaluation failed: "+err.Error())
+	} else if len(notification.Alerts) > 0 {
+		notify("processing", fmt.Sprintf("Raised %d alert(s) for this analysis.", len(notification.Alerts)))
+	}
+
+	nextAction = step2Response.NextActionURI
+	if nextAction == "" {
+		notify("error", "Error: Step 2 response did not contain a valid next action.")
+		err := errors.New("step 2 response did not contain a valid next action")
+		stageDone("ingest", err, "")
+		return err
+	}
+	notify("processing", fmt.Sprintf("Next action: %s", nextAction))
+
+	// ---	Step 3: Ingest into BigQuery ---
+	notify("processing", "Step 3/3: Ingesting data into BigQuery...")
+	var step3Response models.APIResponse
+	if err := callHandler(ctx, bq_ingest.IngestData(cfg), nextAction, &step3Response); err != nil {
+		notify("error", "Step 3 failed: "+err.Error())
+		stageDone("ingest", err, "")
+		return err
+	}
+	notify("success", fmt.Sprintf("Step 3/3 succeeded: %s (Time: %s)", step3Response.Message, step3Response.ProcessingTime))
+	stageDone("ingest", nil, step3Response.Message)
+	return nil
+}
+
+// callHandler invokes another HTTP handler in-process, avoiding network overhead.
+func callHandler(ctx context.Context, handler http.HandlerFunc, targetURL string, targetStruct interface{}) error {
+	req := httptest.NewRequest("GET", targetURL, nil)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	handler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		return fmt.Errorf("handler returned non-200 status: %d, body: %s", rr.Code, rr.Body.String())
+	}
+
+	if err := json.Unmarshal(rr.Body.Bytes(), targetStruct); err != nil {
+		return fmt.Errorf("failed to unmarshal handler response: %w. Body: %s", err, rr.Body.String())
+	}
+
+	// Check for application-level success status from the APIResponse
+	if response, ok := targetStruct.(*models.APIResponse); ok {
+		if response.Status != "success" && response.Status != "skipped" {
+			return fmt.Errorf("handler returned application error: %s", response.Message)
+		}
+	}
+
+	return nil
+}
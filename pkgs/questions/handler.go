@@ -0,0 +1,56 @@
+package questions
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"errors"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+)
+
+// statusAll lists the clusters of both statuses.
+const statusAll = "all"
+
+// List returns the question clusters of an analyzed run. The 'status' query
+// parameter selects 'open' (default), 'answered' or 'all' clusters.
+func List(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		trackingID := r.PathValue("id")
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = models.QuestionOpen
+		}
+		if status != models.QuestionOpen && status != models.QuestionAnswered && status != statusAll {
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "'status' must be 'open', 'answered' or 'all'")
+			return
+		}
+
+		record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, trackingID)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Analysis not found")
+			return
+		}
+		if err != nil {
+			shared.Logger.Error("could not load analysis", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to load analysis")
+			return
+		}
+		if record.Questions == nil {
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "The analysis of this run has no question tracker. Re-run /magic to create one.")
+			return
+		}
+
+		list := models.QuestionList{TrackingID: trackingID, Status: status, QuestionReport: *record.Questions}
+		list.Clusters = []models.QuestionCluster{}
+		for _, c := range record.Questions.Clusters {
+			if status == statusAll || c.Status == status {
+				list.Clusters = append(list.Clusters, c)
+			}
+		}
+		shared.JSONResponse(w, trackingID, http.StatusOK, list)
+	}
+}
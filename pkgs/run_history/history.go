@@ -0,0 +1,79 @@
+package run_history
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"cloud.google.com/go/storage"
+)
+
+// historyMu serializes read-modify-write cycles on history files within this instance.
+var historyMu sync.Mutex
+
+func objectName(videoID string) string {
+	return fmt.Sprintf("history/%s.json", videoID)
+}
+
+// Load returns the run history of a video. A video without recorded runs
+// yields an empty history rather than an error.
+func Load(ctx context.Context, cfg *models.AppConfig, videoID string) (*models.RunHistory, error) {
+	history := &models.RunHistory{VideoID: videoID, Runs: []models.RunEntry{}}
+	fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, objectName(videoID))
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return history, nil
+		}
+		return nil, fmt.Errorf("could not get history file from GCS: %w", err)
+	}
+	if err := json.Unmarshal(fileData, history); err != nil {
+		return nil, fmt.Errorf("could not unmarshal history JSON: %w", err)
+	}
+	return history, nil
+}
+
+// RecordRun appends a run to the history of its video.
+func RecordRun(ctx context.Context, cfg *models.AppConfig, entry models.RunEntry) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	history, err := Load(ctx, cfg, entry.VideoID)
+	if err != nil {
+		return err
+	}
+	history.Runs = append(history.Runs, entry)
+
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("could not marshal history JSON: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, objectName(entry.VideoID), historyJSON); err != nil {
+		return fmt.Errorf("could not upload history file to GCS: %w", err)
+	}
+	return nil
+}
+
+// History returns every recorded pipeline run of a video.
+func History(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		videoID := r.URL.Query().Get("videoId")
+		if videoID == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'videoId' query parameter")
+			return
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "videoId", videoID)
+
+		history, err := Load(r.Context(), cfg, videoID)
+		if err != nil {
+			shared.Logger.Error("could not load run history", "error", err, "videoId", videoID)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load run history")
+			return
+		}
+		shared.JSONResponse(w, "", http.StatusOK, history)
+	}
+}
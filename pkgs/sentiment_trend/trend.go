@@ -0,0 +1,154 @@
+package sentiment_trend
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/run_history"
+	"app/pkgs/shared"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const defaultShiftThreshold = 0.10
+
+// themeSimilarity is the minimum word overlap (Jaccard index) for two theme
+// titles from different runs to be treated as the same theme.
+const themeSimilarity = 0.5
+
+// labelRank orders the sentiment labels produced by the analyzer from most
+// negative to most positive.
+var labelRank = map[string]int{
+	"Overwhelmingly Negative": 0,
+	"Negative":                1,
+	"Mixed":                   2,
+	"Positive":                3,
+	"Overwhelmingly Positive": 4,
+}
+
+// themesMissingFrom returns the themes in from that have no similar theme in to.
+func themesMissingFrom(from, to []string) []string {
+	missing := []string{}
+	for _, theme := range from {
+		found := false
+		for _, other := range to {
+			if shared.SimilarText(theme, other, themeSimilarity) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, theme)
+		}
+	}
+	return missing
+}
+
+// compareRuns describes the change between two consecutive runs and flags it
+// as significant when a share moves by more than the threshold or the
+// sentiment label moves towards negative.
+func compareRuns(prev, next models.TrendPoint, threshold float64) models.TrendTransition {
+	t := models.TrendTransition{
+		FromTrackingID:     prev.TrackingID,
+		ToTrackingID:       next.TrackingID,
+		FromLabel:          prev.SentimentLabel,
+		ToLabel:            next.SentimentLabel,
+		PositiveShareDelta: next.PositiveShare - prev.PositiveShare,
+		NegativeShareDelta: next.NegativeShare - prev.NegativeShare,
+		Reasons:            []string{},
+		ThemesAppeared:     themesMissingFrom(next.KeyThemes, prev.KeyThemes),
+		ThemesDisappeared:  themesMissingFrom(prev.KeyThemes, next.KeyThemes),
+	}
+
+	if t.NegativeShareDelta > threshold {
+		t.Reasons = append(t.Reasons, fmt.Sprintf("Negative share rose by %.1f points", t.NegativeShareDelta*100))
+	}
+	if -t.PositiveShareDelta > threshold {
+		t.Reasons = append(t.Reasons, fmt.Sprintf("Positive share fell by %.1f points", -t.PositiveShareDelta*100))
+	}
+	prevRank, prevOK := labelRank[prev.SentimentLabel]
+	nextRank, nextOK := labelRank[next.SentimentLabel]
+	if prevOK && nextOK && nextRank < prevRank {
+		t.Reasons = append(t.Reasons, fmt.Sprintf("Sentiment label changed from %s to %s", prev.SentimentLabel, next.SentimentLabel))
+	}
+	t.Significant = len(t.Reasons) > 0
+	return t
+}
+
+// BuildTrend loads the analysis of every successful run of a video, orders them
+// by run time and detects significant shifts between consecutive runs.
+func BuildTrend(ctx context.Context, cfg *models.AppConfig, videoID string, threshold float64) (*models.SentimentTrend, error) {
+	history, err := run_history.Load(ctx, cfg, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	trend := &models.SentimentTrend{
+		VideoID:     videoID,
+		Threshold:   threshold,
+		Points:      []models.TrendPoint{},
+		Transitions: []models.TrendTransition{},
+	}
+	for _, run := range history.Runs {
+		if run.Status != "success" {
+			continue
+		}
+		record, err := shared.LoadAnalysisRecord(ctx, cfg.GCSBucketName, run.TrackingID)
+		if err != nil {
+			shared.Logger.Warn("Skipping run without analysis", "error", err, "videoId", videoID, "trackingId", run.TrackingID)
+			continue
+		}
+		point := models.TrendPoint{
+			VideoSentimentPoint: shared.SentimentPoint(run.TrackingID, videoID, "", record),
+			RanAt:               run.FinishedAt,
+			KeyThemes:           []string{},
+		}
+		for _, theme := range record.KeyThemes {
+			point.KeyThemes = append(point.KeyThemes, theme.ThemeTitle)
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	sort.SliceStable(trend.Points, func(i, j int) bool {
+		return trend.Points[i].RanAt.Before(trend.Points[j].RanAt)
+	})
+
+	for i := 1; i < len(trend.Points); i++ {
+		transition := compareRuns(trend.Points[i-1], trend.Points[i], threshold)
+		if transition.Significant {
+			trend.ShiftCount++
+		}
+		trend.Transitions = append(trend.Transitions, transition)
+	}
+	return trend, nil
+}
+
+// Trend returns the sentiment series of a video across its runs together with
+// the detected shifts and theme changes.
+func Trend(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		videoID := r.URL.Query().Get("videoId")
+		if videoID == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'videoId' query parameter")
+			return
+		}
+		threshold := defaultShiftThreshold
+		if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
+			parsed, err := strconv.ParseFloat(thresholdStr, 64)
+			if err != nil || parsed <= 0 || parsed >= 1 {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'threshold' must be a number between 0 and 1")
+				return
+			}
+			threshold = parsed
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "videoId", videoID)
+
+		trend, err := BuildTrend(r.Context(), cfg, videoID, threshold)
+		if err != nil {
+			shared.Logger.Error("could not build sentiment trend", "error", err, "videoId", videoID)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to build sentiment trend")
+			return
+		}
+		shared.JSONResponse(w, "", http.StatusOK, trend)
+	}
+}
@@ -0,0 +1,125 @@
+package shared
+
+import (
+	"app/pkgs/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LoadVideoData reads and decodes the raw <trackingId>.json file from GCS.
+func LoadVideoData(ctx context.Context, GCSBucketName, trackingID string) (*models.VideoData, error) {
+	objectName := fmt.Sprintf("%s.json", trackingID)
+	fileData, err := GetFileFromGCS(ctx, GCSBucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var data models.VideoData
+	if err := json.Unmarshal(fileData, &data); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", objectName, err)
+	}
+	return &data, nil
+}
+
+// LoadAnalysisRecord reads and decodes the <trackingId>_analyzed.json file from GCS.
+func LoadAnalysisRecord(ctx context.Context, GCSBucketName, trackingID string) (*models.AnalysisRecord, error) {
+	objectName := fmt.Sprintf("%s_analyzed.json", trackingID)
+	fileData, err := GetFileFromGCS(ctx, GCSBucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.AnalysisRecord
+	if err := json.Unmarshal(fileData, &record); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", objectName, err)
+	}
+	return &record, nil
+}
+
+// SplitIDs parses a comma-separated list of IDs, dropping blanks and duplicates.
+func SplitIDs(value string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		id := strings.TrimSpace(part)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SentimentPoint summarizes the audience sentiment and engagement of one analysis.
+func SentimentPoint(trackingID, videoID, title string, record *models.AnalysisRecord) models.VideoSentimentPoint {
+	audience := record.AudienceAnalysis
+	point := models.VideoSentimentPoint{
+		TrackingID:         trackingID,
+		VideoID:            videoID,
+		Title:              title,
+		RunDate:            record.RunDate,
+		SentimentLabel:     audience.SentimentLabel,
+		PositiveComments:   audience.PositiveComments,
+		NegativeComments:   audience.NegativeComments,
+		NeutralComments:    audience.NeutralComments,
+		ViewCount:          record.PerformanceMetrics.VideoStatistics.ViewCount,
+		LikeToViewRatio:    record.PerformanceMetrics.EngagementRatios.LikeToViewRatio,
+		CommentToViewRatio: record.PerformanceMetrics.EngagementRatios.CommentToViewRatio,
+	}
+	if total := audience.PositiveComments + audience.NegativeComments + audience.NeutralComments; total > 0 {
+		point.PositiveShare = float64(audience.PositiveComments) / float64(total)
+		point.NegativeShare = float64(audience.NegativeComments) / float64(total)
+	}
+	return point
+}
+
+// SaveAnnotations stores the per-comment annotations of an analysis as <trackingId>_annotations.json.
+func SaveAnnotations(ctx context.Context, GCSBucketName, trackingID string, annotations []models.CommentAnnotation) error {
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return fmt.Errorf("could not marshal annotations: %w", err)
+	}
+	return UploadToGCS(ctx, GCSBucketName, fmt.Sprintf("%s_annotations.json", trackingID), data)
+}
+
+// LoadAnnotations reads the <trackingId>_annotations.json file from GCS.
+func LoadAnnotations(ctx context.Context, GCSBucketName, trackingID string) ([]models.CommentAnnotation, error) {
+	objectName := fmt.Sprintf("%s_annotations.json", trackingID)
+	fileData, err := GetFileFromGCS(ctx, GCSBucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var annotations []models.CommentAnnotation
+	if err := json.Unmarshal(fileData, &annotations); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", objectName, err)
+	}
+	return annotations, nil
+}
+
+// SaveTranscript stores the transcript of a run as <trackingId>_transcript.json.
+func SaveTranscript(ctx context.Context, GCSBucketName, trackingID string, transcript *models.Transcript) error {
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return fmt.Errorf("could not marshal transcript: %w", err)
+	}
+	return UploadToGCS(ctx, GCSBucketName, fmt.Sprintf("%s_transcript.json", trackingID), data)
+}
+
+// LoadTranscript reads the <trackingId>_transcript.json file from GCS.
+func LoadTranscript(ctx context.Context, GCSBucketName, trackingID string) (*models.Transcript, error) {
+	objectName := fmt.Sprintf("%s_transcript.json", trackingID)
+	fileData, err := GetFileFromGCS(ctx, GCSBucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var transcript models.Transcript
+	if err := json.Unmarshal(fileData, &transcript); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", objectName, err)
+	}
+	return &transcript, nil
+}
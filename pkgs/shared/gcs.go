@@ -2,12 +2,16 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 )
 
 var (
@@ -71,3 +75,93 @@ func GetFileFromGCS(ctx context.Context, GCSBucketName, objectName string) ([]by
 
 	return data, nil
 }
+
+// CreateGCSObjectIfAbsent writes an object only if it does not exist yet. It
+// returns false without error when the object already exists, which makes it
+// usable as a cross-instance idempotency marker.
+func CreateGCSObjectIfAbsent(ctx context.Context, GCSBucketName, objectName string, data []byte) (bool, error) {
+	client, err := getStorageClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get GCS client: %w", err)
+	}
+
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Second*50)
+	defer cancel()
+
+	obj := client.Bucket(GCSBucketName).Object(objectName).If(storage.Conditions{DoesNotExist: true})
+	wc := obj.NewWriter(uploadCtx)
+	if _, err := wc.Write(data); err != nil {
+		wc.Close()
+		return false, fmt.Errorf("failed to write to GCS: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to close GCS writer: %w", err)
+	}
+	return true, nil
+}
+
+// GetFileWithGenerationFromGCS reads an object together with its generation,
+// for a later ReplaceGCSObjectIfGeneration.
+func GetFileWithGenerationFromGCS(ctx context.Context, GCSBucketName, objectName string) ([]byte, int64, error) {
+	client, err := getStorageClient(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get GCS client: %w", err)
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, time.Second*50)
+	defer cancel()
+
+	rc, err := client.Bucket(GCSBucketName).Object(objectName).NewReader(readCtx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create reader for object %s: %w", objectName, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read object content for %s: %w", objectName, err)
+	}
+	return data, rc.Attrs.Generation, nil
+}
+
+// ReplaceGCSObjectIfGeneration overwrites an object only if it is still at
+// the given generation. It returns false without error when another writer
+// replaced it first.
+func ReplaceGCSObjectIfGeneration(ctx context.Context, GCSBucketName, objectName string, data []byte, generation int64) (bool, error) {
+	client, err := getStorageClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get GCS client: %w", err)
+	}
+
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Second*50)
+	defer cancel()
+
+	obj := client.Bucket(GCSBucketName).Object(objectName).If(storage.Conditions{GenerationMatch: generation})
+	wc := obj.NewWriter(uploadCtx)
+	if _, err := wc.Write(data); err != nil {
+		wc.Close()
+		return false, fmt.Errorf("failed to write to GCS: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to close GCS writer: %w", err)
+	}
+	return true, nil
+}
+
+// ParseGCSURI splits a gs://bucket/object URI into its bucket and object name.
+func ParseGCSURI(uri string) (string, string, error) {
+	path := strings.TrimPrefix(uri, "gs://")
+	bucket, object, ok := strings.Cut(path, "/")
+	if path == uri || !ok || bucket == "" || object == "" {
+		return "", "", fmt.Errorf("invalid GCS URI %q, expected gs://<bucket>/<object>", uri)
+	}
+	return bucket, object, nil
+}
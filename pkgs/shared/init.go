@@ -1,29 +1,50 @@
 package shared
 
 import (
-	"log"
+	"app/pkgs/models"
+	"errors"
 	"log/slog"
 	"os"
 )
 
 func init() {
-	// 1. Initialize logger
-	Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	// 1. Initialize logger. Logs go to stderr so that command-line output on
+	// stdout stays machine-readable; Cloud Run collects both streams.
+	Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 	slog.SetDefault(Logger)
 
 	// 2. Initialize config
 	AppConfig.YTApiKey = GetEnvString("YOUTUBE_API_KEY", "")
 	AppConfig.GEMINIApiKey = GetEnvString("GEMINI_API_KEY", "")
 
-	if AppConfig.YTApiKey == "" || AppConfig.GEMINIApiKey == "" {
-		log.Fatal("CRITICAL: YOUTUBE_API_KEY and GEMINI_API_KEY environment variables must be set.")
-	}
-
 	AppConfig.GCSBucketName = GetEnvString("GCS_BUCKET_NAME", "yt-sentiment-bucket")
 	AppConfig.GCPProject = GetEnvString("GCP_PROJECT", "")
 	AppConfig.GCPLocation = GetEnvString("GCP_LOCATION", "us-central1")
 	AppConfig.BQDataset = GetEnvString("BQ_DATASET", "yt_sentiment_data")
 	AppConfig.GEMINIModel = GetEnvString("GEMINI_MODEL", "gemini-1.5-pro-latest")
+	AppConfig.EmbeddingModel = GetEnvString("EMBEDDING_MODEL", "text-embedding-004")
 	AppConfig.MaxCommentsToFetch = GetEnvInt("MAX_COMMENTS_TO_FETCH", 5000)
 	AppConfig.Port = GetEnvString("PORT", "8080")
+	AppConfig.SchedulerInterval = GetEnvInt("SCHEDULER_INTERVAL_MINUTES", 0)
+	AppConfig.StatsPollInterval = GetEnvInt("STATS_POLL_INTERVAL_MINUTES", 0)
+	AppConfig.AlertWebhookURL = GetEnvString("ALERT_WEBHOOK_URL", "")
+	AppConfig.AlertSlackURL = GetEnvString("ALERT_SLACK_WEBHOOK_URL", "")
+	AppConfig.AlertSMTPHost = GetEnvString("ALERT_SMTP_HOST", "")
+	AppConfig.AlertSMTPPort = GetEnvInt("ALERT_SMTP_PORT", 587)
+	AppConfig.AlertSMTPUsername = GetEnvString("ALERT_SMTP_USERNAME", "")
+	AppConfig.AlertSMTPPassword = GetEnvString("ALERT_SMTP_PASSWORD", "")
+	AppConfig.AlertEmailFrom = GetEnvString("ALERT_EMAIL_FROM", "")
+	AppConfig.AlertEmailTo = GetEnvString("ALERT_EMAIL_TO", "")
+	AppConfig.WebhookSecret = GetEnvString("WEBHOOK_SIGNING_SECRET", "")
+}
+
+// RequireAPIKeys returns an error when one of the requested API keys is not configured.
+func RequireAPIKeys(cfg *models.AppConfig, youtube, gemini bool) error {
+	if youtube && cfg.YTApiKey == "" {
+		return errors.New("YOUTUBE_API_KEY environment variable must be set")
+	}
+	if gemini && cfg.GEMINIApiKey == "" {
+		return errors.New("GEMINI_API_KEY environment variable must be set")
+	}
+	return nil
 }
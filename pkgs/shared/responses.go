@@ -22,3 +22,14 @@ func JSONErrorResponse(w http.ResponseWriter, trackingID string, code int, messa
 		Logger.Error("FATAL: could not write JSON error response", "error", err, "trackingId", trackingID)
 	}
 }
+
+func JSONResponse(w http.ResponseWriter, trackingID string, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(payload); err != nil {
+		Logger.Error("could not write JSON response", "error", err, "trackingId", trackingID)
+	}
+}
@@ -0,0 +1,34 @@
+package shared
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words returns the set of lower-cased words in a text.
+func Words(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// SimilarText reports whether the word overlap (Jaccard index) of two short
+// texts, such as theme titles or questions, reaches the given threshold.
+func SimilarText(a, b string, threshold float64) bool {
+	wa, wb := Words(a), Words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return false
+	}
+	intersection := 0
+	for word := range wa {
+		if wb[word] {
+			intersection++
+		}
+	}
+	union := len(wa) + len(wb) - intersection
+	return float64(intersection)/float64(union) >= threshold
+}
@@ -0,0 +1,136 @@
+package transcript
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"app/pkgs/yt_video"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// maxUploadBytes limits the size of an uploaded caption file.
+const maxUploadBytes = 10 << 20
+
+// FetchCaptions downloads the captions of a video and converts them into a transcript.
+func FetchCaptions(ctx context.Context, videoID, language string) (*models.Transcript, error) {
+	data, trackLanguage, err := yt_video.DownloadCaptions(ctx, videoID, language)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := Parse(data, FormatVTT)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse downloaded captions: %w", err)
+	}
+	return &models.Transcript{VideoID: videoID, Source: SourceCaptions, Language: trackLanguage, Segments: segments}, nil
+}
+
+// Transcript stores the transcript of a run for transcript-aware analysis.
+// POST with a multipart 'file' (SRT or WebVTT) uploads one, POST with
+// fetch=true downloads the video's captions, and GET returns the stored
+// transcript.
+func Transcript(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		trackingID := r.URL.Query().Get("trackingId")
+		if trackingID == "" {
+			shared.Logger.Warn("Missing 'trackingId' query parameter")
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'trackingId' query parameter")
+			return
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		switch r.Method {
+		case http.MethodGet:
+			transcript, err := shared.LoadTranscript(ctx, cfg.GCSBucketName, trackingID)
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "No transcript stored for this tracking ID")
+				return
+			}
+			if err != nil {
+				shared.Logger.Error("Failed to load transcript", "error", err, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to load transcript")
+				return
+			}
+			shared.JSONResponse(w, trackingID, http.StatusOK, transcript)
+			return
+		case http.MethodPost:
+		default:
+			shared.JSONErrorResponse(w, trackingID, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		videoData, err := shared.LoadVideoData(ctx, cfg.GCSBucketName, trackingID)
+		if err != nil {
+			shared.Logger.Error("could not load raw data from GCS", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "No video data stored for this tracking ID")
+			return
+		}
+
+		var transcript *models.Transcript
+		if r.URL.Query().Get("fetch") == "true" {
+			transcript, err = FetchCaptions(ctx, videoData.ID, r.URL.Query().Get("language"))
+			switch {
+			case errors.Is(err, yt_video.ErrNoCaptions):
+				shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, err.Error())
+				return
+			case errors.Is(err, yt_video.ErrCaptionsForbidden):
+				shared.JSONErrorResponse(w, trackingID, http.StatusForbidden, err.Error()+"; upload an SRT or WebVTT file instead")
+				return
+			case err != nil:
+				shared.Logger.Error("Failed to fetch captions", "error", err, "videoId", videoData.ID, "trackingId", trackingID)
+				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to fetch captions")
+				return
+			}
+		} else {
+			r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+			if err := r.ParseMultipartForm(maxUploadBytes); err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, fmt.Sprintf("Invalid multipart form: %v", err))
+				return
+			}
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'file' form field or 'fetch=true' query parameter")
+				return
+			}
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Failed to read uploaded file")
+				return
+			}
+			format := strings.ToLower(r.FormValue("format"))
+			if format == "" {
+				format = DetectFormat(header.Filename)
+			}
+			segments, err := Parse(data, format)
+			if err != nil {
+				shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, fmt.Sprintf("Failed to parse captions: %v", err))
+				return
+			}
+			transcript = &models.Transcript{VideoID: videoData.ID, Source: SourceUpload, Language: r.FormValue("language"), Segments: segments}
+		}
+
+		if err := shared.SaveTranscript(ctx, cfg.GCSBucketName, trackingID, transcript); err != nil {
+			shared.Logger.Error("Failed to upload transcript to GCS", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to save transcript to GCS")
+			return
+		}
+		shared.Logger.Info("Stored transcript", "source", transcript.Source, "segments", len(transcript.Segments), "videoId", videoData.ID, "trackingId", trackingID)
+
+		shared.JSONResponse(w, trackingID, http.StatusOK, models.APIResponse{
+			TrackingID:     trackingID,
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "success",
+			Message:        fmt.Sprintf("Stored a transcript with %d segments from %s.", len(transcript.Segments), transcript.Source),
+			NextActionURI:  fmt.Sprintf("/magic?trackingId=%s&transcript=true", trackingID),
+		})
+	}
+}
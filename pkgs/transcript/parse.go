@@ -0,0 +1,116 @@
+package transcript
+
+import (
+	"app/pkgs/models"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+)
+
+const (
+	SourceUpload   = "upload"
+	SourceCaptions = "captions"
+)
+
+var (
+	// cueTimePattern matches SRT (00:01:02,500) and WebVTT (01:02.500 or
+	// 00:01:02.500) cue times.
+	cueTimePattern = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{2})[.,](\d{1,3})$`)
+	tagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// DetectFormat guesses the caption format from a file name, defaulting to SRT.
+func DetectFormat(fileName string) string {
+	if strings.EqualFold(filepath.Ext(fileName), ".vtt") {
+		return FormatVTT
+	}
+	return FormatSRT
+}
+
+func parseCueTime(value string) (float64, error) {
+	m := cueTimePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("invalid cue time %q", value)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.Atoi(m[3])
+	millis, _ := strconv.Atoi(m[4] + strings.Repeat("0", 3-len(m[4])))
+	return float64(hours*3600+minutes*60+seconds) + float64(millis)/1000, nil
+}
+
+// Parse reads SRT or WebVTT captions into transcript segments. Both formats
+// are read as blocks separated by blank lines with a "start --> end" timing
+// line followed by the text. Markup tags are removed and repeated lines, which
+// automatic captions produce while text scrolls, are dropped.
+func Parse(data []byte, format string) ([]models.TranscriptSegment, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	switch format {
+	case FormatVTT:
+		if !strings.HasPrefix(text, "WEBVTT") {
+			return nil, errors.New("WebVTT file must start with WEBVTT")
+		}
+	case FormatSRT:
+	default:
+		return nil, fmt.Errorf("unknown caption format %q, expected srt or vtt", format)
+	}
+
+	var segments []models.TranscriptSegment
+	lastLine := ""
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		// Blocks without timing are headers, NOTE, STYLE and REGION blocks.
+		if timing < 0 {
+			continue
+		}
+
+		times := strings.SplitN(lines[timing], "-->", 2)
+		start, err := parseCueTime(times[0])
+		if err != nil {
+			return nil, err
+		}
+		// WebVTT cue settings follow the end time.
+		endFields := strings.Fields(times[1])
+		if len(endFields) == 0 {
+			return nil, fmt.Errorf("missing end time in %q", lines[timing])
+		}
+		end, err := parseCueTime(endFields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var cueText []string
+		for _, line := range lines[timing+1:] {
+			line = strings.TrimSpace(tagPattern.ReplaceAllString(line, ""))
+			if line == "" || line == lastLine {
+				continue
+			}
+			lastLine = line
+			cueText = append(cueText, line)
+		}
+		if len(cueText) == 0 {
+			continue
+		}
+		segments = append(segments, models.TranscriptSegment{Start: start, End: end, Text: strings.Join(cueText, " ")})
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("no caption cues found")
+	}
+	return segments, nil
+}
@@ -0,0 +1,355 @@
+package triggers
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/pipeline"
+	"app/pkgs/shared"
+	"app/pkgs/webhooks"
+	"app/pkgs/yt_video"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxEventBytes matches the maximum Pub/Sub message size.
+const maxEventBytes = 10 << 20
+
+const (
+	TriggerPubSub     = "pubsub"
+	TriggerCloudEvent = "cloudevent"
+)
+
+// event is a trigger normalized from any of the supported delivery formats.
+type event struct {
+	ID         string
+	Source     string
+	Trigger    string
+	Data       []byte
+	Attributes map[string]string
+}
+
+// markerObjectName returns the GCS object used to deduplicate an event. The ID
+// is hashed because message IDs are not guaranteed to be valid object names.
+func markerObjectName(e *event) string {
+	sum := sha256.Sum256([]byte(e.Trigger + "\n" + e.Source + "\n" + e.ID))
+	return fmt.Sprintf("events/%s.json", hex.EncodeToString(sum[:]))
+}
+
+// parseEvent detects the delivery format of a request: CloudEvents in binary
+// mode (ce-* headers), CloudEvents in structured mode
+// (application/cloudevents+json) or a Pub/Sub push envelope.
+func parseEvent(r *http.Request, body []byte) (*event, error) {
+	if specVersion := r.Header.Get("ce-specversion"); specVersion != "" {
+		e := &event{
+			ID:      r.Header.Get("ce-id"),
+			Source:  r.Header.Get("ce-source"),
+			Trigger: TriggerCloudEvent,
+			Data:    body,
+		}
+		if e.ID == "" || e.Source == "" {
+			return nil, errors.New("binary mode CloudEvent is missing the ce-id or ce-source header")
+		}
+		return unwrapPubSubData(e), nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/cloudevents+json" {
+		var ce models.CloudEvent
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, fmt.Errorf("invalid structured mode CloudEvent: %w", err)
+		}
+		if ce.SpecVersion == "" || ce.ID == "" || ce.Source == "" {
+			return nil, errors.New("structured mode CloudEvent is missing specversion, id or source")
+		}
+		e := &event{ID: ce.ID, Source: ce.Source, Trigger: TriggerCloudEvent, Data: ce.DataBase64}
+		if len(ce.Data) > 0 {
+			e.Data = ce.Data
+		}
+		return unwrapPubSubData(e), nil
+	}
+
+	var envelope models.PubSubPushEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid Pub/Sub push envelope: %w", err)
+	}
+	messageID := envelope.Message.MessageID
+	if messageID == "" {
+		messageID = envelope.Message.MessageIDv2
+	}
+	if messageID == "" {
+		return nil, errors.New("Pub/Sub push envelope is missing message.messageId")
+	}
+	return &event{
+		ID:         messageID,
+		Source:     envelope.Subscription,
+		Trigger:    TriggerPubSub,
+		Data:       envelope.Message.Data,
+		Attributes: envelope.Message.Attributes,
+	}, nil
+}
+
+// unwrapPubSubData replaces the data of a CloudEvent with the inner message
+// when the event was produced by Eventarc from a Pub/Sub topic.
+func unwrapPubSubData(e *event) *event {
+	var envelope models.PubSubPushEnvelope
+	if err := json.Unmarshal(e.Data, &envelope); err != nil {
+		return e
+	}
+	if envelope.Message.MessageID == "" && envelope.Message.MessageIDv2 == "" {
+		return e
+	}
+	e.Data = envelope.Message.Data
+	e.Attributes = envelope.Message.Attributes
+	return e
+}
+
+// parseTrigger reads the video reference from the event data and attributes.
+// The data may be a JSON object, a JSON string or plain text containing a video
+// ID or URL.
+func parseTrigger(e *event) (models.VideoTrigger, error) {
+	var trigger models.VideoTrigger
+	data := strings.TrimSpace(string(e.Data))
+	switch {
+	case strings.HasPrefix(data, "{"):
+		if err := json.Unmarshal([]byte(data), &trigger); err != nil {
+			return trigger, fmt.Errorf("could not decode event data: %w", err)
+		}
+	case strings.HasPrefix(data, `"`):
+		if err := json.Unmarshal([]byte(data), &trigger.URL); err != nil {
+			return trigger, fmt.Errorf("could not decode event data: %w", err)
+		}
+	default:
+		trigger.URL = data
+	}
+
+	if trigger.VideoID == "" {
+		trigger.VideoID = e.Attributes["videoId"]
+	}
+	if trigger.URL == "" {
+		trigger.URL = e.Attributes["url"]
+	}
+	if trigger.CallbackURL == "" {
+		trigger.CallbackURL = e.Attributes["callbackUrl"]
+	}
+
+	ref := trigger.VideoID
+	if ref == "" {
+		ref = trigger.URL
+	}
+	if ref == "" {
+		return trigger, errors.New("event does not carry a video ID or URL")
+	}
+	videoID, err := yt_video.ParseVideoRef(ref)
+	if err != nil {
+		return trigger, fmt.Errorf("invalid video reference %q: %w", ref, err)
+	}
+	trigger.VideoID = videoID
+
+	if trigger.CallbackURL != "" {
+		if err := webhooks.ValidateURL(trigger.CallbackURL); err != nil {
+			return trigger, err
+		}
+	}
+	return trigger, nil
+}
+
+// claimLease is how long a claimed marker blocks redeliveries. A claim older
+// than that belongs to a run whose instance was recycled, so the run may start
+// again. It exceeds the longest expected pipeline run.
+const claimLease = 60 * time.Minute
+
+// claimMarker claims the marker of an event for a new run. It returns the
+// claimed marker, or the existing marker and false when the event must not
+// start a run: it is done, or another delivery holds a live claim. Failed
+// runs, expired claims and unreadable markers are reclaimed, with a
+// generation precondition so only one redelivery wins.
+func claimMarker(ctx context.Context, cfg *models.AppConfig, objectName string, marker models.EventMarker) (models.EventMarker, bool, error) {
+	markerJSON, err := json.Marshal(marker)
+	if err != nil {
+		return marker, false, fmt.Errorf("could not marshal event marker: %w", err)
+	}
+	created, err := shared.CreateGCSObjectIfAbsent(ctx, cfg.GCSBucketName, objectName, markerJSON)
+	if err != nil || created {
+		return marker, created, err
+	}
+
+	existingJSON, generation, err := shared.GetFileWithGenerationFromGCS(ctx, cfg.GCSBucketName, objectName)
+	if err != nil {
+		return marker, false, fmt.Errorf("could not read event marker: %w", err)
+	}
+	var existing models.EventMarker
+	if err := json.Unmarshal(existingJSON, &existing); err != nil {
+		shared.Logger.Warn("Reclaiming unreadable event marker", "error", err, "eventId", marker.EventID, "object", objectName)
+	} else {
+		switch {
+		case existing.Status == "" || existing.Status == models.EventDone:
+			return existing, false, nil
+		case existing.Status == models.EventClaimed && time.Since(existing.ClaimedAt) < claimLease:
+			return existing, false, nil
+		}
+		marker.ReceivedAt = existing.ReceivedAt
+		marker.Attempts = existing.Attempts + 1
+		shared.Logger.Info("Reclaiming event marker", "status", existing.Status, "previousTrackingId", existing.TrackingID, "eventId", marker.EventID)
+	}
+
+	if markerJSON, err = json.Marshal(marker); err != nil {
+		return marker, false, fmt.Errorf("could not marshal event marker: %w", err)
+	}
+	replaced, err := shared.ReplaceGCSObjectIfGeneration(ctx, cfg.GCSBucketName, objectName, markerJSON, generation)
+	if err != nil {
+		return marker, false, err
+	}
+	if !replaced {
+		// Another redelivery reclaimed the marker first and holds a live claim.
+		existing.Status = models.EventClaimed
+		return existing, false, nil
+	}
+	return marker, true, nil
+}
+
+// HandleEvent runs the pipeline for a Pub/Sub push delivery or CloudEvent.
+// Requests that are not a recognizable event are rejected with 400. Events that
+// cannot be processed are acknowledged with 200 so they are not redelivered.
+// A new event is acknowledged with 202 once its marker is claimed, and the run
+// continues in the background and records its result on the marker.
+// Redeliveries of completed or running events are acknowledged with 200
+// without starting a run; a redelivery of a failed or abandoned run starts it
+// again.
+func HandleEvent(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		if r.Method != http.MethodPost {
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxEventBytes))
+		if err != nil {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+
+		e, err := parseEvent(r, body)
+		if err != nil {
+			shared.Logger.Warn("Rejected malformed event", "error", err)
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, err.Error())
+			return
+		}
+
+		trigger, err := parseTrigger(e)
+		if err != nil {
+			shared.Logger.Warn("Acknowledging event without a usable video reference", "error", err, "eventId", e.ID, "source", e.Source, "trigger", e.Trigger)
+			shared.JSONResponse(w, "", http.StatusOK, models.APIResponse{
+				ProcessingTime: time.Since(startTime).String(),
+				Status:         "ignored",
+				Message:        err.Error(),
+			})
+			return
+		}
+
+		// Callbacks are only sent signed. An event that asks for one is
+		// rejected until WEBHOOK_SIGNING_SECRET is set rather than run without
+		// its callback.
+		if trigger.CallbackURL != "" {
+			if err := webhooks.ValidateCallbackURL(cfg, trigger.CallbackURL); err != nil {
+				shared.Logger.Warn("Rejected event with callback URL", "error", err, "eventId", e.ID, "source", e.Source)
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		now := time.Now()
+		marker := models.EventMarker{
+			EventID:    e.ID,
+			Source:     e.Source,
+			Trigger:    e.Trigger,
+			VideoID:    trigger.VideoID,
+			TrackingID: uuid.New().String(),
+			ReceivedAt: now,
+			Status:     models.EventClaimed,
+			ClaimedAt:  now,
+			Attempts:   1,
+		}
+
+		// Claiming the marker is atomic across instances, so only one
+		// delivery of a message ID runs at a time. Storage errors are returned
+		// as 500 to have the event redelivered.
+		objectName := markerObjectName(e)
+		marker, claimed, err := claimMarker(r.Context(), cfg, objectName, marker)
+		if err != nil {
+			shared.Logger.Error("Failed to claim event marker", "error", err, "eventId", e.ID, "object", objectName)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to store event marker")
+			return
+		}
+		if !claimed {
+			status, message := "duplicate", fmt.Sprintf("Event %s was already processed", e.ID)
+			if marker.Status == models.EventClaimed {
+				status, message = "in_progress", fmt.Sprintf("Event %s is already being processed", e.ID)
+			}
+			shared.Logger.Info("Acknowledging duplicate event", "eventId", e.ID, "source", e.Source, "status", marker.Status, "trackingId", marker.TrackingID)
+			shared.JSONResponse(w, marker.TrackingID, http.StatusOK, models.APIResponse{
+				TrackingID:     marker.TrackingID,
+				ProcessingTime: time.Since(startTime).String(),
+				Status:         status,
+				Message:        message,
+				NextActionURI:  fmt.Sprintf("/history?videoId=%s", marker.VideoID),
+			})
+			return
+		}
+
+		req := pipeline.Request{
+			VideoID:     trigger.VideoID,
+			Trigger:     e.Trigger,
+			CallbackURL: trigger.CallbackURL,
+			TrackingID:  marker.TrackingID,
+		}
+		shared.Logger.Info("Accepted event", "eventId", e.ID, "source", e.Source, "trigger", e.Trigger, "videoId", trigger.VideoID, "trackingId", marker.TrackingID, "attempt", marker.Attempts)
+		// The event is acknowledged right away and the run continues after the
+		// response, so it must not be tied to the request context.
+		go runEvent(context.WithoutCancel(r.Context()), cfg, req, objectName, marker)
+
+		shared.JSONResponse(w, marker.TrackingID, http.StatusAccepted, models.APIResponse{
+			TrackingID:     marker.TrackingID,
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "accepted",
+			Message:        fmt.Sprintf("Started pipeline for video %s", trigger.VideoID),
+			NextActionURI:  fmt.Sprintf("/history?videoId=%s", marker.VideoID),
+		})
+	}
+}
+
+// runEvent runs the pipeline for a claimed event and records the result on its
+// marker. A marker that cannot be updated keeps its claim until claimLease
+// expires, after which a redelivery of the event runs it again.
+func runEvent(ctx context.Context, cfg *models.AppConfig, req pipeline.Request, objectName string, marker models.EventMarker) {
+	_, runErr := pipeline.Run(ctx, cfg, req, nil)
+	if runErr != nil {
+		shared.Logger.Error("Event-triggered pipeline run failed", "error", runErr, "eventId", marker.EventID, "videoId", req.VideoID, "trackingId", req.TrackingID)
+	}
+
+	marker.FinishedAt = time.Now()
+	marker.Status = models.EventDone
+	if runErr != nil {
+		marker.Status = models.EventFailed
+		marker.Error = runErr.Error()
+	}
+	markerJSON, err := json.Marshal(marker)
+	if err == nil {
+		err = shared.UploadToGCS(ctx, cfg.GCSBucketName, objectName, markerJSON)
+	}
+	if err != nil {
+		shared.Logger.Error("Failed to record the result on the event marker", "error", err, "eventId", marker.EventID, "trackingId", marker.TrackingID)
+	}
+}
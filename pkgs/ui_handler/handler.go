@@ -1,19 +1,13 @@
 package ui_handler
 
 import (
-	"app/pkgs/bq_ingest"
-	"app/pkgs/gemini_magic"
-	"app/pkgs/models"
+	"app/pkgs/pipeline"
 	"app/pkgs/shared"
+	"app/pkgs/webhooks"
 	"app/pkgs/yt_video"
-	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"net/http/httptest"
-	"net/url"
-	"strings"
 )
 
 // Info serves the API information page.
@@ -31,6 +25,10 @@ func Info(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "   - Retrieves the JSON file from GCS using the 'trackingId'.")
 	fmt.Fprintln(w, "   - Sends the data to the Gemini API for a comprehensive marketing and sentiment analysis.")
 	fmt.Fprintln(w, "   - Saves the resulting analysis as a new JSON file to GCS: gs://<bucket>/<trackingId>_analyzed.json")
+	fmt.Fprintln(w, "   - Add '&packaging=true' to critique the title and thumbnail with the multimodal model.")
+	fmt.Fprintln(w, "   - Suspected bot comments are excluded from the sentiment and themes and reported separately; add '&includeBots=true' to keep them.")
+	fmt.Fprintln(w, "   - Add '&clusters=true' to cluster the comments into themes and group questions by their embeddings (needs EMBEDDING_MODEL).")
+	fmt.Fprintln(w, "   - Each comment's language is detected and sentiment is broken down per language; add '&language=sv' to write the report in another language.")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "3. /ingest?trackingId=<TRACKING_ID>")
 	fmt.Fprintln(w, "   - Reads both the raw data (<trackingId>.json) and the analyzed data (<trackingId>_analyzed.json) from GCS.")
@@ -40,6 +38,102 @@ func Info(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "4. /ui")
 	fmt.Fprintln(w, "   - Serves a web interface to run the full analysis pipeline.")
+	fmt.Fprintln(w, "   - Its '/ui/process' stream accepts the 'packaging', 'includeBots', 'language', 'aspects' and 'clusters' parameters of /magic and passes them to the analysis.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "5. /rollup?trackingIds=<TRACKING_ID>,<TRACKING_ID>,...")
+	fmt.Fprintln(w, "   - Reads the raw and analyzed files for several videos from the same channel.")
+	fmt.Fprintln(w, "   - Generates a channel report with recurring themes, persistent criticism, a sentiment trend and best/worst performers.")
+	fmt.Fprintln(w, "   - Saves the report to GCS: gs://<bucket>/<reportId>_channel_report.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "6. /ingest/rollup?reportId=<REPORT_ID>")
+	fmt.Fprintln(w, "   - Ingests the channel report into the 'channel_reports' table in BigQuery.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "7. /compare?trackingIds=<TRACKING_ID>,<TRACKING_ID>,...")
+	fmt.Fprintln(w, "   - Aligns the audience analysis, key themes and engagement ratios of two or more analyzed videos.")
+	fmt.Fprintln(w, "   - Returns a comparative narrative with shared themes and unique praise/criticism per video as JSON.")
+	fmt.Fprintln(w, "   - Saves the comparison to GCS: gs://<bucket>/<comparisonId>_comparison.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "8. /watchlist")
+	fmt.Fprintln(w, "   - GET lists the watched videos and channels, POST adds an entry, DELETE ?id=<ENTRY_ID> removes one.")
+	fmt.Fprintln(w, "   - Entries are re-analyzed on a schedule (default: hourly for 48h, then daily for 30 days).")
+	fmt.Fprintln(w, "   - The watchlist is stored in GCS: gs://<bucket>/watchlist.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "9. /watchlist/run")
+	fmt.Fprintln(w, "   - Runs the full pipeline for up to 10 due watchlist entries and lists the deferred ones. Intended for Cloud Scheduler.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "10. /history?videoId=<YOUTUBE_VIDEO_ID>")
+	fmt.Fprintln(w, "   - Lists every pipeline run of a video with its tracking ID, trigger and status.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "11. /stats/poll[?videoIds=<ID>,<ID>,...]")
+	fmt.Fprintln(w, "   - Snapshots view, like and comment counts into the 'video_stats_snapshots' table in BigQuery.")
+	fmt.Fprintln(w, "   - Polls every active watchlist video when 'videoIds' is omitted. Intended for Cloud Scheduler.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "12. /stats?videoId=<YOUTUBE_VIDEO_ID>[&hours=168]")
+	fmt.Fprintln(w, "   - Returns the statistics snapshots of a video with views/hour, likes/hour and comments/hour.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "13. /trend?videoId=<YOUTUBE_VIDEO_ID>[&threshold=0.1]")
+	fmt.Fprintln(w, "   - Returns the audience sentiment of every successful run of a video in run order.")
+	fmt.Fprintln(w, "   - Flags significant shifts between runs and lists the key themes that appeared or disappeared.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "14. /alerts/rules")
+	fmt.Fprintln(w, "   - GET returns the alert rules, PUT replaces them. Stored in GCS: gs://<bucket>/alert_rules.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "15. /alerts/evaluate?trackingId=<TRACKING_ID>")
+	fmt.Fprintln(w, "   - Evaluates the alert rules for an analysis and notifies the configured webhook, Slack and email targets.")
+	fmt.Fprintln(w, "   - Runs automatically after the analysis step of every pipeline run.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "16. /webhooks")
+	fmt.Fprintln(w, "   - GET lists the registered webhooks, POST {\"url\": ..., \"secret\": ...} registers one, DELETE ?id=<WEBHOOK_ID> removes one.")
+	fmt.Fprintln(w, "   - Every stage (fetch, analyze, ingest) and the pipeline as a whole POST a signed JSON event when they complete or fail.")
+	fmt.Fprintln(w, "   - A per-run callback can be passed as '/ui/process?url=...&callbackUrl=<URL>' when WEBHOOK_SIGNING_SECRET is set.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "17. /webhooks/deliveries?trackingId=<TRACKING_ID>")
+	fmt.Fprintln(w, "   - Lists the webhook deliveries of a run with their status and number of attempts.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "18. /events")
+	fmt.Fprintln(w, "   - Accepts Pub/Sub push envelopes and CloudEvents (binary and structured mode) carrying a video ID or URL and starts the pipeline.")
+	fmt.Fprintln(w, "   - Acknowledges the event with 202 as soon as the run has started and records the result of the run on the event's marker; redeliveries do not start a second run.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "19. /import")
+	fmt.Fprintln(w, "   - POST a multipart form with a CSV, JSONL or YouTube Takeout 'file' to store it as video data for /magic and /ingest.")
+	fmt.Fprintln(w, "   - Optional fields: format, mapping (JSON column mapping), video_id, title, description, channel_id, channel_title, fetch_metadata.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "20. /livechat?videoId=<VIDEO_ID>&minutes=<N>&analyze=true")
+	fmt.Fprintln(w, "   - Collects the live chat of a livestream or premiere in the background for up to N minutes (default 60).")
+	fmt.Fprintln(w, "   - With analyze=true the chat is analyzed and ingested afterwards; the analysis includes a per-minute sentiment timeline.")
+	fmt.Fprintln(w, "   - '/livechat?trackingId=<TRACKING_ID>' returns the status of a capture.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "21. /transcript?trackingId=<TRACKING_ID>")
+	fmt.Fprintln(w, "   - POST a multipart SRT or WebVTT 'file', or POST with '&fetch=true' to download the video's captions (requires OAuth credentials of the video owner).")
+	fmt.Fprintln(w, "   - GET returns the stored transcript. Analyze with '/magic?trackingId=<TRACKING_ID>&transcript=true' to check questions and criticism against it.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "22. /runs/<TRACKING_ID>/search?q=<QUERY>&limit=<N>")
+	fmt.Fprintln(w, "   - Returns the comments of a run ranked by semantic similarity to the query, with their scores.")
+	fmt.Fprintln(w, "   - The first search of a run embeds its comments and stores the index as gs://<bucket>/<trackingId>_embeddings.bin.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "23. /runs/<TRACKING_ID>/chat?q=<QUESTION>[&sessionId=<SESSION_ID>]")
+	fmt.Fprintln(w, "   - Answers a follow-up question about an analyzed run from its report and the most relevant comments, citing comment IDs.")
+	fmt.Fprintln(w, "   - Streams the answer as server-sent events. Pass the returned 'session_id' to keep the conversation history.")
+	fmt.Fprintln(w, "   - Without 'q', returns the stored session: gs://<bucket>/chat/<trackingId>/<sessionId>.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "24. /runs/<TRACKING_ID>/moderation[?status=open&category=<CATEGORY>&severity=<SEVERITY>]")
+	fmt.Fprintln(w, "   - GET lists the comments flagged for harassment, hate, spam or self-promotion during the analysis, most severe first.")
+	fmt.Fprintln(w, "   - POST {\"comment_ids\": [...], \"status\": \"handled\", \"handled_by\": ...} marks them as handled; status 'open' reopens them.")
+	fmt.Fprintln(w, "   - Review state is stored in GCS: gs://<bucket>/moderation/<trackingId>.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "25. /aspects/<CHANNEL_ID_OR_PROFILE>")
+	fmt.Fprintln(w, "   - GET returns and PUT {\"aspects\": [{\"name\": \"price\", \"description\": ...}]} replaces an aspect taxonomy, e.g. price, battery, audio, host.")
+	fmt.Fprintln(w, "   - /magic tags every comment with the aspects it mentions and their sentiment, and adds an aspect scorecard that /ingest writes to the 'aspects' table.")
+	fmt.Fprintln(w, "   - The taxonomy of the video's channel is used automatically; add '&aspects=<PROFILE>' to /magic to use a profile instead.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "26. /mentions/dictionary")
+	fmt.Fprintln(w, "   - GET returns and PUT {\"entities\": [{\"name\": ..., \"type\": \"brand\", \"aliases\": [...]}]} replaces the dictionary of known brands, products and competitors.")
+	fmt.Fprintln(w, "   - /magic extracts brand, product and competitor mentions from the comments and the description and counts them per entity, with aliases merged by the dictionary.")
+	fmt.Fprintln(w, "   - /ingest writes the counts to the 'mentions' table. The dictionary is stored in GCS: gs://<bucket>/mention_dictionary.json")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "27. /runs/<TRACKING_ID>/questions[?status=open|answered|all]")
+	fmt.Fprintln(w, "   - Lists the questions viewers asked in top-level comments, grouped into clusters with example comment IDs, open clusters by default.")
+	fmt.Fprintln(w, "   - A question counts as answered when the channel owner replied to it or a reply was pinned or hearted.")
 }
 
 // ServeUI serves the main HTML page for the user interface.
@@ -65,87 +159,43 @@ func ProcessHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videoID, err := extractVideoID(youtubeURL)
+	videoID, err := yt_video.ExtractVideoID(youtubeURL)
 	if err != nil {
 		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": err.Error()})
 		return
 	}
 	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": fmt.Sprintf("Extracted Video ID: %s", videoID)})
 
-	ctx := r.Context()
-
-	// ---	Step 1: Fetch YouTube Data ---
-	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": "Step 1/3: Fetching YouTube data..."})
-	youtubeEndpoint := fmt.Sprintf("/youtube?videoId=%s", videoID)
-	var step1Response models.APIResponse
-	if err := callHandler(ctx, yt_video.FetchData(&shared.AppConfig), youtubeEndpoint, &step1Response); err != nil {
-		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": "Step 1 failed: " + err.Error()})
-		return
-	}
-	step1Message := fmt.Sprintf("Step 1/3 succeeded: %s (Tracking ID: %s, Time: %s)", step1Response.Message, step1Response.TrackingID, step1Response.ProcessingTime)
-	sendSSEMessage(w, flusher, map[string]string{"status": "success", "message": step1Message})
-
-	nextAction := step1Response.NextActionURI
-	if nextAction == "" {
-		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": "Error: Step 1 response did not contain a valid next action."})
-		return
+	callbackURL := r.URL.Query().Get("callbackUrl")
+	if callbackURL != "" {
+		if err := webhooks.ValidateCallbackURL(&shared.AppConfig, callbackURL); err != nil {
+			sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": err.Error()})
+			return
+		}
 	}
-	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": fmt.Sprintf("Next action: %s", nextAction)})
 
-	// ---	Step 2: Analyze with Gemini ---
-	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": "Step 2/3: Analyzing data with Gemini..."})
-	var step2Response models.APIResponse
-	if err := callHandler(ctx, gemini_magic.AnalyzeData(&shared.AppConfig), nextAction, &step2Response); err != nil {
-		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": "Step 2 failed: " + err.Error()})
-		return
+	notify := func(status, message string) {
+		sendSSEMessage(w, flusher, map[string]string{"status": status, "message": message})
 	}
-	step2Message := fmt.Sprintf("Step 2/3 succeeded: %s (Time: %s)", step2Response.Message, step2Response.ProcessingTime)
-	sendSSEMessage(w, flusher, map[string]string{"status": "success", "message": step2Message})
-
-	nextAction = step2Response.NextActionURI
-	if nextAction == "" {
-		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": "Error: Step 2 response did not contain a valid next action."})
-		return
+	query := r.URL.Query()
+	req := pipeline.Request{
+		VideoID:     videoID,
+		Trigger:     "ui",
+		CallbackURL: callbackURL,
+		Analyze: pipeline.AnalyzeOptions{
+			Packaging:   query.Get("packaging") == "true",
+			IncludeBots: query.Get("includeBots") == "true",
+			Language:    query.Get("language"),
+			Aspects:     query.Get("aspects"),
+			Clusters:    query.Get("clusters") == "true",
+		},
 	}
-	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": fmt.Sprintf("Next action: %s", nextAction)})
-
-	// ---	Step 3: Ingest into BigQuery ---
-	sendSSEMessage(w, flusher, map[string]string{"status": "processing", "message": "Step 3/3: Ingesting data into BigQuery..."})
-	var step3Response models.APIResponse
-	if err := callHandler(ctx, bq_ingest.IngestData(&shared.AppConfig), nextAction, &step3Response); err != nil {
-		sendSSEMessage(w, flusher, map[string]string{"status": "error", "message": "Step 3 failed: " + err.Error()})
+	trackingID, err := pipeline.Run(r.Context(), &shared.AppConfig, req, notify)
+	if err != nil {
 		return
 	}
-	step3Message := fmt.Sprintf("Step 3/3 succeeded: %s (Time: %s)", step3Response.Message, step3Response.ProcessingTime)
-	sendSSEMessage(w, flusher, map[string]string{"status": "success", "message": step3Message})
-
-	sendSSEMessage(w, flusher, map[string]string{"status": "complete", "message": "All steps completed successfully!"})
-}
-
-// callHandler invokes another HTTP handler in-process, avoiding network overhead.
-func callHandler(ctx context.Context, handler http.HandlerFunc, targetURL string, targetStruct interface{}) error {
-	req := httptest.NewRequest("GET", targetURL, nil)
-	req = req.WithContext(ctx)
-	rr := httptest.NewRecorder()
-
-	handler(rr, req)
-
-	if rr.Code != http.StatusOK {
-		return fmt.Errorf("handler returned non-200 status: %d, body: %s", rr.Code, rr.Body.String())
-	}
 
-	if err := json.Unmarshal(rr.Body.Bytes(), targetStruct); err != nil {
-		return fmt.Errorf("failed to unmarshal handler response: %w. Body: %s", err, rr.Body.String())
-	}
-
-	// Check for application-level success status from the APIResponse
-	if response, ok := targetStruct.(*models.APIResponse); ok {
-		if response.Status != "success" && response.Status != "skipped" {
-			return fmt.Errorf("handler returned application error: %s", response.Message)
-		}
-	}
-
-	return nil
+	sendSSEMessage(w, flusher, map[string]string{"status": "complete", "message": "All steps completed successfully!", "tracking_id": trackingID})
 }
 
 func sendSSEMessage(w http.ResponseWriter, flusher http.Flusher, data interface{}) {
@@ -158,21 +208,3 @@ func sendSSEMessage(w http.ResponseWriter, flusher http.Flusher, data interface{
 	fmt.Fprintf(w, "data: %s\n\n", jsonData)
 	flusher.Flush()
 }
-
-func extractVideoID(youtubeURL string) (string, error) {
-	u, err := url.Parse(youtubeURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid URL: %w", err)
-	}
-	if strings.Contains(u.Host, "youtube.com") {
-		if videoID := u.Query().Get("v"); videoID != "" {
-			return videoID, nil
-		}
-	}
-	if strings.Contains(u.Host, "youtu.be") {
-		if videoID := strings.TrimPrefix(u.Path, "/"); videoID != "" {
-			return videoID, nil
-		}
-	}
-	return "", errors.New("could not find video ID in URL")
-}
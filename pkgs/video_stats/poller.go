@@ -0,0 +1,110 @@
+package video_stats
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"app/pkgs/watchlist"
+	"app/pkgs/yt_video"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+const snapshotsTable = "video_stats_snapshots"
+
+// StartPoller snapshots the statistics of all active watchlist videos every
+// cfg.StatsPollInterval minutes until ctx is cancelled. It does nothing when the
+// interval is not positive; use /stats/poll from Cloud Scheduler instead.
+func StartPoller(ctx context.Context, cfg *models.AppConfig) {
+	if cfg.StatsPollInterval <= 0 {
+		shared.Logger.Info("In-process stats poller disabled", "intervalMinutes", cfg.StatsPollInterval)
+		return
+	}
+	interval := time.Duration(cfg.StatsPollInterval) * time.Minute
+	shared.Logger.Info("Starting in-process stats poller", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			videoIDs, err := watchlist.ActiveVideoIDs(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("Failed to load watched videos for stats poll", "error", err)
+				continue
+			}
+			if _, err := Poll(ctx, cfg, videoIDs); err != nil {
+				shared.Logger.Error("Scheduled stats poll failed", "error", err)
+			}
+		}
+	}
+}
+
+// Poll fetches the current statistics of the given videos and inserts one
+// snapshot per video into the 'video_stats_snapshots' table.
+func Poll(ctx context.Context, cfg *models.AppConfig, videoIDs []string) ([]models.VideoStatsSnapshot, error) {
+	if len(videoIDs) == 0 {
+		return []models.VideoStatsSnapshot{}, nil
+	}
+
+	snapshots, err := yt_video.FetchStatistics(ctx, cfg, videoIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return snapshots, nil
+	}
+
+	client, err := bigquery.NewClient(ctx, cfg.GCPProject)
+	if err != nil {
+		return nil, fmt.Errorf("could not create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	inserter := client.Dataset(cfg.BQDataset).Table(snapshotsTable).Inserter()
+	if err := inserter.Put(ctx, snapshots); err != nil {
+		return nil, fmt.Errorf("could not insert stats snapshots into BigQuery: %w", err)
+	}
+	shared.Logger.Info("Inserted stats snapshots", "count", len(snapshots))
+	return snapshots, nil
+}
+
+// PollStats performs a single stats poll. Without a 'videoIds' parameter it
+// polls every active watchlist video; it is intended for Cloud Scheduler.
+func PollStats(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		ctx := r.Context()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		videoIDs := shared.SplitIDs(r.URL.Query().Get("videoIds"))
+		if len(videoIDs) == 0 {
+			var err error
+			videoIDs, err = watchlist.ActiveVideoIDs(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load watched videos", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load watchlist")
+				return
+			}
+		}
+
+		snapshots, err := Poll(ctx, cfg, videoIDs)
+		if err != nil {
+			shared.Logger.Error("Stats poll failed", "error", err)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to poll video statistics")
+			return
+		}
+
+		response := models.APIResponse{
+			ProcessingTime: time.Since(startTime).String(),
+			Status:         "success",
+			Message:        fmt.Sprintf("Stored %d statistics snapshots.", len(snapshots)),
+		}
+		shared.JSONResponse(w, "", http.StatusOK, response)
+	}
+}
@@ -0,0 +1,125 @@
+package video_stats
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+)
+
+const defaultVelocityWindowHours = 168
+
+func loadSnapshots(ctx context.Context, cfg *models.AppConfig, videoID string, hours int) ([]models.VideoStatsSnapshot, error) {
+	client, err := bigquery.NewClient(ctx, cfg.GCPProject)
+	if err != nil {
+		return nil, fmt.Errorf("could not create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	queryStr := fmt.Sprintf(
+		"SELECT video_id, snapshot_at, view_count, like_count, comment_count FROM `%s.%s.%s` "+
+			"WHERE video_id = @videoID AND snapshot_at >= TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL @hours HOUR) "+
+			"ORDER BY snapshot_at",
+		cfg.GCPProject, cfg.BQDataset, snapshotsTable,
+	)
+	q := client.Query(queryStr)
+	q.Parameters = []bigquery.QueryParameter{
+		{Name: "videoID", Value: videoID},
+		{Name: "hours", Value: hours},
+	}
+
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not query table %s: %w", snapshotsTable, err)
+	}
+
+	var snapshots []models.VideoStatsSnapshot
+	for {
+		var snapshot models.VideoStatsSnapshot
+		err := it.Next(&snapshot)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query result from table %s: %w", snapshotsTable, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func perHour(delta int64, hours float64) float64 {
+	if hours <= 0 {
+		return 0
+	}
+	return float64(delta) / hours
+}
+
+// computeVelocity derives per-hour growth rates from snapshots ordered by time.
+// Each point carries the rate since the previous snapshot; the top-level rates
+// cover the most recent interval.
+func computeVelocity(videoID string, snapshots []models.VideoStatsSnapshot) models.VideoVelocity {
+	velocity := models.VideoVelocity{
+		VideoID:       videoID,
+		SnapshotCount: len(snapshots),
+		Series:        []models.VelocityPoint{},
+	}
+	for i, snapshot := range snapshots {
+		point := models.VelocityPoint{
+			SnapshotAt:   snapshot.SnapshotAt,
+			ViewCount:    snapshot.ViewCount,
+			LikeCount:    snapshot.LikeCount,
+			CommentCount: snapshot.CommentCount,
+		}
+		if i > 0 {
+			prev := snapshots[i-1]
+			hours := snapshot.SnapshotAt.Sub(prev.SnapshotAt).Hours()
+			point.ViewsPerHour = perHour(snapshot.ViewCount-prev.ViewCount, hours)
+			point.LikesPerHour = perHour(snapshot.LikeCount-prev.LikeCount, hours)
+			point.CommentsPerHour = perHour(snapshot.CommentCount-prev.CommentCount, hours)
+		}
+		velocity.Series = append(velocity.Series, point)
+	}
+	if n := len(velocity.Series); n > 1 {
+		latest := velocity.Series[n-1]
+		velocity.ViewsPerHour = latest.ViewsPerHour
+		velocity.LikesPerHour = latest.LikesPerHour
+		velocity.CommentsPerHour = latest.CommentsPerHour
+	}
+	return velocity
+}
+
+// Velocity returns the statistics snapshots of a video together with derived
+// views/hour, likes/hour and comments/hour.
+func Velocity(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		videoID := r.URL.Query().Get("videoId")
+		if videoID == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'videoId' query parameter")
+			return
+		}
+		hours := defaultVelocityWindowHours
+		if hoursStr := r.URL.Query().Get("hours"); hoursStr != "" {
+			parsed, err := strconv.Atoi(hoursStr)
+			if err != nil || parsed <= 0 {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'hours' must be a positive integer")
+				return
+			}
+			hours = parsed
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "videoId", videoID)
+
+		snapshots, err := loadSnapshots(r.Context(), cfg, videoID, hours)
+		if err != nil {
+			shared.Logger.Error("could not load stats snapshots", "error", err, "videoId", videoID)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load statistics snapshots")
+			return
+		}
+		shared.JSONResponse(w, "", http.StatusOK, computeVelocity(videoID, snapshots))
+	}
+}
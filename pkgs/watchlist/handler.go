@@ -0,0 +1,147 @@
+package watchlist
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// addRequest is the body accepted by POST /watchlist.
+type addRequest struct {
+	Kind       string                 `json:"kind"`
+	TargetID   string                 `json:"target_id"`
+	Schedule   []models.SchedulePhase `json:"schedule"`
+	VideoCount int                    `json:"video_count"`
+}
+
+// Manage lists (GET), adds (POST) and removes (DELETE) watchlist entries.
+func Manage(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		switch r.Method {
+		case http.MethodGet:
+			watchlist, err := Load(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load watchlist", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load watchlist")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, watchlist)
+
+		case http.MethodPost:
+			var req addRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if req.Kind != KindVideo && req.Kind != KindChannel {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "'kind' must be 'video' or 'channel'")
+				return
+			}
+			if req.TargetID == "" {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'target_id'")
+				return
+			}
+			if len(req.Schedule) == 0 {
+				req.Schedule = DefaultSchedule
+			}
+			if err := validateSchedule(req.Schedule); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid schedule: "+err.Error())
+				return
+			}
+			if req.Kind == KindChannel && req.VideoCount <= 0 {
+				req.VideoCount = defaultChannelVideoCount
+			}
+
+			entry := &models.WatchlistEntry{
+				ID:         uuid.New().String(),
+				Kind:       req.Kind,
+				TargetID:   req.TargetID,
+				Schedule:   req.Schedule,
+				VideoCount: req.VideoCount,
+				CreatedAt:  time.Now(),
+			}
+			errDuplicate := errors.New("duplicate entry")
+			_, err := update(ctx, cfg, func(watchlist *models.Watchlist) error {
+				if findByTarget(watchlist, req.Kind, req.TargetID) != nil {
+					return errDuplicate
+				}
+				watchlist.Entries = append(watchlist.Entries, entry)
+				return nil
+			})
+			if errors.Is(err, errDuplicate) {
+				shared.JSONErrorResponse(w, "", http.StatusConflict, fmt.Sprintf("%s %s is already on the watchlist", req.Kind, req.TargetID))
+				return
+			}
+			if err != nil {
+				shared.Logger.Error("could not update watchlist", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to update watchlist")
+				return
+			}
+			entry.NextRunAt = nextRunAt(entry)
+			shared.Logger.Info("Added watchlist entry", "entryId", entry.ID, "kind", entry.Kind, "targetId", entry.TargetID)
+			shared.JSONResponse(w, "", http.StatusCreated, entry)
+
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'id' query parameter")
+				return
+			}
+			removed := false
+			_, err := update(ctx, cfg, func(watchlist *models.Watchlist) error {
+				kept := watchlist.Entries[:0]
+				for _, entry := range watchlist.Entries {
+					if entry.ID == id {
+						removed = true
+						continue
+					}
+					kept = append(kept, entry)
+				}
+				watchlist.Entries = kept
+				return nil
+			})
+			if err != nil {
+				shared.Logger.Error("could not update watchlist", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to update watchlist")
+				return
+			}
+			if !removed {
+				shared.JSONErrorResponse(w, "", http.StatusNotFound, "Watchlist entry not found")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, models.APIResponse{Status: "success", Message: fmt.Sprintf("Removed watchlist entry %s.", id)})
+
+		default:
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// RunWatchlist performs a single scheduler pass. It is intended to be called by
+// Cloud Scheduler when the in-process scheduler is disabled.
+func RunWatchlist(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		summary, err := RunDue(r.Context(), cfg)
+		if errors.Is(err, ErrAlreadyRunning) {
+			shared.JSONErrorResponse(w, "", http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			shared.Logger.Error("Watchlist run failed", "error", err)
+			shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to run watchlist")
+			return
+		}
+		shared.JSONResponse(w, "", http.StatusOK, summary)
+	}
+}
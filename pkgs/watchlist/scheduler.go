@@ -0,0 +1,206 @@
+package watchlist
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/pipeline"
+	"app/pkgs/shared"
+	"app/pkgs/yt_video"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrAlreadyRunning is returned by RunDue when another pass is still in progress.
+var ErrAlreadyRunning = errors.New("a watchlist run is already in progress")
+
+// runMu prevents the in-process scheduler and the one-shot endpoint from running passes concurrently.
+var runMu sync.Mutex
+
+const (
+	// maxRunsPerPass bounds the pipeline runs of one pass, so a pass triggered
+	// by a request finishes within its deadline. Due videos beyond it are
+	// deferred to the next pass.
+	maxRunsPerPass = 10
+	// runConcurrency is the number of pipeline runs of a pass that run at once.
+	runConcurrency = 3
+)
+
+// StartScheduler runs RunDue every cfg.SchedulerInterval minutes until ctx is
+// cancelled. It does nothing when the interval is not positive, which is the
+// default; deployments that scale to zero should call /watchlist/run from
+// Cloud Scheduler instead.
+func StartScheduler(ctx context.Context, cfg *models.AppConfig) {
+	if cfg.SchedulerInterval <= 0 {
+		shared.Logger.Info("In-process scheduler disabled", "intervalMinutes", cfg.SchedulerInterval)
+		return
+	}
+	interval := time.Duration(cfg.SchedulerInterval) * time.Minute
+	shared.Logger.Info("Starting in-process scheduler", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := RunDue(ctx, cfg); err != nil {
+				shared.Logger.Error("Scheduled watchlist run failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunDue discovers new uploads for watched channels and runs the pipeline for
+// the video entries whose next run time has passed, most overdue first. At
+// most maxRunsPerPass videos are run, runConcurrency at a time; the other due
+// videos are reported as deferred and stay due for the next pass.
+func RunDue(ctx context.Context, cfg *models.AppConfig) (*models.WatchlistRunSummary, error) {
+	if !runMu.TryLock() {
+		return nil, ErrAlreadyRunning
+	}
+	defer runMu.Unlock()
+
+	summary := &models.WatchlistRunSummary{StartedAt: time.Now(), Runs: []models.WatchlistRun{}, DeferredVideos: []string{}}
+
+	discovered, err := discoverChannelUploads(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	summary.DiscoveredVideos = discovered
+
+	watchlist, err := Load(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var due []*models.WatchlistEntry
+	for _, entry := range watchlist.Entries {
+		if next := nextRunAt(entry); !next.IsZero() && !next.After(now) {
+			due = append(due, entry)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return nextRunAt(due[i]).Before(nextRunAt(due[j])) })
+	if len(due) > maxRunsPerPass {
+		for _, entry := range due[maxRunsPerPass:] {
+			summary.DeferredVideos = append(summary.DeferredVideos, entry.TargetID)
+		}
+		due = due[:maxRunsPerPass]
+	}
+
+	runs := make([]models.WatchlistRun, len(due))
+	updateErrs := make([]error, len(due))
+	slots := make(chan struct{}, runConcurrency)
+	var wg sync.WaitGroup
+	for i, entry := range due {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			runs[i], updateErrs[i] = runEntry(ctx, cfg, entry)
+		}()
+	}
+	wg.Wait()
+	summary.Runs = append(summary.Runs, runs...)
+	if err := errors.Join(updateErrs...); err != nil {
+		return nil, err
+	}
+
+	summary.FinishedAt = time.Now()
+	shared.Logger.Info("Watchlist run completed", "discovered", summary.DiscoveredVideos, "runs", len(summary.Runs), "deferred", len(summary.DeferredVideos))
+	return summary, nil
+}
+
+// runEntry runs the pipeline for a due video entry and records the attempt on
+// the watchlist. The returned error is only set when the watchlist could not
+// be updated.
+func runEntry(ctx context.Context, cfg *models.AppConfig, entry *models.WatchlistEntry) (models.WatchlistRun, error) {
+	trackingID, runErr := pipeline.Run(ctx, cfg, pipeline.Request{VideoID: entry.TargetID, Trigger: "scheduler"}, nil)
+	run := models.WatchlistRun{
+		EntryID:    entry.ID,
+		VideoID:    entry.TargetID,
+		TrackingID: trackingID,
+		Status:     "success",
+	}
+	if runErr != nil {
+		run.Status = "error"
+		run.Message = runErr.Error()
+		shared.Logger.Error("Scheduled pipeline run failed", "error", runErr, "videoId", entry.TargetID, "trackingId", trackingID)
+	}
+
+	// A failed run still counts as an attempt so a broken video does not block the schedule.
+	ranAt := time.Now()
+	_, err := update(ctx, cfg, func(w *models.Watchlist) error {
+		if stored := findByID(w, entry.ID); stored != nil {
+			stored.LastRunAt = ranAt
+			stored.RunCount++
+		}
+		return nil
+	})
+	return run, err
+}
+
+// discoverChannelUploads adds a video entry for every recent upload of a
+// watched channel that is not on the watchlist yet and whose schedule, counted
+// from its publish time, has not ended.
+func discoverChannelUploads(ctx context.Context, cfg *models.AppConfig) (int, error) {
+	watchlist, err := Load(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	uploads := make(map[string][]models.ChannelUpload)
+	for _, entry := range watchlist.Entries {
+		if entry.Kind != KindChannel {
+			continue
+		}
+		channelUploads, err := yt_video.LatestUploads(ctx, cfg, entry.TargetID, entry.VideoCount)
+		if err != nil {
+			shared.Logger.Error("Failed to list channel uploads", "error", err, "channelId", entry.TargetID)
+			continue
+		}
+		uploads[entry.ID] = channelUploads
+	}
+	if len(uploads) == 0 {
+		return 0, nil
+	}
+
+	discovered := 0
+	_, err = update(ctx, cfg, func(w *models.Watchlist) error {
+		for channelEntryID, channelUploads := range uploads {
+			channelEntry := findByID(w, channelEntryID)
+			if channelEntry == nil {
+				continue
+			}
+			for _, upload := range channelUploads {
+				if findByTarget(w, KindVideo, upload.VideoID) != nil {
+					continue
+				}
+				entry := &models.WatchlistEntry{
+					ID:          uuid.New().String(),
+					Kind:        KindVideo,
+					TargetID:    upload.VideoID,
+					Schedule:    channelEntry.Schedule,
+					OriginID:    channelEntry.ID,
+					CreatedAt:   time.Now(),
+					PublishedAt: upload.PublishedAt,
+				}
+				// Older uploads of a newly watched channel are past their
+				// schedule and would never run.
+				if nextRunAt(entry).IsZero() {
+					continue
+				}
+				w.Entries = append(w.Entries, entry)
+				discovered++
+			}
+		}
+		return nil
+	})
+	return discovered, err
+}
@@ -0,0 +1,173 @@
+package watchlist
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const watchlistObjectName = "watchlist.json"
+
+const (
+	KindVideo   = "video"
+	KindChannel = "channel"
+)
+
+const defaultChannelVideoCount = 5
+
+// DefaultSchedule re-analyzes a video hourly for its first 48 hours and daily for the following 30 days.
+var DefaultSchedule = []models.SchedulePhase{
+	{Every: "1h", For: "48h"},
+	{Every: "24h", For: "30d"},
+}
+
+// watchlistMu serializes read-modify-write cycles on the watchlist within this instance.
+var watchlistMu sync.Mutex
+
+// parseDuration extends time.ParseDuration with a "d" (day) unit.
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func validateSchedule(schedule []models.SchedulePhase) error {
+	for i, phase := range schedule {
+		every, err := parseDuration(phase.Every)
+		if err != nil || every <= 0 {
+			return fmt.Errorf("phase %d: 'every' must be a positive duration such as '1h' or '1d'", i+1)
+		}
+		length, err := parseDuration(phase.For)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("phase %d: 'for' must be a positive duration such as '48h' or '30d'", i+1)
+		}
+	}
+	return nil
+}
+
+// nextRunAt returns when a video entry is next due. The phases of the schedule
+// run back to back starting at the video's publish time for discovered uploads
+// and at the entry's creation time otherwise; once the last phase has ended
+// the entry is finished and the zero time is returned.
+func nextRunAt(entry *models.WatchlistEntry) time.Time {
+	if entry.Kind != KindVideo {
+		return time.Time{}
+	}
+	phaseStart := entry.CreatedAt
+	if !entry.PublishedAt.IsZero() {
+		phaseStart = entry.PublishedAt
+	}
+	for _, phase := range entry.Schedule {
+		every, err := parseDuration(phase.Every)
+		if err != nil {
+			return time.Time{}
+		}
+		length, err := parseDuration(phase.For)
+		if err != nil {
+			return time.Time{}
+		}
+		phaseEnd := phaseStart.Add(length)
+
+		next := phaseStart
+		if !entry.LastRunAt.IsZero() {
+			next = entry.LastRunAt.Add(every)
+		}
+		if next.Before(phaseStart) {
+			next = phaseStart
+		}
+		if next.Before(phaseEnd) {
+			return next
+		}
+		phaseStart = phaseEnd
+	}
+	return time.Time{}
+}
+
+// Load reads the watchlist from GCS. A missing watchlist is treated as empty.
+func Load(ctx context.Context, cfg *models.AppConfig) (*models.Watchlist, error) {
+	watchlist := &models.Watchlist{Entries: []*models.WatchlistEntry{}}
+	fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, watchlistObjectName)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return watchlist, nil
+		}
+		return nil, fmt.Errorf("could not get watchlist from GCS: %w", err)
+	}
+	if err := json.Unmarshal(fileData, watchlist); err != nil {
+		return nil, fmt.Errorf("could not unmarshal watchlist JSON: %w", err)
+	}
+	return watchlist, nil
+}
+
+// update loads the watchlist, applies fn and saves the result.
+func update(ctx context.Context, cfg *models.AppConfig, fn func(*models.Watchlist) error) (*models.Watchlist, error) {
+	watchlistMu.Lock()
+	defer watchlistMu.Unlock()
+
+	watchlist, err := Load(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(watchlist); err != nil {
+		return nil, err
+	}
+	for _, entry := range watchlist.Entries {
+		entry.NextRunAt = nextRunAt(entry)
+	}
+
+	watchlistJSON, err := json.Marshal(watchlist)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal watchlist JSON: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, watchlistObjectName, watchlistJSON); err != nil {
+		return nil, fmt.Errorf("could not upload watchlist to GCS: %w", err)
+	}
+	return watchlist, nil
+}
+
+func findByTarget(watchlist *models.Watchlist, kind, targetID string) *models.WatchlistEntry {
+	for _, entry := range watchlist.Entries {
+		if entry.Kind == kind && entry.TargetID == targetID {
+			return entry
+		}
+	}
+	return nil
+}
+
+func findByID(watchlist *models.Watchlist, id string) *models.WatchlistEntry {
+	for _, entry := range watchlist.Entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// ActiveVideoIDs returns the IDs of watched videos whose schedule has not finished.
+func ActiveVideoIDs(ctx context.Context, cfg *models.AppConfig) ([]string, error) {
+	watchlist, err := Load(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var videoIDs []string
+	for _, entry := range watchlist.Entries {
+		if entry.Kind == KindVideo && !nextRunAt(entry).IsZero() {
+			videoIDs = append(videoIDs, entry.TargetID)
+		}
+	}
+	return videoIDs, nil
+}
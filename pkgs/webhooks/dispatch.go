@@ -0,0 +1,246 @@
+package webhooks
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDeliveryAttempts = 5
+	initialBackoff      = time.Second
+)
+
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var deliveryHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+var (
+	// pending tracks deliveries that are still being attempted in the background.
+	pending sync.WaitGroup
+	// deliveryLogMu serializes read-modify-write cycles on delivery logs within this instance.
+	deliveryLogMu sync.Mutex
+)
+
+// target is a single destination for an event.
+type target struct {
+	url    string
+	secret string
+}
+
+// NewEvent builds an event for a pipeline stage ("fetch", "analyze", "ingest")
+// or for the pipeline as a whole (stage "pipeline").
+func NewEvent(stage, status, trackingID, videoID, summary string, artifactURIs []string) models.WebhookEvent {
+	eventType := "stage." + status
+	if stage == "pipeline" {
+		eventType = "pipeline." + status
+	}
+	if artifactURIs == nil {
+		artifactURIs = []string{}
+	}
+	return models.WebhookEvent{
+		EventID:      uuid.New().String(),
+		Type:         eventType,
+		Stage:        stage,
+		Status:       status,
+		TrackingID:   trackingID,
+		VideoID:      videoID,
+		ArtifactURIs: artifactURIs,
+		Summary:      summary,
+		Timestamp:    time.Now().UTC(),
+	}
+}
+
+// Sign returns the value of the X-Webhook-Signature header: an HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the secret. Receivers should recompute it
+// with the X-Webhook-Timestamp header and reject stale timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch delivers an event in the background to the run's callback URL, if
+// any, and to every registered webhook. Per-run callbacks are signed with
+// WEBHOOK_SIGNING_SECRET; registered webhooks use their own secret.
+func Dispatch(cfg *models.AppConfig, event models.WebhookEvent, callbackURL string) {
+	var targets []target
+	if callbackURL != "" {
+		targets = append(targets, target{url: callbackURL, secret: cfg.WebhookSecret})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	subscriptions, err := LoadSubscriptions(ctx, cfg)
+	cancel()
+	if err != nil {
+		shared.Logger.Error("Failed to load webhook subscriptions", "error", err, "trackingId", event.TrackingID)
+	}
+	for _, subscription := range subscriptions {
+		secret := subscription.Secret
+		if secret == "" {
+			secret = cfg.WebhookSecret
+		}
+		targets = append(targets, target{url: subscription.URL, secret: secret})
+	}
+
+	for _, t := range targets {
+		pending.Add(1)
+		go func(t target) {
+			defer pending.Done()
+			delivery := deliver(event, t)
+			logCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := recordDelivery(logCtx, cfg, event.TrackingID, delivery); err != nil {
+				shared.Logger.Error("Failed to record webhook delivery", "error", err, "trackingId", event.TrackingID)
+			}
+		}(t)
+	}
+}
+
+// Wait blocks until every background delivery has finished. Short-lived
+// processes such as batch jobs call it before exiting.
+func Wait() {
+	pending.Wait()
+}
+
+// deliver POSTs the event, retrying with exponential backoff on transport
+// errors and non-2xx responses. Events are never sent unsigned: a target
+// without a secret is recorded as a failed delivery.
+func deliver(event models.WebhookEvent, t target) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		EventID:   event.EventID,
+		EventType: event.Type,
+		URL:       t.url,
+		Status:    "failed",
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("could not marshal event: %v", err)
+		delivery.CompletedAt = time.Now()
+		return delivery
+	}
+	if t.secret == "" {
+		shared.Logger.Error("Not sending unsigned webhook; set WEBHOOK_SIGNING_SECRET to sign per-run callbacks", "url", t.url, "trackingId", event.TrackingID)
+		delivery.Error = "no signing secret configured"
+		delivery.CompletedAt = time.Now()
+		return delivery
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		delivery.Attempts = attempt
+		statusCode, err := post(t, event, body)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Status = "delivered"
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		shared.Logger.Warn("Webhook delivery attempt failed", "attempt", attempt, "url", t.url, "error", err, "trackingId", event.TrackingID)
+		if attempt < maxDeliveryAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	delivery.CompletedAt = time.Now()
+	return delivery
+}
+
+func post(t target, event models.WebhookEvent, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("could not create request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-ID", event.EventID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(t.secret, timestamp, body))
+
+	resp, err := deliveryHTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func deliveryLogObjectName(trackingID string) string {
+	return fmt.Sprintf("webhooks/deliveries/%s.json", trackingID)
+}
+
+// LoadDeliveries returns the delivery log of a tracking ID.
+func LoadDeliveries(ctx context.Context, cfg *models.AppConfig, trackingID string) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, deliveryLogObjectName(trackingID))
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return deliveries, nil
+		}
+		return nil, fmt.Errorf("could not get delivery log from GCS: %w", err)
+	}
+	if err := json.Unmarshal(fileData, &deliveries); err != nil {
+		return nil, fmt.Errorf("could not unmarshal delivery log JSON: %w", err)
+	}
+	return deliveries, nil
+}
+
+func recordDelivery(ctx context.Context, cfg *models.AppConfig, trackingID string, delivery models.WebhookDelivery) error {
+	deliveryLogMu.Lock()
+	defer deliveryLogMu.Unlock()
+
+	deliveries, err := LoadDeliveries(ctx, cfg, trackingID)
+	if err != nil {
+		return err
+	}
+	deliveriesJSON, err := json.Marshal(append(deliveries, delivery))
+	if err != nil {
+		return fmt.Errorf("could not marshal delivery log JSON: %w", err)
+	}
+	return shared.UploadToGCS(ctx, cfg.GCSBucketName, deliveryLogObjectName(trackingID), deliveriesJSON)
+}
+
+// Deliveries returns the webhook delivery log of a tracking ID.
+func Deliveries(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trackingID := r.URL.Query().Get("trackingId")
+		if trackingID == "" {
+			shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'trackingId' query parameter")
+			return
+		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+
+		deliveries, err := LoadDeliveries(r.Context(), cfg, trackingID)
+		if err != nil {
+			shared.Logger.Error("could not load delivery log", "error", err, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to load delivery log")
+			return
+		}
+		shared.JSONResponse(w, trackingID, http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+)
+
+const subscriptionsObjectName = "webhooks.json"
+
+// registryMu serializes read-modify-write cycles on the subscription list within this instance.
+var registryMu sync.Mutex
+
+// ValidateURL checks that a callback URL is an absolute http(s) URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// ValidateCallbackURL checks a per-run callback URL. Callbacks are only
+// accepted when WEBHOOK_SIGNING_SECRET is set, because they would otherwise be
+// sent unsigned.
+func ValidateCallbackURL(cfg *models.AppConfig, rawURL string) error {
+	if err := ValidateURL(rawURL); err != nil {
+		return err
+	}
+	if cfg.WebhookSecret == "" {
+		return errors.New("callback URLs require WEBHOOK_SIGNING_SECRET to be set")
+	}
+	return nil
+}
+
+// LoadSubscriptions reads the globally registered webhooks from GCS.
+func LoadSubscriptions(ctx context.Context, cfg *models.AppConfig) ([]models.WebhookSubscription, error) {
+	subscriptions := []models.WebhookSubscription{}
+	fileData, err := shared.GetFileFromGCS(ctx, cfg.GCSBucketName, subscriptionsObjectName)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return subscriptions, nil
+		}
+		return nil, fmt.Errorf("could not get webhook subscriptions from GCS: %w", err)
+	}
+	if err := json.Unmarshal(fileData, &subscriptions); err != nil {
+		return nil, fmt.Errorf("could not unmarshal webhook subscriptions JSON: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func saveSubscriptions(ctx context.Context, cfg *models.AppConfig, subscriptions []models.WebhookSubscription) error {
+	subscriptionsJSON, err := json.Marshal(subscriptions)
+	if err != nil {
+		return fmt.Errorf("could not marshal webhook subscriptions JSON: %w", err)
+	}
+	if err := shared.UploadToGCS(ctx, cfg.GCSBucketName, subscriptionsObjectName, subscriptionsJSON); err != nil {
+		return fmt.Errorf("could not upload webhook subscriptions to GCS: %w", err)
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Subscriptions lists (GET), registers (POST) and removes (DELETE) global webhooks.
+// A signing secret is generated when none is supplied; it is only returned by POST.
+func Subscriptions(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String())
+
+		switch r.Method {
+		case http.MethodGet:
+			subscriptions, err := LoadSubscriptions(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load webhook subscriptions", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load webhook subscriptions")
+				return
+			}
+			for i := range subscriptions {
+				subscriptions[i].Secret = ""
+			}
+			shared.JSONResponse(w, "", http.StatusOK, subscriptions)
+
+		case http.MethodPost:
+			var subscription models.WebhookSubscription
+			if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			if err := ValidateURL(subscription.URL); err != nil {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, err.Error())
+				return
+			}
+			if subscription.Secret == "" {
+				secret, err := newSecret()
+				if err != nil {
+					shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to generate signing secret")
+					return
+				}
+				subscription.Secret = secret
+			}
+			subscription.ID = uuid.New().String()
+			subscription.CreatedAt = time.Now()
+
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			subscriptions, err := LoadSubscriptions(ctx, cfg)
+			if err == nil {
+				err = saveSubscriptions(ctx, cfg, append(subscriptions, subscription))
+			}
+			if err != nil {
+				shared.Logger.Error("could not register webhook", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to register webhook")
+				return
+			}
+			shared.Logger.Info("Registered webhook", "webhookId", subscription.ID, "webhookUrl", subscription.URL)
+			shared.JSONResponse(w, "", http.StatusCreated, subscription)
+
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				shared.JSONErrorResponse(w, "", http.StatusBadRequest, "Missing 'id' query parameter")
+				return
+			}
+
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			subscriptions, err := LoadSubscriptions(ctx, cfg)
+			if err != nil {
+				shared.Logger.Error("could not load webhook subscriptions", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to load webhook subscriptions")
+				return
+			}
+			kept := []models.WebhookSubscription{}
+			for _, subscription := range subscriptions {
+				if subscription.ID != id {
+					kept = append(kept, subscription)
+				}
+			}
+			if len(kept) == len(subscriptions) {
+				shared.JSONErrorResponse(w, "", http.StatusNotFound, "Webhook not found")
+				return
+			}
+			if err := saveSubscriptions(ctx, cfg, kept); err != nil {
+				shared.Logger.Error("could not remove webhook", "error", err)
+				shared.JSONErrorResponse(w, "", http.StatusInternalServerError, "Failed to remove webhook")
+				return
+			}
+			shared.JSONResponse(w, "", http.StatusOK, models.APIResponse{Status: "success", Message: fmt.Sprintf("Removed webhook %s.", id)})
+
+		default:
+			shared.JSONErrorResponse(w, "", http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package yt_video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+var (
+	// ErrNoCaptions is returned when a video has no caption track in the requested language.
+	ErrNoCaptions = errors.New("video has no caption track in the requested language")
+	// ErrCaptionsForbidden is returned when the credentials may not download
+	// the captions. The API only allows this for OAuth credentials of the
+	// video's owner, not for API keys.
+	ErrCaptionsForbidden = errors.New("captions can only be downloaded with OAuth credentials of the video owner")
+)
+
+// DownloadCaptions downloads a caption track of a video as WebVTT using the
+// application default credentials. Manually created tracks are preferred over
+// automatic ones; an empty language accepts any track. It returns the caption
+// data and the language of the track.
+func DownloadCaptions(ctx context.Context, videoID, language string) ([]byte, string, error) {
+	service, err := youtube.NewService(ctx, option.WithScopes(youtube.YoutubeForceSslScope))
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to create YouTube service with default credentials: %w", err)
+	}
+
+	resp, err := service.Captions.List([]string{"snippet"}, videoID).Context(ctx).Do()
+	if err != nil {
+		return nil, "", captionsError("Error listing captions", err)
+	}
+	var track *youtube.Caption
+	for _, item := range resp.Items {
+		if item.Snippet == nil || (language != "" && item.Snippet.Language != language) {
+			continue
+		}
+		if track == nil || (track.Snippet.TrackKind == "asr" && item.Snippet.TrackKind != "asr") {
+			track = item
+		}
+	}
+	if track == nil {
+		return nil, "", ErrNoCaptions
+	}
+
+	download, err := service.Captions.Download(track.Id).Tfmt("vtt").Context(ctx).Download()
+	if err != nil {
+		return nil, "", captionsError("Error downloading captions", err)
+	}
+	defer download.Body.Close()
+	data, err := io.ReadAll(download.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Error reading captions: %w", err)
+	}
+	return data, track.Snippet.Language, nil
+}
+
+func captionsError(message string, err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusUnauthorized) {
+		return ErrCaptionsForbidden
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
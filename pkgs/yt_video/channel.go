@@ -0,0 +1,47 @@
+package yt_video
+
+import (
+	"app/pkgs/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// LatestUploads returns the most recent uploads of a channel with their
+// publish times, newest first, by reading the channel's uploads playlist.
+func LatestUploads(ctx context.Context, cfg *models.AppConfig, channelID string, maxResults int) ([]models.ChannelUpload, error) {
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
+
+	channelResponse, err := ytService.Channels.List([]string{"contentDetails"}).Id(channelID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching channel details: %w", err)
+	}
+	if len(channelResponse.Items) == 0 || channelResponse.Items[0].ContentDetails == nil || channelResponse.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return nil, fmt.Errorf("channel %s not found", channelID)
+	}
+	uploadsPlaylistID := channelResponse.Items[0].ContentDetails.RelatedPlaylists.Uploads
+
+	playlistResponse, err := ytService.PlaylistItems.List([]string{"contentDetails"}).
+		PlaylistId(uploadsPlaylistID).
+		MaxResults(int64(maxResults)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching channel uploads: %w", err)
+	}
+
+	var uploads []models.ChannelUpload
+	for _, item := range playlistResponse.Items {
+		if item.ContentDetails != nil && item.ContentDetails.VideoId != "" {
+			publishedAt, _ := time.Parse(time.RFC3339, item.ContentDetails.VideoPublishedAt)
+			uploads = append(uploads, models.ChannelUpload{
+				VideoID:     item.ContentDetails.VideoId,
+				PublishedAt: publishedAt,
+			})
+		}
+	}
+	return uploads, nil
+}
@@ -5,6 +5,7 @@ import (
 	"app/pkgs/shared"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -32,155 +33,255 @@ func getYouTubeService(ctx context.Context, apiKey string) (*youtube.Service, er
 	return youtubeService, nil
 }
 
-func FetchData(cfg *models.AppConfig) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		startTime := time.Now()
-		trackingID := r.URL.Query().Get("trackingId")
-		if trackingID == "" {
-			trackingID = uuid.New().String()
-		}
-		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
+// authorChannelID returns the channel ID of a comment's author, which is
+// missing for some deleted or legacy accounts.
+func authorChannelID(snippet *youtube.CommentSnippet) string {
+	if snippet.AuthorChannelId == nil {
+		return ""
+	}
+	return snippet.AuthorChannelId.Value
+}
 
-		runDate := time.Now().Format("2006-01-02")
-		videoId := r.URL.Query().Get("videoId")
-		if videoId == "" {
-			shared.Logger.Warn("Missing 'videoId' query parameter", "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'videoId' query parameter")
-			return
-		}
+// parseTimestamp parses an RFC 3339 timestamp from the API, returning the zero
+// time when it is missing or malformed.
+func parseTimestamp(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
 
-		ctx := r.Context()
+// ErrVideoNotFound is returned by Fetch when the video does not exist or is not public.
+var ErrVideoNotFound = errors.New("video not found")
 
-		ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
-		if err != nil {
-			err = fmt.Errorf("Unable to create YouTube service: %w", err)
-			shared.Logger.Error(err.Error(), "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Internal Server Error: Unable to create YouTube service")
-			return
-		}
+// FetchDetails retrieves the metadata and statistics of a video without its comments.
+func FetchDetails(ctx context.Context, cfg *models.AppConfig, videoId, trackingID string) (*models.VideoData, error) {
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
+
+	videoCall := ytService.Videos.List([]string{"snippet", "contentDetails", "statistics"}).Id(videoId).Context(ctx)
+	videoResponse, err := videoCall.Do()
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching video details: %w", err)
+	}
+	if len(videoResponse.Items) == 0 {
+		return nil, ErrVideoNotFound
+	}
+	shared.Logger.Info("Successfully fetched video details", "videoId", videoId, "trackingId", trackingID)
+
+	video := videoResponse.Items[0]
+
+	thumbnailURL := ""
+	if video.Snippet.Thumbnails.Maxres != nil {
+		thumbnailURL = video.Snippet.Thumbnails.Maxres.Url
+	} else if video.Snippet.Thumbnails.Standard != nil {
+		thumbnailURL = video.Snippet.Thumbnails.Standard.Url
+	} else if video.Snippet.Thumbnails.High != nil {
+		thumbnailURL = video.Snippet.Thumbnails.High.Url
+	}
+
+	return &models.VideoData{
+		ID:            video.Id,
+		ChannelID:     video.Snippet.ChannelId,
+		ChannelTitle:  video.Snippet.ChannelTitle,
+		TrackingID:    trackingID,
+		RunDate:       time.Now().Format("2006-01-02"),
+		Title:         video.Snippet.Title,
+		Description:   video.Snippet.Description,
+		ThumbnailURL:  thumbnailURL,
+		Duration:      video.ContentDetails.Duration,
+		CategoryID:    video.Snippet.CategoryId,
+		ViewCount:     int64(video.Statistics.ViewCount),
+		LikeCount:     int64(video.Statistics.LikeCount),
+		FavoriteCount: int64(video.Statistics.FavoriteCount),
+		CommentCount:  int64(video.Statistics.CommentCount),
+		Comments:      []*models.Comment{},
+	}, nil
+}
+
+// maxQuestionThreadReplies caps the replies fetched for a single question
+// thread, so a viral thread cannot use up the quota of a run.
+const maxQuestionThreadReplies = 500
 
-		videoCall := ytService.Videos.List([]string{"snippet", "contentDetails", "statistics"}).Id(videoId).Context(ctx)
-		videoResponse, err := videoCall.Do()
+// mayBeQuestion reports whether a top-level comment could be a question to the
+// creator. Only these threads have their replies paged beyond the inline ones,
+// so the question tracker sees owner answers further down a thread without
+// spending a quota unit on every thread.
+func mayBeQuestion(comment *youtube.Comment, channelID string) bool {
+	return authorChannelID(comment.Snippet) != channelID && strings.Contains(comment.Snippet.TextDisplay, "?")
+}
+
+// fetchReplies pages all replies of a comment thread, up to
+// maxQuestionThreadReplies, oldest first.
+func fetchReplies(ctx context.Context, ytService *youtube.Service, threadID string) ([]*youtube.Comment, error) {
+	var replies []*youtube.Comment
+	pageToken := ""
+	for {
+		call := ytService.Comments.List([]string{"snippet"}).
+			ParentId(threadID).
+			TextFormat("plainText").
+			MaxResults(100)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		response, err := call.Context(ctx).Do()
 		if err != nil {
-			err = fmt.Errorf("Error fetching video details: %w", err)
-			shared.Logger.Error(err.Error(), "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to fetch video details")
-			return
+			return nil, err
 		}
-		if len(videoResponse.Items) == 0 {
-			shared.Logger.Info("Video not found for videoId", "videoId", videoId, "trackingId", trackingID)
-			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Video not found")
-			return
+		replies = append(replies, response.Items...)
+		pageToken = response.NextPageToken
+		if pageToken == "" || len(replies) >= maxQuestionThreadReplies {
+			return replies, nil
 		}
-		shared.Logger.Info("Successfully fetched video details", "videoId", videoId, "trackingId", trackingID)
+	}
+}
+
+// Fetch retrieves the details and up to cfg.MaxCommentsToFetch comments of a video.
+func Fetch(ctx context.Context, cfg *models.AppConfig, videoId, trackingID string) (*models.VideoData, error) {
+	data, err := FetchDetails(ctx, cfg, videoId, trackingID)
+	if err != nil {
+		return nil, err
+	}
+	runDate := data.RunDate
 
-		video := videoResponse.Items[0]
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
 
-		thumbnailURL := ""
-		if video.Snippet.Thumbnails.Maxres != nil {
-			thumbnailURL = video.Snippet.Thumbnails.Maxres.Url
-		} else if video.Snippet.Thumbnails.Standard != nil {
-			thumbnailURL = video.Snippet.Thumbnails.Standard.Url
-		} else if video.Snippet.Thumbnails.High != nil {
-			thumbnailURL = video.Snippet.Thumbnails.High.Url
-		}
+	var comments []*models.Comment
+	videoChannelId := data.ChannelID
+	nextPageToken := ""
+	quotaExhausted := false
 
-		data := models.VideoData{
-			ID:            video.Id,
-			ChannelID:     video.Snippet.ChannelId,
-			ChannelTitle:  video.Snippet.ChannelTitle,
-			TrackingID:    trackingID,
-			RunDate:       runDate,
-			Title:         video.Snippet.Title,
-			Description:   video.Snippet.Description,
-			ThumbnailURL:  thumbnailURL,
-			Duration:      video.ContentDetails.Duration,
-			CategoryID:    video.Snippet.CategoryId,
-			ViewCount:     int64(video.Statistics.ViewCount),
-			LikeCount:     int64(video.Statistics.LikeCount),
-			FavoriteCount: int64(video.Statistics.FavoriteCount),
-			CommentCount:  int64(video.Statistics.CommentCount),
-			Comments:      []*models.Comment{},
-		}
+	shared.Logger.Info("Fetching comments ordered by 'relevance'. Note: This may not retrieve all available comments.", "trackingId", trackingID)
 
-		var comments []*models.Comment
-		videoChannelId := video.Snippet.ChannelId
-		nextPageToken := ""
+FetchCommentsLoop:
+	for {
+		call := ytService.CommentThreads.List([]string{"snippet", "replies"}).
+			VideoId(videoId).
+			TextFormat("plainText").
+			MaxResults(100).
+			Order("relevance")
 
-		shared.Logger.Info("Fetching comments ordered by 'relevance'. Note: This may not retrieve all available comments.", "trackingId", trackingID)
+		if nextPageToken != "" {
+			call = call.PageToken(nextPageToken)
+		}
 
-	FetchCommentsLoop:
-		for {
-			call := ytService.CommentThreads.List([]string{"snippet", "replies"}).
-				VideoId(videoId).
-				TextFormat("plainText").
-				MaxResults(100).
-				Order("relevance")
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			if strings.Contains(err.Error(), "quotaExceeded") {
+				shared.Logger.Warn("YouTube API quota exceeded while fetching comments. Proceeding with fetched comments.", "trackingId", trackingID)
+				break FetchCommentsLoop
+			}
+			return nil, fmt.Errorf("Error fetching comments: %w", err)
+		}
 
-			if nextPageToken != "" {
-				call = call.PageToken(nextPageToken)
+		for _, item := range response.Items {
+			topLevelComment := item.Snippet.TopLevelComment
+			comments = append(comments, &models.Comment{
+				ID:          topLevelComment.Id,
+				ParentID:    "", // Top-level comments have no parent
+				ChannelID:   videoChannelId,
+				AuthorID:    authorChannelID(topLevelComment.Snippet),
+				Text:        topLevelComment.Snippet.TextDisplay,
+				LikeCount:   topLevelComment.Snippet.LikeCount,
+				ReplyCount:  item.Snippet.TotalReplyCount,
+				TrackingID:  trackingID,
+				RunDate:     runDate,
+				Source:      models.SourceComments,
+				PublishedAt: parseTimestamp(topLevelComment.Snippet.PublishedAt),
+			})
+			if len(comments) >= cfg.MaxCommentsToFetch {
+				break FetchCommentsLoop
 			}
 
-			response, err := call.Context(ctx).Do()
-			if err != nil {
-				if strings.Contains(err.Error(), "quotaExceeded") {
-					shared.Logger.Warn("YouTube API quota exceeded while fetching comments. Proceeding with fetched comments.", "trackingId", trackingID)
-					break FetchCommentsLoop
+			var replies []*youtube.Comment
+			if item.Replies != nil {
+				replies = item.Replies.Comments
+			}
+			// The thread only carries the first few replies. Questions page
+			// the rest, so an answer by the owner is not missed.
+			if !quotaExhausted && int(item.Snippet.TotalReplyCount) > len(replies) && mayBeQuestion(topLevelComment, videoChannelId) {
+				allReplies, err := fetchReplies(ctx, ytService, item.Id)
+				switch {
+				case err == nil:
+					replies = allReplies
+				case strings.Contains(err.Error(), "quotaExceeded"):
+					shared.Logger.Warn("YouTube API quota exceeded while fetching replies. Proceeding with inline replies.", "trackingId", trackingID)
+					quotaExhausted = true
+				default:
+					shared.Logger.Warn("Failed to fetch the replies of a question thread. Proceeding with inline replies.", "error", err, "threadId", item.Id, "trackingId", trackingID)
 				}
-				err = fmt.Errorf("Error fetching comments: %w", err)
-				shared.Logger.Error(err.Error(), "trackingId", trackingID)
-				shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to fetch comments")
-				return
 			}
-
-			for _, item := range response.Items {
-				topLevelComment := item.Snippet.TopLevelComment
+			for _, reply := range replies {
 				comments = append(comments, &models.Comment{
-					ID:         topLevelComment.Id,
-					ParentID:   "", // Top-level comments have no parent
-					ChannelID:  videoChannelId,
-					Text:       topLevelComment.Snippet.TextDisplay,
-					LikeCount:  topLevelComment.Snippet.LikeCount,
-					ReplyCount: item.Snippet.TotalReplyCount,
-					TrackingID: trackingID,
-					RunDate:    runDate,
+					ID:          reply.Id,
+					ParentID:    topLevelComment.Id,
+					ChannelID:   videoChannelId,
+					AuthorID:    authorChannelID(reply.Snippet),
+					Text:        reply.Snippet.TextDisplay,
+					LikeCount:   reply.Snippet.LikeCount,
+					ReplyCount:  0,
+					TrackingID:  trackingID,
+					RunDate:     runDate,
+					Source:      models.SourceComments,
+					PublishedAt: parseTimestamp(reply.Snippet.PublishedAt),
 				})
 				if len(comments) >= cfg.MaxCommentsToFetch {
 					break FetchCommentsLoop
 				}
-
-				if item.Replies != nil {
-					for _, reply := range item.Replies.Comments {
-						comments = append(comments, &models.Comment{
-							ID:         reply.Id,
-							ParentID:   topLevelComment.Id,
-							ChannelID:  videoChannelId,
-							Text:       reply.Snippet.TextDisplay,
-							LikeCount:  reply.Snippet.LikeCount,
-							ReplyCount: 0,
-							TrackingID: trackingID,
-							RunDate:    runDate,
-						})
-						if len(comments) >= cfg.MaxCommentsToFetch {
-							break FetchCommentsLoop
-						}
-					}
-				}
 			}
+		}
 
-			nextPageToken = response.NextPageToken
-			if nextPageToken == "" {
-				break
-			}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+
+	if len(comments) >= cfg.MaxCommentsToFetch {
+		shared.Logger.Info("Reached comment fetch limit. Processing comments.", "limit", cfg.MaxCommentsToFetch, "trackingId", trackingID)
+		comments = comments[:cfg.MaxCommentsToFetch]
+	}
+
+	data.Comments = comments
+	shared.Logger.Info("Successfully fetched comments", "count", len(data.Comments), "videoId", videoId, "trackingId", trackingID)
+	return data, nil
+}
+
+func FetchData(cfg *models.AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		trackingID := r.URL.Query().Get("trackingId")
+		if trackingID == "" {
+			trackingID = uuid.New().String()
 		}
+		shared.Logger.Info("Received request", "method", r.Method, "url", r.URL.String(), "trackingId", trackingID)
 
-		if len(comments) >= cfg.MaxCommentsToFetch {
-			shared.Logger.Info("Reached comment fetch limit. Processing comments.", "limit", cfg.MaxCommentsToFetch, "trackingId", trackingID)
-			comments = comments[:cfg.MaxCommentsToFetch]
+		videoId := r.URL.Query().Get("videoId")
+		if videoId == "" {
+			shared.Logger.Warn("Missing 'videoId' query parameter", "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusBadRequest, "Missing 'videoId' query parameter")
+			return
 		}
 
-		data.Comments = comments
-		shared.Logger.Info("Successfully fetched comments", "count", len(data.Comments), "videoId", videoId, "trackingId", trackingID)
+		ctx := r.Context()
+
+		data, err := Fetch(ctx, cfg, videoId, trackingID)
+		if errors.Is(err, ErrVideoNotFound) {
+			shared.Logger.Info("Video not found for videoId", "videoId", videoId, "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusNotFound, "Video not found")
+			return
+		}
+		if err != nil {
+			shared.Logger.Error(err.Error(), "trackingId", trackingID)
+			shared.JSONErrorResponse(w, trackingID, http.StatusInternalServerError, "Failed to fetch YouTube data")
+			return
+		}
 
 		jsonData, err := json.Marshal(data)
 		if err != nil {
@@ -0,0 +1,157 @@
+package yt_video
+
+import (
+	"app/pkgs/models"
+	"app/pkgs/shared"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrNoLiveChat is returned when a video has no active live chat.
+var ErrNoLiveChat = errors.New("video has no active live chat")
+
+// minPollInterval protects the quota when the API does not return a polling interval.
+const minPollInterval = 5 * time.Second
+
+// LiveChatID returns the active live chat of a video, or ErrNoLiveChat.
+func LiveChatID(ctx context.Context, cfg *models.AppConfig, videoID string) (string, error) {
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return "", fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
+	resp, err := ytService.Videos.List([]string{"liveStreamingDetails"}).Id(videoID).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("Error fetching live streaming details: %w", err)
+	}
+	if len(resp.Items) == 0 {
+		return "", ErrVideoNotFound
+	}
+	details := resp.Items[0].LiveStreamingDetails
+	if details == nil || details.ActiveLiveChatId == "" {
+		return "", ErrNoLiveChat
+	}
+	return details.ActiveLiveChatId, nil
+}
+
+// chatEnded reports whether an API error means the chat is no longer available.
+func chatEnded(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "liveChatEnded" || item.Reason == "liveChatNotFound" || item.Reason == "liveChatDisabled" {
+			return true
+		}
+	}
+	return apiErr.Code == 404
+}
+
+// CollectLiveChat polls the live chat of a stream or premiere and returns the
+// messages as comments with source live_chat. Polling follows the interval
+// requested by the API and stops when the chat ends, after maxDuration, when
+// cfg.MaxCommentsToFetch messages were collected or when ctx is cancelled.
+func CollectLiveChat(ctx context.Context, cfg *models.AppConfig, videoID, trackingID string, maxDuration time.Duration) (*models.VideoData, error) {
+	data, err := FetchDetails(ctx, cfg, videoID, trackingID)
+	if err != nil {
+		return nil, err
+	}
+	chatID, err := LiveChatID(ctx, cfg, videoID)
+	if err != nil {
+		return nil, err
+	}
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
+
+	shared.Logger.Info("Collecting live chat", "videoId", videoID, "liveChatId", chatID, "maxDuration", maxDuration.String(), "trackingId", trackingID)
+	deadline := time.Now().Add(maxDuration)
+	seen := make(map[string]bool)
+	pageToken := ""
+
+CollectLoop:
+	for {
+		call := ytService.LiveChatMessages.List(chatID, []string{"snippet", "authorDetails"}).MaxResults(2000).Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			if chatEnded(err) {
+				shared.Logger.Info("Live chat ended", "videoId", videoID, "trackingId", trackingID)
+				break
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if strings.Contains(err.Error(), "quotaExceeded") {
+				shared.Logger.Warn("YouTube API quota exceeded while collecting live chat. Proceeding with collected messages.", "trackingId", trackingID)
+				break
+			}
+			return nil, fmt.Errorf("Error fetching live chat messages: %w", err)
+		}
+
+		for _, item := range resp.Items {
+			if item.Snippet == nil || seen[item.Id] {
+				continue
+			}
+			text := item.Snippet.DisplayMessage
+			switch item.Snippet.Type {
+			case "textMessageEvent":
+			case "superChatEvent":
+				if item.Snippet.SuperChatDetails != nil {
+					text = item.Snippet.SuperChatDetails.UserComment
+				}
+			default:
+				continue
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			seen[item.Id] = true
+			data.Comments = append(data.Comments, &models.Comment{
+				ID:          item.Id,
+				ChannelID:   data.ChannelID,
+				AuthorID:    item.Snippet.AuthorChannelId,
+				Text:        text,
+				TrackingID:  trackingID,
+				RunDate:     data.RunDate,
+				Source:      models.SourceLiveChat,
+				PublishedAt: parseTimestamp(item.Snippet.PublishedAt),
+			})
+			if len(data.Comments) >= cfg.MaxCommentsToFetch {
+				shared.Logger.Info("Reached comment fetch limit. Stopping live chat collection.", "limit", cfg.MaxCommentsToFetch, "trackingId", trackingID)
+				break CollectLoop
+			}
+		}
+
+		if resp.OfflineAt != "" {
+			shared.Logger.Info("Live chat went offline", "offlineAt", resp.OfflineAt, "trackingId", trackingID)
+			break
+		}
+		pageToken = resp.NextPageToken
+
+		interval := time.Duration(resp.PollingIntervalMillis) * time.Millisecond
+		if interval < minPollInterval {
+			interval = minPollInterval
+		}
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break CollectLoop
+		case <-time.After(interval):
+		}
+	}
+
+	data.CommentCount = int64(len(data.Comments))
+	shared.Logger.Info("Finished collecting live chat", "messages", len(data.Comments), "videoId", videoID, "trackingId", trackingID)
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package yt_video
+
+import (
+	"app/pkgs/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxIDsPerVideosCall is the YouTube Data API limit for ids in a single videos.list call.
+const maxIDsPerVideosCall = 50
+
+// FetchStatistics reads only the 'statistics' part of the given videos, which
+// costs a single quota unit per 50 videos, and returns one snapshot per video.
+func FetchStatistics(ctx context.Context, cfg *models.AppConfig, videoIDs []string) ([]models.VideoStatsSnapshot, error) {
+	ytService, err := getYouTubeService(ctx, cfg.YTApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create YouTube service: %w", err)
+	}
+
+	var snapshots []models.VideoStatsSnapshot
+	for start := 0; start < len(videoIDs); start += maxIDsPerVideosCall {
+		end := start + maxIDsPerVideosCall
+		if end > len(videoIDs) {
+			end = len(videoIDs)
+		}
+
+		response, err := ytService.Videos.List([]string{"statistics"}).Id(videoIDs[start:end]...).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching video statistics: %w", err)
+		}
+
+		snapshotAt := time.Now().UTC()
+		for _, video := range response.Items {
+			if video.Statistics == nil {
+				continue
+			}
+			snapshots = append(snapshots, models.VideoStatsSnapshot{
+				VideoID:      video.Id,
+				SnapshotAt:   snapshotAt,
+				ViewCount:    int64(video.Statistics.ViewCount),
+				LikeCount:    int64(video.Statistics.LikeCount),
+				CommentCount: int64(video.Statistics.CommentCount),
+			})
+		}
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,39 @@
+package yt_video
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ExtractVideoID returns the video ID of a youtube.com or youtu.be URL.
+func ExtractVideoID(youtubeURL string) (string, error) {
+	u, err := url.Parse(youtubeURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if strings.Contains(u.Host, "youtube.com") {
+		if videoID := u.Query().Get("v"); videoID != "" {
+			return videoID, nil
+		}
+	}
+	if strings.Contains(u.Host, "youtu.be") {
+		if videoID := strings.TrimPrefix(u.Path, "/"); videoID != "" {
+			return videoID, nil
+		}
+	}
+	return "", errors.New("could not find video ID in URL")
+}
+
+// ParseVideoRef accepts either a bare video ID or a YouTube URL and returns the video ID.
+func ParseVideoRef(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if videoIDPattern.MatchString(ref) {
+		return ref, nil
+	}
+	return ExtractVideoID(ref)
+}
@@ -24,7 +24,10 @@ text STRING,
 like_count INTEGER,
 reply_count INTEGER,
 tracking_id STRING,
-run_date DATE
+run_date DATE,
+source STRING,
+published_at TIMESTAMP,
+author_channel_id STRING
 );
 
 CREATE TABLE your_dataset_name.analyzed (
@@ -61,6 +64,17 @@ CREATE TABLE your_dataset_name.analyzed (
         summary STRING,
         representative_comment STRING
     >>,
+    theme_clusters ARRAY<STRUCT<
+        theme_title STRING,
+        summary STRING,
+        representative_comment STRING,
+        member_count INT64,
+        share FLOAT64,
+        like_total INT64,
+        positive INT64,
+        negative INT64,
+        neutral INT64
+    >>,
     engagement_highlights ARRAY<STRUCT<
         comment_text STRING,
         engagement_count INT64,
@@ -79,3 +93,98 @@ CREATE TABLE your_dataset_name.analyzed (
         monetization_opportunities ARRAY<STRUCT<category STRING, products ARRAY<STRING>>>
     >
 );
+
+CREATE TABLE your_dataset_name.moments (
+    tracking_id STRING,
+    video_id STRING,
+    run_date DATE,
+    start_seconds INT64,
+    end_seconds INT64,
+    label STRING,
+    peak_second INT64,
+    mentions INT64,
+    positive INT64,
+    negative INT64,
+    neutral INT64,
+    top_quotes ARRAY<STRING>
+);
+
+CREATE TABLE your_dataset_name.aspects (
+    tracking_id STRING,
+    video_id STRING,
+    channel_id STRING,
+    run_date DATE,
+    taxonomy STRING,
+    aspect STRING,
+    mentions INT64,
+    share FLOAT64,
+    positive INT64,
+    negative INT64,
+    neutral INT64,
+    net_sentiment FLOAT64,
+    top_quotes ARRAY<STRING>
+);
+
+CREATE TABLE your_dataset_name.mentions (
+    tracking_id STRING,
+    video_id STRING,
+    channel_id STRING,
+    run_date DATE,
+    entity STRING,
+    type STRING,
+    mentions INT64,
+    share FLOAT64,
+    positive INT64,
+    negative INT64,
+    neutral INT64,
+    like_total INT64,
+    in_description BOOL,
+    top_quotes ARRAY<STRING>
+);
+
+CREATE TABLE your_dataset_name.channel_reports (
+    report_id STRING,
+    run_date DATE,
+    channel_id STRING,
+    channel_title STRING,
+    video_count INT64,
+    tracking_ids ARRAY<STRING>,
+    executive_summary STRING,
+    recurring_themes ARRAY<STRUCT<
+        theme_title STRING,
+        summary STRING,
+        video_count INT64
+    >>,
+    persistent_criticism ARRAY<STRUCT<
+        point STRING,
+        video_count INT64,
+        representative_comment STRING
+    >>,
+    sentiment_trend ARRAY<STRUCT<
+        tracking_id STRING,
+        video_id STRING,
+        title STRING,
+        run_date DATE,
+        sentiment_label STRING,
+        positive_comments INT64,
+        negative_comments INT64,
+        neutral_comments INT64,
+        positive_share FLOAT64,
+        negative_share FLOAT64,
+        view_count INT64,
+        like_to_view_ratio FLOAT64,
+        comment_to_view_ratio FLOAT64
+    >>,
+    best_performers ARRAY<STRUCT<tracking_id STRING, video_id STRING, title STRING, reason STRING>>,
+    worst_performers ARRAY<STRUCT<tracking_id STRING, video_id STRING, title STRING, reason STRING>>
+);
+
+CREATE TABLE your_dataset_name.video_stats_snapshots (
+    video_id STRING,
+    snapshot_at TIMESTAMP,
+    view_count INT64,
+    like_count INT64,
+    comment_count INT64
+)
+PARTITION BY DATE(snapshot_at)
+CLUSTER BY video_id;